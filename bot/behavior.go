@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// Behavior decides what a bot does.
+//
+// Next is called every actionSendInterval and returns the actions to send for
+// that interval, nothing is sent when it returns none.
+type Behavior interface {
+	Next(view View, now time.Time) []input.Action
+}
+
+// View is what a bot knows about the world, taken from its latest snapshot.
+type View struct {
+	Scene   string
+	Tick    int
+	Self    snapshot.EntityState
+	HasSelf bool // False until the bot has identified its entity.
+	Others  []snapshot.EntityState
+}
+
+// behaviorOptions are the settings shared by every behavior.
+type behaviorOptions struct {
+	script *Timeline // Timeline for the scripted behavior, if one was given.
+}
+
+// behaviorFactory builds a fresh behavior for a single bot.
+type behaviorFactory func(opts behaviorOptions) (Behavior, error)
+
+// behaviors are the built-in behaviors by name.
+var behaviors = map[string]behaviorFactory{
+	"wander":  func(behaviorOptions) (Behavior, error) { return newWander(), nil },
+	"jumper":  func(behaviorOptions) (Behavior, error) { return newJumper(), nil },
+	"dropper": func(behaviorOptions) (Behavior, error) { return newDropper(), nil },
+	"patrol":  func(behaviorOptions) (Behavior, error) { return newPatrol(), nil },
+	"chase":   func(behaviorOptions) (Behavior, error) { return newChase(), nil },
+	"script":  newScripted,
+}
+
+// behaviorNames lists the built-in behaviors, for flag help and errors.
+func behaviorNames() []string {
+	names := make([]string, 0, len(behaviors))
+	for name := range behaviors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BehaviorMix assigns behaviors to bots by percentage.
+type BehaviorMix []mixEntry
+
+type mixEntry struct {
+	name   string
+	weight float64
+}
+
+// ParseBehaviorMix parses "name" or "name:percent,name:percent,...".
+// Weights are relative, so they don't need to add up to 100.
+func ParseBehaviorMix(s string) (BehaviorMix, error) {
+	mix := BehaviorMix{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, weight := part, 100.0
+		if n, w, ok := strings.Cut(part, ":"); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSuffix(w, "%"), 64)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("behavior %q: invalid percentage %q", n, w)
+			}
+			name, weight = n, parsed
+		}
+		if _, ok := behaviors[name]; !ok {
+			return nil, fmt.Errorf("unknown behavior %q (available: %s)", name, strings.Join(behaviorNames(), ", "))
+		}
+		if weight > 0 {
+			mix = append(mix, mixEntry{name: name, weight: weight})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("no behaviors selected")
+	}
+	return mix, nil
+}
+
+// Uses reports whether the mix includes a behavior.
+func (mix BehaviorMix) Uses(name string) bool {
+	for _, entry := range mix {
+		if entry.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign picks the behavior for bot index of count, splitting the swarm by the
+// mix's percentages in order.
+func (mix BehaviorMix) Assign(index, count int) string {
+	total := 0.0
+	for _, entry := range mix {
+		total += entry.weight
+	}
+	if count <= 0 {
+		count = 1
+	}
+
+	// Place the bot in the middle of its share of the swarm
+	position := (float64(index) + 0.5) / float64(count) * total
+	for _, entry := range mix {
+		if position < entry.weight {
+			return entry.name
+		}
+		position -= entry.weight
+	}
+	return mix[len(mix)-1].name
+}
+
+// NewBehavior builds the behavior for bot index of count.
+func (mix BehaviorMix) NewBehavior(index, count int, opts behaviorOptions) (string, Behavior, error) {
+	name := mix.Assign(index, count)
+	behavior, err := behaviors[name](opts)
+	return name, behavior, err
+}
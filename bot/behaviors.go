@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/ldtk"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+)
+
+// Constants for the built-in behaviors.
+const (
+	// minStateDuration defines minimum time in movement/idle state.
+	minStateDuration = 500 * time.Millisecond
+	// maxStateDuration defines maximum time in movement/idle state.
+	maxStateDuration = 3000 * time.Millisecond
+	// downChance defines probability of sending Down action per interval.
+	downChance = 0.1
+
+	// minJumpInterval and maxJumpInterval bound the jumper's time between jumps.
+	minJumpInterval = 400 * time.Millisecond
+	maxJumpInterval = 1500 * time.Millisecond
+	// turnChance defines probability of the jumper turning around per jump.
+	turnChance = 0.3
+
+	// arriveDistance is how close patrol and chase get to their target.
+	arriveDistance = 16.0
+	// climbHeight is how far above a target has to be before it is jumped to.
+	climbHeight = 24.0
+	// stuckDuration is how long without horizontal progress counts as stuck.
+	stuckDuration = 500 * time.Millisecond
+
+	// dashDistance is how far away a chased player has to be to dash after.
+	dashDistance = 160.0
+	// dashChance defines probability of chase dashing per interval when far enough.
+	dashChance = 0.05
+)
+
+// randomDuration picks a duration in [min, max).
+func randomDuration(min, max time.Duration) time.Duration {
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+// horizontal is the action that moves from x towards target.
+func horizontal(x, target float64) input.Action {
+	if target < x {
+		return actions.Left
+	}
+	return actions.Right
+}
+
+// BotState represents current high-level behavior of the wander behavior.
+type BotState int
+
+const (
+	StateIdle BotState = iota
+	StateMovingLeft
+	StateMovingRight
+)
+
+// String returns a human-readable representation of the BotState.
+func (s BotState) String() string {
+	switch s {
+	case StateIdle:
+		return "Idle"
+	case StateMovingLeft:
+		return "MovingLeft"
+	case StateMovingRight:
+		return "MovingRight"
+	default:
+		return fmt.Sprintf("UnknownState(%d)", int(s))
+	}
+}
+
+// wander idles and walks in random directions, with occasional Down presses.
+type wander struct {
+	state    BotState
+	stateEnd time.Time
+}
+
+func newWander() *wander {
+	return &wander{state: StateIdle}
+}
+
+func (w *wander) Next(view View, now time.Time) []input.Action {
+	if now.After(w.stateEnd) {
+		w.chooseNewState(now)
+	}
+
+	acts := []input.Action{}
+	switch w.state {
+	case StateMovingLeft:
+		acts = append(acts, actions.Left)
+	case StateMovingRight:
+		acts = append(acts, actions.Right)
+	}
+
+	if rand.Float32() < downChance {
+		acts = []input.Action{actions.Down}
+	}
+	return acts
+}
+
+// chooseNewState selects next state randomly and sets its duration.
+func (w *wander) chooseNewState(now time.Time) {
+	rnd := rand.Float32()
+	w.state = StateIdle
+
+	if rnd < 0.35 {
+		w.state = StateMovingLeft
+	} else if rnd < 0.70 {
+		w.state = StateMovingRight
+	}
+	w.stateEnd = now.Add(randomDuration(minStateDuration, maxStateDuration))
+}
+
+// jumper runs back and forth, jumping at random intervals.
+type jumper struct {
+	dir      input.Action
+	nextJump time.Time
+}
+
+func newJumper() *jumper {
+	dir := actions.Left
+	if rand.Intn(2) == 0 {
+		dir = actions.Right
+	}
+	return &jumper{dir: dir}
+}
+
+func (j *jumper) Next(view View, now time.Time) []input.Action {
+	if now.Before(j.nextJump) {
+		return []input.Action{j.dir}
+	}
+	j.nextJump = now.Add(randomDuration(minJumpInterval, maxJumpInterval))
+
+	if rand.Float32() < turnChance {
+		if j.dir == actions.Left {
+			j.dir = actions.Right
+		} else {
+			j.dir = actions.Left
+		}
+	}
+	return []input.Action{j.dir, actions.Jump}
+}
+
+// dropper climbs onto platforms and drops back through them.
+//
+// The lowest height it has stood at is taken as the floor: from the floor it
+// jumps, anywhere higher it presses Down to fall through the platform.
+type dropper struct {
+	wander *wander
+	floor  float64
+	seen   bool
+}
+
+func newDropper() *dropper {
+	return &dropper{wander: newWander()}
+}
+
+func (d *dropper) Next(view View, now time.Time) []input.Action {
+	acts := d.wander.Next(view, now)
+	if !view.HasSelf || !view.Self.Grounded {
+		return acts
+	}
+
+	// Larger Y is further down
+	y := view.Self.Position.Y
+	if !d.seen || y > d.floor {
+		d.floor, d.seen = y, true
+	}
+
+	if y < d.floor-climbHeight {
+		return []input.Action{actions.Down}
+	}
+	return append(acts, actions.Jump)
+}
+
+// patrol walks between the LDtk entities of its scene, left to right and back.
+// It wanders until the bot has identified its entity.
+type patrol struct {
+	idle      *wander
+	loaded    bool
+	scene     string
+	waypoints []vector.Two
+	target    int
+	step      int
+
+	lastX    float64
+	progress time.Time
+}
+
+func newPatrol() *patrol {
+	return &patrol{idle: newWander(), step: 1}
+}
+
+func (p *patrol) Next(view View, now time.Time) []input.Action {
+	if !view.HasSelf {
+		return p.idle.Next(view, now)
+	}
+	if !p.loaded || view.Scene != p.scene {
+		p.load(view.Scene)
+	}
+	if len(p.waypoints) == 0 {
+		return nil
+	}
+
+	pos := view.Self.Position
+	target := p.waypoints[p.target]
+	if math.Abs(target.X-pos.X) < arriveDistance {
+		p.advance()
+		target = p.waypoints[p.target]
+	}
+
+	acts := []input.Action{horizontal(pos.X, target.X)}
+
+	if math.Abs(pos.X-p.lastX) > 1 {
+		p.lastX = pos.X
+		p.progress = now
+	}
+	stuck := now.Sub(p.progress) > stuckDuration
+	if view.Self.Grounded && (stuck || pos.Y-target.Y > climbHeight) {
+		acts = append(acts, actions.Jump)
+	}
+	return acts
+}
+
+// load reads the waypoints of a scene, the first catalog scene when unknown.
+func (p *patrol) load(scene string) {
+	p.loaded = true
+	p.scene = scene
+	p.target, p.step = 0, 1
+
+	if scene == "" {
+		scene = scenes.Catalog[0].Name
+	}
+	waypoints, err := ldtk.EntityPositions(scene)
+	if err != nil {
+		log.Printf("Patrol: failed to read waypoints for %s: %v", scene, err)
+	}
+	p.waypoints = waypoints
+}
+
+// advance moves to the next waypoint, turning around at either end.
+func (p *patrol) advance() {
+	if len(p.waypoints) < 2 {
+		return
+	}
+	next := p.target + p.step
+	if next < 0 || next >= len(p.waypoints) {
+		p.step = -p.step
+		next = p.target + p.step
+	}
+	p.target = next
+}
+
+// chase follows the nearest other player, wandering while there is nobody to
+// chase or the bot has not identified its entity.
+type chase struct {
+	idle     *wander
+	lastX    float64
+	progress time.Time
+}
+
+func newChase() *chase {
+	return &chase{idle: newWander()}
+}
+
+func (c *chase) Next(view View, now time.Time) []input.Action {
+	if !view.HasSelf || len(view.Others) == 0 {
+		return c.idle.Next(view, now)
+	}
+
+	pos := view.Self.Position
+	nearest := view.Others[0].Position
+	for _, other := range view.Others[1:] {
+		if distance(pos, other.Position) < distance(pos, nearest) {
+			nearest = other.Position
+		}
+	}
+
+	acts := []input.Action{}
+	if math.Abs(nearest.X-pos.X) > arriveDistance {
+		acts = append(acts, horizontal(pos.X, nearest.X))
+	}
+	if math.Abs(nearest.X-pos.X) > dashDistance && rand.Float32() < dashChance {
+		acts = append(acts, actions.Dash)
+	}
+
+	if math.Abs(pos.X-c.lastX) > 1 {
+		c.lastX = pos.X
+		c.progress = now
+	}
+	stuck := len(acts) > 0 && now.Sub(c.progress) > stuckDuration
+	if view.Self.Grounded && (stuck || pos.Y-nearest.Y > climbHeight) {
+		acts = append(acts, actions.Jump)
+	}
+	return acts
+}
+
+func distance(a, b vector.Two) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
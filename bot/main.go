@@ -7,11 +7,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -19,6 +20,8 @@ import (
 	// Import types for actions and messages.
 	"github.com/TheBitDrifter/bappa/blueprint/input"
 	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/session"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
 )
 
 // Constants for connection and behavior parameters.
@@ -35,56 +38,73 @@ const (
 	shutdownWaitTimeout = 10 * time.Second
 	// lengthPrefixBytes defines size of message length prefix.
 	lengthPrefixBytes = 4
+	// claimRetryInterval defines wait time between session claim attempts.
+	claimRetryInterval = 100 * time.Millisecond
+	// maxFrameBytes rejects length prefixes no snapshot could need.
+	maxFrameBytes = 16 * 1024 * 1024
 
 	// actionSendInterval controls action send frequency.
 	actionSendInterval = 100 * time.Millisecond
-	// minStateDuration defines minimum time in movement/idle state.
-	minStateDuration = 500 * time.Millisecond
-	// maxStateDuration defines maximum time in movement/idle state.
-	maxStateDuration = 3000 * time.Millisecond
-	// downChance defines probability of sending Down action per interval.
-	downChance = 0.1
 )
 
-// BotState represents current high-level behavior.
-type BotState int
-
-const (
-	StateIdle BotState = iota
-	StateMovingLeft
-	StateMovingRight
-)
+// botConfig is how every bot of a swarm connects.
+type botConfig struct {
+	server    string
+	session   string // Session endpoint, empty when sessions are not used.
+	tps       int
+	reconnect bool
+}
 
 // BotClient manages state and network connection for a bot instance.
 type BotClient struct {
 	id           int
-	conn         net.Conn
-	currentState BotState
-	stateEndTime time.Time // Time when state should change.
-
-	running     bool
-	mutex       sync.Mutex // Protects conn, running, state vars, actionStamp.
-	actionStamp int        // Monotonically increasing counter for sent actions.
+	cfg          botConfig
+	conn         net.Conn // Nil while reconnecting.
+	behaviorName string
+	behavior     Behavior // Only used by the action loop.
+
+	running bool
+	token   string     // Session token, resumes the bot's player after a reconnect.
+	mutex   sync.Mutex // Protects conn, running, token, track, stats.
+
+	decoder snapshot.SceneDecoders // Only used by the connection monitor.
+	track   *tracker               // Own entity, tick estimate and pending inputs.
+	predict *predictor             // Nil when the bot's scene can't be simulated.
+	desyncs *snapshot.DesyncDetector
+	stats   botStats
+
+	decoded snapshot.Ack // Newest frame decoded, acked with the next actions.
+	acked   snapshot.Ack // Newest frame acked.
+	binary  bool         // Whether the server switched the stream to binary.
 }
 
-// NewBotClient creates and initializes a connected bot client.
-func NewBotClient(id int, serverAddr string) (*BotClient, error) {
-	log.Printf("[Bot %d] Connecting to %s", id, serverAddr)
-	conn, err := net.DialTimeout("tcp", serverAddr, connectionTimeout)
+// integrity checks every decoded frame against the checksums sent with it, which
+// catches corrupted streams. Desyncs are counted per bot from its prediction.
+var integrity = &snapshot.DesyncDetector{}
+
+// NewBotClient creates and initializes a connected bot client. A token from an
+// earlier bot resumes that bot's player.
+func NewBotClient(id int, cfg botConfig, behaviorName string, behavior Behavior, token string) (*BotClient, error) {
+	log.Printf("[Bot %d] Connecting to %s", id, cfg.server)
+	conn, err := net.DialTimeout("tcp", cfg.server, connectionTimeout)
 	if err != nil {
 		log.Printf("[Bot %d] Failed connection: %v", id, err)
 		return nil, fmt.Errorf("bot %d connection failed", id)
 	}
-	log.Printf("[Bot %d] Connected to %s", id, conn.RemoteAddr())
+	log.Printf("[Bot %d] Connected to %s as %s", id, conn.RemoteAddr(), behaviorName)
 
 	// Initialize state.
 	return &BotClient{
 		id:           id,
+		cfg:          cfg,
 		conn:         conn,
-		currentState: StateIdle,
-		stateEndTime: time.Now(),
+		behaviorName: behaviorName,
+		behavior:     behavior,
 		running:      true,
-		actionStamp:  0,
+		token:        token,
+		track:        newTracker(cfg.tps),
+		predict:      newPredictor(),
+		desyncs:      &snapshot.DesyncDetector{},
 	}, nil
 }
 
@@ -96,11 +116,13 @@ func (b *BotClient) Start() {
 		log.Printf("[Bot %d] Start called but already stopped.", b.id)
 		return
 	}
+	conn := b.conn
 	b.mutex.Unlock()
 
 	log.Printf("[Bot %d] Starting loops", b.id)
 	go b.actionLoop()
 	go b.connectionMonitor()
+	go b.claim(conn)
 }
 
 // Stop shuts down the bot and closes its connection.
@@ -131,12 +153,139 @@ func (b *BotClient) IsRunning() bool {
 	return b.running
 }
 
-// connectionMonitor checks connection health and stops the bot on errors.
+// Token is the bot's session token, empty until its connection was claimed.
+func (b *BotClient) Token() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.token
+}
+
+// drop handles conn failing. With reconnects enabled the bot redials in the
+// background, otherwise it stops. It reports whether the bot is still running.
+func (b *BotClient) drop(conn net.Conn, format string, args ...any) bool {
+	b.mutex.Lock()
+	if !b.running {
+		b.mutex.Unlock()
+		return false
+	}
+	if b.conn != conn {
+		// The other loop already dropped it
+		b.mutex.Unlock()
+		return true
+	}
+	b.stats.dropped = true
+	b.stats.drops++
+
+	if !b.cfg.reconnect {
+		b.mutex.Unlock()
+		log.Printf("[Bot %d] %s. Stopping.", b.id, fmt.Sprintf(format, args...))
+		b.Stop()
+		return false
+	}
+	b.conn = nil
+	b.mutex.Unlock()
+
+	log.Printf("[Bot %d] %s. Reconnecting.", b.id, fmt.Sprintf(format, args...))
+	err := conn.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("[Bot %d] Error closing connection: %v", b.id, err)
+	}
+	go b.reconnect()
+	return true
+}
+
+// reconnect redials the server with backoff until it succeeds or the bot is
+// stopped.
+func (b *BotClient) reconnect() {
+	backoff := session.NewBackoff()
+	for {
+		time.Sleep(backoff.Next())
+		if !b.IsRunning() {
+			return
+		}
+
+		conn, err := net.DialTimeout("tcp", b.cfg.server, connectionTimeout)
+		if err != nil {
+			log.Printf("[Bot %d] Reconnect attempt %d failed: %v", b.id, backoff.Attempts(), err)
+			continue
+		}
+
+		b.mutex.Lock()
+		if !b.running {
+			b.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		b.conn = conn
+		b.track = newTracker(b.cfg.tps)
+		b.predict = newPredictor()
+		b.decoded, b.acked, b.binary = snapshot.Ack{}, snapshot.Ack{}, false
+		b.stats.dropped = false
+		b.stats.reconnects++
+		b.mutex.Unlock()
+
+		log.Printf("[Bot %d] Reconnected after %d attempts", b.id, backoff.Attempts())
+		b.claim(conn)
+		return
+	}
+}
+
+// claim sends a proof over conn and presents it to the session endpoint for
+// conn's token, resuming the bot's earlier session when it has one. The claim
+// also tells the bot which entity it is.
+func (b *BotClient) claim(conn net.Conn) {
+	if b.cfg.session == "" || conn == nil {
+		return
+	}
+
+	proof := session.NewProof()
+	req := session.ClaimRequest{Proof: proof.String(), Token: b.Token()}
+	deadline := time.Now().Add(connectionTimeout)
+	var resp session.ClaimResponse
+	for {
+		// Actions are dropped until the server spawned the bot's player, so
+		// the proof goes out again with every attempt
+		b.mutex.Lock()
+		stamp := b.track.nextStamp(time.Now())
+		b.mutex.Unlock()
+		err := sendActions(conn, []input.StampedAction{proof.Action(stamp)})
+		if err != nil {
+			log.Printf("[Bot %d] Failed to send session proof: %v", b.id, err)
+			return
+		}
+
+		resp, err = session.Claim(b.cfg.session, req)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, session.ErrUnknownConnection) || time.Now().After(deadline) {
+			log.Printf("[Bot %d] Session claim failed: %v", b.id, err)
+			return
+		}
+		time.Sleep(claimRetryInterval)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn != conn {
+		// Dropped again in the meantime
+		return
+	}
+	b.token = resp.Token
+	b.track.claim(resp.Entity)
+	if resp.Resumed {
+		b.stats.resumed++
+		log.Printf("[Bot %d] Resumed its player %d in %s", b.id, resp.Entity, resp.Scene)
+	}
+}
+
+// connectionMonitor reads and decodes the server's state frames, dropping the
+// connection on errors.
 func (b *BotClient) connectionMonitor() {
 	log.Printf("[Bot %d] Connection monitor started.", b.id)
-	buffer := make([]byte, 1)
 	defer log.Printf("[Bot %d] Connection monitor finished.", b.id)
 
+	var lastConn net.Conn
 	for {
 		if !b.IsRunning() {
 			return
@@ -146,56 +295,118 @@ func (b *BotClient) connectionMonitor() {
 		currentConn := b.conn
 		b.mutex.Unlock()
 		if currentConn == nil {
-			return
+			// Reconnecting
+			time.Sleep(monitorCheckInterval)
+			continue
+		}
+		if currentConn != lastConn {
+			// A new connection starts its streams with keyframes
+			b.decoder = snapshot.SceneDecoders{}
+			lastConn = currentConn
 		}
 
 		err := currentConn.SetReadDeadline(time.Now().Add(readDeadline))
 		if err != nil {
-			if b.IsRunning() {
-				log.Printf("[Bot %d] Monitor set deadline error: %v. Stopping.", b.id, err)
-				b.Stop()
+			if !b.drop(currentConn, "Monitor set deadline error: %v", err) {
+				return
 			}
-			return
+			continue
 		}
 
-		_, err = currentConn.Read(buffer)
+		data, err := readFrame(currentConn)
+		if errors.Is(err, errNoFrame) {
+			continue
+		}
 		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				continue
-			}
-			if b.IsRunning() {
-				log.Printf("[Bot %d] Monitor read error: %v. Stopping.", b.id, err)
-				b.Stop()
+			if !b.drop(currentConn, "Monitor read error: %v", err) {
+				return
 			}
-			return
+			continue
 		}
+
+		b.handleFrame(data)
 	}
 }
 
-// chooseNewState selects next state randomly and sets its duration.
-func (b *BotClient) chooseNewState() {
-	rnd := rand.Float32()
-	newState := StateIdle
+// errNoFrame is a read deadline passing between frames.
+var errNoFrame = errors.New("no frame before deadline")
+
+// readFrame reads one length-prefixed frame.
+func readFrame(conn net.Conn) ([]byte, error) {
+	prefix := make([]byte, lengthPrefixBytes)
+	n, err := io.ReadFull(conn, prefix)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && n == 0 {
+			return nil, errNoFrame
+		}
+		return nil, err
+	}
 
-	if rnd < 0.35 {
-		newState = StateMovingLeft
-	} else if rnd < 0.70 {
-		newState = StateMovingRight
+	size := binary.BigEndian.Uint32(prefix)
+	if size > maxFrameBytes {
+		return nil, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(conn, data)
+	if err != nil {
+		return nil, err
 	}
+	return data, nil
+}
 
-	durationRange := int64(maxStateDuration - minStateDuration)
-	duration := minStateDuration + time.Duration(rand.Int63n(durationRange))
-	endTime := time.Now().Add(duration)
+// handleFrame decodes a state frame and updates the bot's tracking and stats.
+func (b *BotClient) handleFrame(data []byte) {
+	now := time.Now()
+	frame, err := b.decoder.Decode(data)
 
 	b.mutex.Lock()
-	b.currentState = newState
-	b.stateEndTime = endTime
-	b.mutex.Unlock()
+	defer b.mutex.Unlock()
+
+	b.stats.snapshots++
+	b.stats.bytes += uint64(len(data) + lengthPrefixBytes)
+
+	if errors.Is(err, snapshot.ErrMissingBaseline) {
+		// Wait for the next keyframe
+		return
+	}
+	if err != nil {
+		b.stats.decodeErrors++
+		log.Printf("[Bot %d] Failed to decode frame (%d bytes): %v", b.id, len(data), err)
+		return
+	}
+
+	integrity.VerifyFrame(frame)
+	b.decoded = snapshot.Ack{Scene: frame.Scene, Tick: frame.Snapshot.Tick}
+	b.binary = b.binary || snapshot.IsBinary(data)
 
-	log.Printf("[Bot %d] Entering state: %v for %v", b.id, newState, duration)
+	b.track.setTickRate(frame.TickRate)
+	latencies := b.track.observe(frame, snapshot.IsBinary(data), now)
+	b.stats.latencies = append(b.stats.latencies, latencies...)
+
+	b.checkPrediction(frame, snapshot.IsBinary(data))
+}
+
+// checkPrediction compares what the bot predicted for its own entity with the
+// server's checksum, once the bot knows which entity is its own.
+func (b *BotClient) checkPrediction(frame snapshot.Frame, binary bool) {
+	if b.predict == nil || !b.track.hasOwn {
+		return
+	}
+	predicted, auth, ok, err := b.predict.check(frame, binary, b.track.own)
+	if err != nil {
+		log.Printf("[Bot %d] Not predicting in %s: %v", b.id, frame.Scene, err)
+		b.predict = nil
+		return
+	}
+	if !ok {
+		return
+	}
+	if sum, ok := frame.Checksum(auth.ID); ok {
+		b.desyncs.Check(frame.Snapshot.Tick, predicted, sum, auth, true)
+	}
 }
 
-// actionLoop manages state transitions and sends actions based on current state.
+// actionLoop asks the bot's behavior for actions and sends them.
 func (b *BotClient) actionLoop() {
 	log.Printf("[Bot %d] Action loop started.", b.id)
 	ticker := time.NewTicker(actionSendInterval)
@@ -219,85 +430,80 @@ func (b *BotClient) actionLoop() {
 		now := time.Now()
 
 		b.mutex.Lock()
-		endTime := b.stateEndTime
-		currentState := b.currentState
+		view := b.track.view()
 		b.mutex.Unlock()
 
-		if now.After(endTime) {
-			b.chooseNewState()
-			b.mutex.Lock()
-			currentState = b.currentState
-			b.mutex.Unlock()
-		}
+		actionsToSend := b.behavior.Next(view, now)
 
-		shouldSendAction := false
-		var actionToSend input.Action
-
-		switch currentState {
-		case StateMovingLeft:
-			actionToSend = actions.Left
-			shouldSendAction = true
-		case StateMovingRight:
-			actionToSend = actions.Right
-			shouldSendAction = true
-		case StateIdle:
-			shouldSendAction = false
+		b.mutex.Lock()
+		var currentStamp int
+		if len(actionsToSend) > 0 {
+			currentStamp = b.track.sentInputs(now)
+		} else {
+			currentStamp = b.track.nextStamp(now)
 		}
-
-		rnd := rand.Float32()
-		if rnd < downChance {
-			actionToSend = actions.Down
-			shouldSendAction = true
+		stampedActions := make([]input.StampedAction, 0, len(actionsToSend)+2)
+		for _, action := range actionsToSend {
+			switch action {
+			case actions.Left:
+				b.track.sent(currentStamp, -1, now)
+			case actions.Right:
+				b.track.sent(currentStamp, 1, now)
+			}
+			stampedActions = append(stampedActions, input.StampedAction{Val: action, Tick: currentStamp})
+		}
+		if b.predict != nil {
+			b.predict.sent(stampedActions...)
 		}
+		// Offer binary until the server switches to it, actions sent before
+		// the bot's player spawned are dropped
+		if !b.binary {
+			stampedActions = append(stampedActions, snapshot.OfferAction(currentStamp, snapshot.CodecBinary, snapshot.CodecJSON))
+		}
+		if b.decoded.Tick > 0 && b.decoded != b.acked {
+			stampedActions = append(stampedActions, b.decoded.Action(currentStamp))
+			b.acked = b.decoded
+		}
+		currentConn := b.conn
+		isRunning := b.running
+		b.mutex.Unlock()
 
-		if shouldSendAction {
-			b.mutex.Lock()
-			currentStamp := b.actionStamp
-			b.actionStamp++
-			currentConn := b.conn
-			isRunning := b.running
-			b.mutex.Unlock()
+		if !isRunning {
+			return
+		}
+		if currentConn == nil || len(stampedActions) == 0 {
+			continue
+		}
 
-			if !isRunning {
+		err := sendActions(currentConn, stampedActions)
+		if err != nil {
+			if !b.drop(currentConn, "Send error for actions %v: %v", actionsToSend, err) {
 				return
 			}
-			if currentConn == nil {
-				continue
-			}
-
-			stampedAction := input.StampedAction{Val: actionToSend, Tick: currentStamp}
+			continue
+		}
+	}
+}
 
-			actionMsg := input.ClientActionMessage{ReceiverIndex: 0, Actions: []input.StampedAction{stampedAction}}
-			msgData, err := json.Marshal(actionMsg)
-			if err != nil {
-				log.Printf("[Bot %d] Marshal error for action %v: %v. Skipping.", b.id, actionToSend, err)
-				continue
-			}
+// sendActions frames stamped actions the way the drip server reads them and
+// writes them to conn.
+func sendActions(conn net.Conn, stamped []input.StampedAction) error {
+	msgData, err := json.Marshal(input.ClientActionMessage{ReceiverIndex: 0, Actions: stamped})
+	if err != nil {
+		return err
+	}
 
-			msgLen := uint32(len(msgData))
-			prefixBytes := make([]byte, lengthPrefixBytes)
-			binary.BigEndian.PutUint32(prefixBytes, msgLen)
-			framedMsg := append(prefixBytes, msgData...)
-
-			err = currentConn.SetWriteDeadline(time.Now().Add(writeDeadline))
-			if err != nil {
-				if b.IsRunning() {
-					log.Printf("[Bot %d] Set deadline error: %v. Stopping.", b.id, err)
-					b.Stop()
-				}
-				return
-			}
+	msgLen := uint32(len(msgData))
+	prefixBytes := make([]byte, lengthPrefixBytes)
+	binary.BigEndian.PutUint32(prefixBytes, msgLen)
+	framedMsg := append(prefixBytes, msgData...)
 
-			_, err = currentConn.Write(framedMsg)
-			if err != nil {
-				if b.IsRunning() {
-					log.Printf("[Bot %d] Send error for action %v: %v. Stopping.", b.id, actionToSend, err)
-					b.Stop()
-				}
-				return
-			}
-		}
+	err = conn.SetWriteDeadline(time.Now().Add(writeDeadline))
+	if err != nil {
+		return err
 	}
+	_, err = conn.Write(framedMsg)
+	return err
 }
 
 const BOT_COUNT = 120
@@ -306,78 +512,117 @@ func main() {
 	// Parse command line flags.
 	numBots := flag.Int("bots", BOT_COUNT, "Number of bot clients to create")
 	serverAddr := flag.String("server", "localhost:8080", "Server address (host:port)")
+	tps := flag.Int("tps", 60, "Server ticks per second, used to stamp actions until the server reports its own")
+	duration := flag.Duration("duration", 0, "Run for this long then report (0 runs until Ctrl+C)")
+	scenarioPath := flag.String("scenario", "", "Scenario file (.toml or .json), replaces -bots and -duration")
+	reportFormat := flag.String("report", "text", "End of run report format (text or json)")
+	reportFile := flag.String("report-file", "", "Write the report to this file instead of stdout")
+	behaviorFlag := flag.String("behavior", "wander",
+		fmt.Sprintf("Behavior or percentage mix, e.g. jumper:50,chase:50 (available: %s)", strings.Join(behaviorNames(), ", ")))
+	scriptPath := flag.String("script", "", "Timeline file for the script behavior")
+	sessionAddr := flag.String("session", "", "Server session endpoint (host:port, usually "+session.DEFAULT_ADDRESS+"), empty disables sessions")
+	reconnect := flag.Bool("reconnect", true, "Reconnect with backoff when a connection drops instead of stopping the bot")
 	flag.Parse()
 
-	log.Printf("--- Bot Swarm Starting ---")
-	log.Printf("Server: %s, Bots: %d", *serverAddr, *numBots)
+	if *reportFormat != "text" && *reportFormat != "json" {
+		log.Fatalf("Unknown report format %q (expected text or json)", *reportFormat)
+	}
 
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	bots := make([]*BotClient, 0, *numBots)
-	var wg sync.WaitGroup
+	scenario := DefaultScenario(*numBots, *duration)
+	if *scenarioPath != "" {
+		var err error
+		scenario, err = LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Invalid scenario:\n%v", err)
+		}
+	}
+	if scenario.Behavior != "" {
+		*behaviorFlag = scenario.Behavior
+	}
 
-	log.Printf("Launching bots...")
-	launchedCount := 0
-	for i := 0; i < *numBots; i++ {
-		bot, err := NewBotClient(i, *serverAddr)
+	mix, err := ParseBehaviorMix(*behaviorFlag)
+	if err != nil {
+		log.Fatalf("Invalid -behavior: %v", err)
+	}
+	opts := behaviorOptions{}
+	if *scriptPath != "" {
+		opts.script, err = LoadTimeline(*scriptPath)
 		if err != nil {
-			continue
+			log.Fatalf("Failed to load script: %v", err)
 		}
-		bots = append(bots, bot)
-		launchedCount++
-		wg.Add(1)
-		go func(b *BotClient) {
-			defer wg.Done()
-			b.Start()
-			monitorTicker := time.NewTicker(monitorCheckInterval)
-			defer monitorTicker.Stop()
-			for range monitorTicker.C {
-				if !b.IsRunning() {
-					break
-				}
-			}
-		}(bot)
-		time.Sleep(time.Duration(rand.Intn(50)+50) * time.Millisecond)
+	} else if mix.Uses("script") {
+		log.Fatalf("The script behavior needs a timeline file (-script)")
 	}
-	log.Printf("--- %d bots launched ---", launchedCount)
+
+	log.Printf("--- Bot Swarm Starting ---")
+	log.Printf("Server: %s, Scenario: %s, Peak bots: %d, Behavior: %s", *serverAddr, scenario.Name, scenario.Peak(), *behaviorFlag)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	stop := make(chan struct{})
+	go func() {
+		sig := <-sigChan
+		log.Printf("--- Received signal: %v. Shutting down... ---", sig)
+		close(stop)
+	}()
 	log.Println("Bot swarm running. Press Ctrl+C to stop.")
 
-	sig := <-sigChan
-	log.Printf("--- Received signal: %v. Shutting down... ---", sig)
-
-	log.Printf("Stopping %d bots...", len(bots))
-	for _, bot := range bots {
-		bot.Stop()
+	cfg := botConfig{server: *serverAddr, session: *sessionAddr, tps: *tps, reconnect: *reconnect}
+	swarm := NewSwarm(cfg, mix, opts, scenario.Peak())
+	elapsed, interrupted := swarm.Run(scenario, stop)
+	if !interrupted {
+		log.Printf("--- Scenario finished after %v. Shutting down... ---", elapsed.Round(time.Second))
 	}
 
 	log.Println("Waiting for bots to finish...")
-	waitChan := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(waitChan)
-	}()
-
-	select {
-	case <-waitChan:
+	if swarm.Stop(shutdownWaitTimeout) {
 		log.Println("--- All bot goroutines finished gracefully. ---")
-	case <-time.After(shutdownWaitTimeout):
+	} else {
 		log.Println("--- WARNING: Timeout waiting for bots to finish. ---")
 	}
 	log.Println("--- Bot swarm shutdown complete ---")
+
+	report := swarm.Report(elapsed)
+	report.Scenario = scenario.Name
+	code, err := finish(report, scenario.Thresholds, interrupted && *scenarioPath != "", *reportFormat, *reportFile)
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	os.Exit(code)
 }
 
-// String returns a human-readable representation of the BotState.
-func (s BotState) String() string {
-	switch s {
-	case StateIdle:
-		return "Idle"
-	case StateMovingLeft:
-		return "MovingLeft"
-	case StateMovingRight:
-		return "MovingRight"
-	default:
-		return fmt.Sprintf("UnknownState(%d)", int(s))
+// finish checks the report against the thresholds, writes it and returns the
+// run's exit code: 1 when a threshold was exceeded or the scenario was cut short.
+func finish(report Report, thresholds Thresholds, interrupted bool, format, path string) (int, error) {
+	report.Violations = thresholds.Check(report)
+	if interrupted {
+		report.Violations = append(report.Violations, "scenario was interrupted before it finished")
+	}
+
+	err := writeReport(report, format, path)
+	if err != nil {
+		return 1, err
+	}
+	if !report.Passed() {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// writeReport writes the report to path, or stdout when path is empty.
+func writeReport(report Report, format, path string) error {
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "json" {
+		return report.WriteJSON(w)
 	}
+	return report.WriteText(w)
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// maxReplayTicks is the longest gap between two frames the predictor replays,
+// after a longer one it starts over from the newer frame.
+const maxReplayTicks = 30
+
+// predictor checks a bot's own entity against the server's checksums the way a
+// client checks its prediction.
+//
+// It keeps a headless copy of the bot's scene holding just the bot's player.
+// Every frame rewinds that player to its authoritative state, the next frame's
+// state is predicted by replaying the inputs the server received in between.
+// Binary frames ack the newest input stamp the server received, so the inputs
+// are replayed on the tick the server applied them. On JSON streams they are
+// assumed to arrive on the tick they were stamped with.
+//
+// Other players are not part of the copy, pushes from them show up as desyncs.
+type predictor struct {
+	sim     *headless.Simulation
+	player  warehouse.Entity
+	pending []input.StampedAction // Sent, oldest first, not yet replayed.
+	acked   int                   // Newest input stamp the server acked.
+
+	base     snapshot.EntityState
+	baseTick int
+	hasBase  bool
+}
+
+func newPredictor() *predictor {
+	return &predictor{acked: -1}
+}
+
+// sent notes inputs the bot sent to the server.
+func (p *predictor) sent(stamped ...input.StampedAction) {
+	p.pending = append(p.pending, stamped...)
+}
+
+// check predicts the state of the bot's entity own at the frame's tick and
+// rewinds to the frame. It returns the prediction next to the server's state
+// and reports false when there was nothing to predict from, such as the first
+// frame of a scene.
+func (p *predictor) check(frame snapshot.Frame, binary bool, own uint32) (predicted, auth snapshot.EntityState, ok bool, err error) {
+	tick := frame.Snapshot.Tick
+	if binary {
+		if ack, acked := frame.Ack(own); acked {
+			p.acked = max(p.acked, ack)
+		}
+	}
+
+	auth, found := frame.Snapshot.Find(own)
+	if !found {
+		p.hasBase = false
+		return predicted, auth, false, nil
+	}
+
+	if p.sim == nil || p.sim.Name() != frame.Scene {
+		err := p.load(frame.Scene, auth)
+		if err != nil {
+			return predicted, auth, false, err
+		}
+	}
+	p.sim.SetTickRate(frame.TickRate)
+
+	if p.hasBase && tick > p.baseTick && tick-p.baseTick <= maxReplayTicks {
+		predicted, err = p.replay(tick, binary)
+		if err != nil {
+			return predicted, auth, false, err
+		}
+		predicted.ID = auth.ID
+		ok = true
+	}
+
+	// Inputs the server already applied are part of auth
+	p.take(func(action input.StampedAction) bool {
+		if binary {
+			return action.Tick <= p.acked
+		}
+		return action.Tick <= tick
+	})
+	p.base, p.baseTick, p.hasBase = auth, tick, true
+	return predicted, auth, ok, nil
+}
+
+// replay steps the copy from the previous frame up to tick.
+func (p *predictor) replay(tick int, binary bool) (snapshot.EntityState, error) {
+	err := snapshot.Apply(p.player, p.base)
+	if err != nil {
+		return snapshot.EntityState{}, err
+	}
+	buffer := input.Components.ActionBuffer.GetFromEntity(p.player)
+	buffer.Values = buffer.Values[:0]
+
+	p.sim.SetTick(p.baseTick + 1)
+	for t := p.baseTick + 1; t <= tick; t++ {
+		arrived := p.take(func(action input.StampedAction) bool {
+			if binary {
+				// Only the frame of tick says what arrived since the last one
+				return t == tick && action.Tick <= p.acked
+			}
+			return action.Tick <= t
+		})
+		p.sim.Deliver(p.player, arrived...)
+
+		err := p.sim.Step()
+		if err != nil {
+			return snapshot.EntityState{}, err
+		}
+	}
+	return p.sim.State(p.player), nil
+}
+
+// take removes and returns the pending inputs matching arrived.
+func (p *predictor) take(arrived func(input.StampedAction) bool) []input.StampedAction {
+	taken := []input.StampedAction{}
+	kept := p.pending[:0]
+	for _, action := range p.pending {
+		if arrived(action) {
+			taken = append(taken, action)
+			continue
+		}
+		kept = append(kept, action)
+	}
+	p.pending = kept
+	return taken
+}
+
+// load builds the headless copy of scene with the bot's player at auth.
+func (p *predictor) load(scene string, auth snapshot.EntityState) error {
+	p.sim, p.hasBase = nil, false
+	sim, err := headless.FromScene(scene)
+	if err != nil {
+		return err
+	}
+	player, err := sim.SpawnPlayer(auth.Position.X, auth.Position.Y)
+	if err != nil {
+		return err
+	}
+	p.sim, p.player = sim, player
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// botStats is what a single bot measured, guarded by the bot's mutex.
+type botStats struct {
+	snapshots    int
+	bytes        uint64
+	decodeErrors int
+	latencies    []time.Duration
+	dropped      bool // Lost its connection and did not get it back.
+	drops        int
+	reconnects   int
+	resumed      int // Reconnects that got the bot's player back.
+}
+
+// Report summarises a swarm run.
+type Report struct {
+	Scenario           string          `json:"scenario"`
+	DurationSeconds    float64         `json:"duration_seconds"`
+	Requested          int             `json:"bots_peak"`
+	Launched           int             `json:"sessions"`
+	Identified         int             `json:"bots_identified"`
+	Disconnections     int             `json:"disconnections"`
+	ConnectFailures    int             `json:"connect_failures"`
+	Churned            int             `json:"churned"`
+	Drops              int             `json:"drops"`
+	Reconnects         int             `json:"reconnects"`
+	Resumed            int             `json:"sessions_resumed"`
+	DisconnectRate     float64         `json:"disconnect_rate"`
+	Snapshots          int             `json:"snapshots"`
+	SnapshotsPerSecond float64         `json:"snapshots_per_second_per_bot"`
+	BytesReceived      uint64          `json:"bytes_received"`
+	DecodeErrors       int             `json:"decode_errors"`
+	Behaviors          map[string]int  `json:"behaviors"`
+	Latency            LatencyReport   `json:"latency"`
+	Integrity          IntegrityReport `json:"integrity"`
+	Desync             DesyncReport    `json:"desync"`
+	Violations         []string        `json:"violations"`
+	Bots               []BotReport     `json:"bots"`
+}
+
+// Passed reports whether the run stayed within its thresholds.
+func (r Report) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// LatencyReport is input-to-acknowledgement latency in milliseconds.
+type LatencyReport struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// IntegrityReport counts decoded states that did not hash to the checksum sent
+// with them, a sign of a corrupted stream or a codec bug.
+type IntegrityReport struct {
+	Checked int     `json:"checked"`
+	Corrupt int     `json:"corrupt"`
+	Rate    float64 `json:"rate"`
+}
+
+// DesyncReport counts bot predictions that did not match the server's checksum
+// of the same tick, ByComponent names the components that differed.
+type DesyncReport struct {
+	Checked     int            `json:"checked"`
+	Desyncs     int            `json:"desyncs"`
+	Rate        float64        `json:"rate"`
+	ByComponent map[string]int `json:"by_component,omitempty"`
+}
+
+func desyncReport(stats snapshot.DesyncStats) DesyncReport {
+	return DesyncReport{Checked: stats.Checked, Desyncs: stats.Desyncs, Rate: stats.Rate(), ByComponent: stats.ByComponent}
+}
+
+// BotReport is a single bot's view of the run.
+type BotReport struct {
+	ID           int          `json:"id"`
+	Behavior     string       `json:"behavior"`
+	Entity       uint32       `json:"entity,omitempty"`
+	Identified   bool         `json:"identified"`
+	Position     vector.Two   `json:"position"`
+	Snapshots    int          `json:"snapshots"`
+	Bytes        uint64       `json:"bytes"`
+	Disconnected bool         `json:"disconnected"`
+	Reconnects   int          `json:"reconnects"`
+	Desync       DesyncReport `json:"desync"`
+}
+
+// buildReport collects the stats of stopped bots.
+func buildReport(bots []*BotClient, requested int, elapsed time.Duration, integrity *snapshot.DesyncDetector) Report {
+	report := Report{
+		DurationSeconds: elapsed.Seconds(),
+		Requested:       requested,
+		Launched:        len(bots),
+		Behaviors:       map[string]int{},
+	}
+
+	latencies := []time.Duration{}
+	report.Desync.ByComponent = map[string]int{}
+	for _, b := range bots {
+		b.mutex.Lock()
+		stats := b.stats
+		bot := BotReport{
+			ID:           b.id,
+			Behavior:     b.behaviorName,
+			Entity:       b.track.own,
+			Identified:   b.track.hasOwn,
+			Position:     b.track.position,
+			Snapshots:    stats.snapshots,
+			Bytes:        stats.bytes,
+			Disconnected: stats.dropped,
+			Reconnects:   stats.reconnects,
+			Desync:       desyncReport(b.desyncs.Stats()),
+		}
+		latencies = append(latencies, stats.latencies...)
+		b.mutex.Unlock()
+
+		report.Behaviors[bot.Behavior]++
+		if bot.Identified {
+			report.Identified++
+		}
+		if bot.Disconnected {
+			report.Disconnections++
+		}
+		report.Drops += stats.drops
+		report.Reconnects += stats.reconnects
+		report.Resumed += stats.resumed
+		report.Snapshots += stats.snapshots
+		report.BytesReceived += stats.bytes
+		report.DecodeErrors += stats.decodeErrors
+		report.Desync.Checked += bot.Desync.Checked
+		report.Desync.Desyncs += bot.Desync.Desyncs
+		for name, n := range bot.Desync.ByComponent {
+			report.Desync.ByComponent[name] += n
+		}
+		report.Bots = append(report.Bots, bot)
+	}
+
+	if len(bots) > 0 && elapsed > 0 {
+		report.SnapshotsPerSecond = float64(report.Snapshots) / elapsed.Seconds() / float64(len(bots))
+	}
+	report.Latency = latencyReport(latencies)
+	if report.Desync.Checked > 0 {
+		report.Desync.Rate = float64(report.Desync.Desyncs) / float64(report.Desync.Checked)
+	}
+
+	stats := integrity.Stats()
+	report.Integrity = IntegrityReport{Checked: stats.Checked, Corrupt: stats.Desyncs, Rate: stats.Rate()}
+	return report
+}
+
+func latencyReport(samples []time.Duration) LatencyReport {
+	n := len(samples)
+	if n == 0 {
+		return LatencyReport{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	at := func(q float64) float64 {
+		return milliseconds(sorted[int(q*float64(n-1))])
+	}
+	return LatencyReport{
+		Samples: n,
+		P50:     at(0.50),
+		P95:     at(0.95),
+		P99:     at(0.99),
+		Max:     milliseconds(sorted[n-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteText writes the human readable summary.
+func (r Report) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `--- Bot Swarm Report: %s ---
+Duration:        %.1fs
+Bots:            %d sessions for a peak of %d, %d identified their entity
+Behaviors:       %s
+Disconnections:  %d dropped, %d failed to connect (%.2f%%), %d churned
+Reconnects:      %d of %d drops, %d resumed their player
+Snapshots:       %d (%.1f/s per bot)
+Bytes received:  %d
+Decode errors:   %d
+Latency:         p50 %.1fms  p95 %.1fms  p99 %.1fms  max %.1fms (%d samples)
+Corrupt states:  %d of %d checks (%.2f%%)
+Desyncs:         %d of %d predictions (%.2f%%)
+`,
+		r.Scenario,
+		r.DurationSeconds,
+		r.Launched, r.Requested, r.Identified,
+		r.behaviorSummary(),
+		r.Disconnections, r.ConnectFailures, r.DisconnectRate*100, r.Churned,
+		r.Reconnects, r.Drops, r.Resumed,
+		r.Snapshots, r.SnapshotsPerSecond,
+		r.BytesReceived,
+		r.DecodeErrors,
+		r.Latency.P50, r.Latency.P95, r.Latency.P99, r.Latency.Max, r.Latency.Samples,
+		r.Integrity.Corrupt, r.Integrity.Checked, r.Integrity.Rate*100,
+		r.Desync.Desyncs, r.Desync.Checked, r.Desync.Rate*100,
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.Passed() {
+		_, err = fmt.Fprintln(w, "Thresholds:      passed")
+		return err
+	}
+	_, err = fmt.Fprintln(w, "Thresholds:      FAILED")
+	for _, violation := range r.Violations {
+		_, err = fmt.Fprintf(w, "  - %s\n", violation)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the full report, including every bot.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// behaviorSummary lists the bots per behavior, e.g. "chase 10, jumper 20".
+func (r Report) behaviorSummary() string {
+	names := make([]string, 0, len(r.Behaviors))
+	for name := range r.Behaviors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d", name, r.Behaviors[name]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Scenario describes a load test: a sequence of stages that move the swarm
+// towards a bot count, and the thresholds the run has to stay within.
+//
+// Scenarios are TOML or JSON files, picked by extension. See
+// example.scenario.toml.
+type Scenario struct {
+	Name       string     `toml:"name" json:"name"`
+	Behavior   string     `toml:"behavior" json:"behavior"` // Overrides -behavior when set.
+	Stages     []Stage    `toml:"stages" json:"stages"`
+	Thresholds Thresholds `toml:"thresholds" json:"thresholds"`
+}
+
+// Stage moves the swarm from the previous stage's bot count to Bots over
+// Duration, following Curve. A zero duration jumps straight to Bots, on the
+// last stage it then holds until the run is interrupted.
+type Stage struct {
+	Name     string   `toml:"name" json:"name"`
+	Duration Duration `toml:"duration" json:"duration"`
+	Bots     int      `toml:"bots" json:"bots"`
+	Curve    Curve    `toml:"curve" json:"curve"`
+
+	// Churn disconnects this many random bots per minute, each reconnecting as
+	// a new bot after ReconnectDelay, which resumes the old player if it can.
+	Churn          float64  `toml:"churn_per_minute" json:"churn_per_minute"`
+	ReconnectDelay Duration `toml:"reconnect_delay" json:"reconnect_delay"`
+}
+
+// Thresholds fail the run when exceeded, unset thresholds are not checked.
+type Thresholds struct {
+	MaxP99Latency     Duration `toml:"max_p99_latency" json:"max_p99_latency"`
+	MaxDisconnectRate *float64 `toml:"max_disconnect_rate" json:"max_disconnect_rate,omitempty"`
+	MaxCorruptRate    *float64 `toml:"max_corrupt_rate" json:"max_corrupt_rate,omitempty"`
+	MaxDesyncRate     *float64 `toml:"max_desync_rate" json:"max_desync_rate,omitempty"`
+}
+
+// Curve shapes a stage's ramp.
+type Curve string
+
+const (
+	CurveLinear      Curve = "linear"      // Evenly over the stage (the default).
+	CurveStep        Curve = "step"        // All at once when the stage starts.
+	CurveExponential Curve = "exponential" // Slowly at first, then quickly.
+)
+
+// Duration is a time.Duration written as a string such as "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// LoadScenario reads a scenario file.
+func LoadScenario(path string) (Scenario, error) {
+	var scenario Scenario
+
+	f, err := os.Open(path)
+	if err != nil {
+		return scenario, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		md, err := toml.NewDecoder(f).Decode(&scenario)
+		if err != nil {
+			return scenario, fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return scenario, fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	case ".json":
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&scenario)
+		if err != nil {
+			return scenario, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return scenario, fmt.Errorf("%s: unsupported scenario format (expected .toml or .json)", path)
+	}
+
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return scenario, scenario.Validate()
+}
+
+// DefaultScenario is the classic swarm: ramp up to bots at roughly the old
+// launch jitter, then hold for duration (or until interrupted when zero).
+func DefaultScenario(bots int, duration time.Duration) Scenario {
+	return Scenario{
+		Name: "default",
+		Stages: []Stage{
+			{Name: "ramp", Bots: bots, Duration: Duration{time.Duration(bots) * 75 * time.Millisecond}},
+			{Name: "hold", Bots: bots, Duration: Duration{duration}},
+		},
+	}
+}
+
+// Validate reports every problem with the scenario at once.
+func (s Scenario) Validate() error {
+	var errs []error
+
+	if len(s.Stages) == 0 {
+		errs = append(errs, errors.New("stages: at least one stage is required"))
+	}
+	for i, stage := range s.Stages {
+		name := stage.label(i)
+		if stage.Bots < 0 {
+			errs = append(errs, fmt.Errorf("%s: bots must not be negative", name))
+		}
+		if stage.Duration.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s: duration must not be negative", name))
+		}
+		switch stage.Curve {
+		case "", CurveLinear, CurveStep, CurveExponential:
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown curve %q (expected linear, step or exponential)", name, stage.Curve))
+		}
+		if stage.Churn < 0 || stage.ReconnectDelay.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s: churn must not be negative", name))
+		}
+	}
+
+	t := s.Thresholds
+	if t.MaxP99Latency.Duration < 0 {
+		errs = append(errs, errors.New("thresholds: max_p99_latency must not be negative"))
+	}
+	if t.MaxDisconnectRate != nil && (*t.MaxDisconnectRate < 0 || *t.MaxDisconnectRate > 1) {
+		errs = append(errs, errors.New("thresholds: max_disconnect_rate must be between 0 and 1"))
+	}
+	if t.MaxCorruptRate != nil && (*t.MaxCorruptRate < 0 || *t.MaxCorruptRate > 1) {
+		errs = append(errs, errors.New("thresholds: max_corrupt_rate must be between 0 and 1"))
+	}
+	if t.MaxDesyncRate != nil && (*t.MaxDesyncRate < 0 || *t.MaxDesyncRate > 1) {
+		errs = append(errs, errors.New("thresholds: max_desync_rate must be between 0 and 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Peak is the highest bot count the scenario asks for.
+func (s Scenario) Peak() int {
+	peak := 0
+	for _, stage := range s.Stages {
+		peak = max(peak, stage.Bots)
+	}
+	return peak
+}
+
+func (stage Stage) label(i int) string {
+	if stage.Name != "" {
+		return fmt.Sprintf("stage %q", stage.Name)
+	}
+	return fmt.Sprintf("stage %d", i+1)
+}
+
+// Target is the bot count elapsed into the stage, starting from the previous
+// stage's count.
+func (stage Stage) Target(from int, elapsed time.Duration) int {
+	progress := 1.0
+	if stage.Duration.Duration > 0 {
+		progress = min(float64(elapsed)/float64(stage.Duration.Duration), 1)
+	}
+
+	switch stage.Curve {
+	case CurveStep:
+		progress = 1
+	case CurveExponential:
+		const k = 4.0
+		progress = (math.Exp(k*progress) - 1) / (math.Exp(k) - 1)
+	}
+	return from + int(math.Round(float64(stage.Bots-from)*progress))
+}
+
+// Check returns a description of every threshold the report exceeded.
+func (t Thresholds) Check(report Report) []string {
+	violations := []string{}
+
+	if limit := t.MaxP99Latency.Duration; limit > 0 {
+		if report.Latency.Samples == 0 {
+			violations = append(violations, "p99 latency: no latency samples were collected")
+		} else if p99 := report.Latency.P99; p99 > milliseconds(limit) {
+			violations = append(violations, fmt.Sprintf("p99 latency %.1fms exceeds %v", p99, limit))
+		}
+	}
+	if t.MaxDisconnectRate != nil && report.DisconnectRate > *t.MaxDisconnectRate {
+		violations = append(violations, fmt.Sprintf("disconnect rate %.2f%% exceeds %.2f%%",
+			report.DisconnectRate*100, *t.MaxDisconnectRate*100))
+	}
+	if t.MaxCorruptRate != nil && report.Integrity.Rate > *t.MaxCorruptRate {
+		violations = append(violations, fmt.Sprintf("corrupt state rate %.2f%% exceeds %.2f%%",
+			report.Integrity.Rate*100, *t.MaxCorruptRate*100))
+	}
+	if t.MaxDesyncRate != nil && report.Desync.Rate > *t.MaxDesyncRate {
+		violations = append(violations, fmt.Sprintf("desync rate %.2f%% exceeds %.2f%%",
+			report.Desync.Rate*100, *t.MaxDesyncRate*100))
+	}
+	return violations
+}
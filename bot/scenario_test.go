@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func rate(r float64) *float64 {
+	return &r
+}
+
+func TestThresholdsCheck(t *testing.T) {
+	report := Report{
+		Latency:        LatencyReport{Samples: 100, P99: 180},
+		DisconnectRate: 0.05,
+		Integrity:      IntegrityReport{Checked: 100, Corrupt: 1, Rate: 0.01},
+		Desync:         DesyncReport{Checked: 100, Desyncs: 20, Rate: 0.2},
+	}
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		report     Report
+		want       []string
+	}{
+		{
+			name:   "nothing set",
+			report: report,
+			want:   []string{},
+		},
+		{
+			name:       "within every threshold",
+			thresholds: Thresholds{MaxP99Latency: Duration{200 * time.Millisecond}, MaxDisconnectRate: rate(0.1), MaxCorruptRate: rate(0.02), MaxDesyncRate: rate(0.25)},
+			report:     report,
+			want:       []string{},
+		},
+		{
+			name:       "p99 latency",
+			thresholds: Thresholds{MaxP99Latency: Duration{150 * time.Millisecond}},
+			report:     report,
+			want:       []string{"p99 latency 180.0ms exceeds 150ms"},
+		},
+		{
+			name:       "no latency samples",
+			thresholds: Thresholds{MaxP99Latency: Duration{150 * time.Millisecond}},
+			report:     Report{},
+			want:       []string{"p99 latency: no latency samples were collected"},
+		},
+		{
+			name:       "disconnect rate",
+			thresholds: Thresholds{MaxDisconnectRate: rate(0.02)},
+			report:     report,
+			want:       []string{"disconnect rate 5.00% exceeds 2.00%"},
+		},
+		{
+			name:       "corrupt rate",
+			thresholds: Thresholds{MaxCorruptRate: rate(0)},
+			report:     report,
+			want:       []string{"corrupt state rate 1.00% exceeds 0.00%"},
+		},
+		{
+			name:       "desync rate",
+			thresholds: Thresholds{MaxDesyncRate: rate(0.1)},
+			report:     report,
+			want:       []string{"desync rate 20.00% exceeds 10.00%"},
+		},
+		{
+			name:       "every threshold",
+			thresholds: Thresholds{MaxP99Latency: Duration{150 * time.Millisecond}, MaxDisconnectRate: rate(0.02), MaxCorruptRate: rate(0), MaxDesyncRate: rate(0.1)},
+			report:     report,
+			want: []string{
+				"p99 latency 180.0ms exceeds 150ms",
+				"disconnect rate 5.00% exceeds 2.00%",
+				"corrupt state rate 1.00% exceeds 0.00%",
+				"desync rate 20.00% exceeds 10.00%",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.thresholds.Check(tt.report)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinishExitCode(t *testing.T) {
+	passing := Report{Latency: LatencyReport{Samples: 10, P99: 50}}
+	limits := Thresholds{MaxP99Latency: Duration{100 * time.Millisecond}}
+
+	tests := []struct {
+		name           string
+		report         Report
+		interrupted    bool
+		wantCode       int
+		wantViolations int
+	}{
+		{name: "passed", report: passing, wantCode: 0, wantViolations: 0},
+		{name: "threshold exceeded", report: Report{Latency: LatencyReport{Samples: 10, P99: 150}}, wantCode: 1, wantViolations: 1},
+		{name: "interrupted", report: passing, interrupted: true, wantCode: 1, wantViolations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.json")
+			code, err := finish(tt.report, limits, tt.interrupted, "json", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var written Report
+			err = json.Unmarshal(data, &written)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(written.Violations) != tt.wantViolations {
+				t.Errorf("report lists violations %q, want %d", written.Violations, tt.wantViolations)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+)
+
+// Timeline is a scripted sequence of held actions, loaded from a text file with
+// one step per line:
+//
+//	# offset  actions
+//	0s        right
+//	1.5s      right jump
+//	2s        idle
+//	4s        loop
+//
+// Each step's actions are held from its offset until the next step. A final
+// "loop" step restarts the timeline at that offset, without one the last step
+// is held forever.
+type Timeline struct {
+	Steps []TimelineStep
+	Loop  time.Duration // Zero when the timeline does not loop.
+}
+
+// TimelineStep is the set of actions held from At.
+type TimelineStep struct {
+	At      time.Duration
+	Actions []input.Action
+}
+
+// LoadTimeline reads a timeline file.
+func LoadTimeline(path string) (*Timeline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	timeline := &Timeline{}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if timeline.Loop > 0 {
+			return nil, fmt.Errorf("%s:%d: step after loop", path, line)
+		}
+
+		at, err := time.ParseDuration(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if n := len(timeline.Steps); n > 0 && at < timeline.Steps[n-1].At {
+			return nil, fmt.Errorf("%s:%d: offset %v is before the previous step", path, line, at)
+		}
+
+		step := TimelineStep{At: at}
+		for _, name := range fields[1:] {
+			name = strings.ToLower(name)
+			switch name {
+			case "idle":
+			case "loop":
+				if at == 0 {
+					return nil, fmt.Errorf("%s:%d: loop needs a positive offset", path, line)
+				}
+				timeline.Loop = at
+			default:
+				action, ok := actions.ByName[name]
+				if !ok {
+					return nil, fmt.Errorf("%s:%d: unknown action %q", path, line, name)
+				}
+				step.Actions = append(step.Actions, action)
+			}
+		}
+		if timeline.Loop == 0 {
+			timeline.Steps = append(timeline.Steps, step)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(timeline.Steps) == 0 {
+		return nil, fmt.Errorf("%s: timeline has no steps", path)
+	}
+	return timeline, nil
+}
+
+// At returns the actions held at elapsed time into the timeline.
+func (t *Timeline) At(elapsed time.Duration) []input.Action {
+	if t.Loop > 0 {
+		elapsed %= t.Loop
+	}
+	current := []input.Action(nil)
+	for _, step := range t.Steps {
+		if step.At > elapsed {
+			break
+		}
+		current = step.Actions
+	}
+	return current
+}
+
+// scripted plays a timeline from the moment the bot starts acting.
+type scripted struct {
+	timeline *Timeline
+	started  time.Time
+}
+
+func newScripted(opts behaviorOptions) (Behavior, error) {
+	if opts.script == nil {
+		return nil, fmt.Errorf("the script behavior needs a timeline file (-script)")
+	}
+	return &scripted{timeline: opts.script}, nil
+}
+
+func (s *scripted) Next(view View, now time.Time) []input.Action {
+	if s.started.IsZero() {
+		s.started = now
+	}
+	return s.timeline.At(now.Sub(s.started))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// controlInterval is how often the swarm moves towards its target bot count.
+const controlInterval = 100 * time.Millisecond
+
+// Swarm runs a scenario, launching and stopping bots to follow its stages.
+type Swarm struct {
+	cfg  botConfig
+	mix  BehaviorMix
+	opts behaviorOptions
+	peak int
+
+	mutex           sync.Mutex // Protects everything below.
+	active          []*BotClient
+	sessions        []*BotClient
+	connecting      int // Launched bots still dialing.
+	reconnecting    int // Churned bots waiting to reconnect.
+	nextID          int
+	connectFailures int
+	churned         int
+	stopped         bool
+
+	wg sync.WaitGroup
+}
+
+func NewSwarm(cfg botConfig, mix BehaviorMix, opts behaviorOptions, peak int) *Swarm {
+	return &Swarm{cfg: cfg, mix: mix, opts: opts, peak: max(peak, 1)}
+}
+
+// Run follows the scenario's stages until they finish or stop is closed. It
+// reports how long it ran and whether it was interrupted.
+func (s *Swarm) Run(scenario Scenario, stop <-chan struct{}) (time.Duration, bool) {
+	started := time.Now()
+	ticker := time.NewTicker(controlInterval)
+	defer ticker.Stop()
+
+	from := 0
+	for i, stage := range scenario.Stages {
+		log.Printf("--- Stage %s: %d -> %d bots over %v ---", stage.label(i), from, stage.Bots, stage.Duration)
+		stageStart := time.Now()
+		last := stageStart
+		holdForever := stage.Duration.Duration == 0 && i == len(scenario.Stages)-1
+		churnDebt := 0.0
+
+		for {
+			now := time.Now()
+			elapsed := now.Sub(stageStart)
+
+			s.scale(stage.Target(from, elapsed))
+
+			churnDebt += stage.Churn / 60 * now.Sub(last).Seconds()
+			last = now
+			for ; churnDebt >= 1; churnDebt-- {
+				s.churn(stage.ReconnectDelay.Duration)
+			}
+
+			if !holdForever && elapsed >= stage.Duration.Duration {
+				break
+			}
+
+			select {
+			case <-stop:
+				return time.Since(started), true
+			case <-ticker.C:
+			}
+		}
+		from = stage.Bots
+	}
+	return time.Since(started), false
+}
+
+// scale launches or stops bots to reach target.
+func (s *Swarm) scale(target int) {
+	s.mutex.Lock()
+	s.pruneLocked()
+	count := len(s.active) + s.connecting + s.reconnecting
+
+	var extra []*BotClient
+	if count > target && len(s.active) > 0 {
+		// Stop the newest bots first
+		n := min(count-target, len(s.active))
+		extra = append(extra, s.active[len(s.active)-n:]...)
+		s.active = s.active[:len(s.active)-n]
+	}
+	s.mutex.Unlock()
+
+	for i := count; i < target; i++ {
+		s.launch("")
+	}
+	for _, bot := range extra {
+		bot.Stop()
+	}
+}
+
+// churn disconnects a random bot and reconnects it after delay. The new bot
+// resumes the old one's player when the server still holds it.
+func (s *Swarm) churn(delay time.Duration) {
+	s.mutex.Lock()
+	s.pruneLocked()
+	if len(s.active) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+	i := rand.Intn(len(s.active))
+	bot := s.active[i]
+	s.active = append(s.active[:i], s.active[i+1:]...)
+	s.churned++
+	s.reconnecting++
+	s.mutex.Unlock()
+
+	log.Printf("[Bot %d] Churning, reconnecting in %v", bot.id, delay)
+	bot.Stop()
+	token := bot.Token()
+
+	time.AfterFunc(delay, func() {
+		s.mutex.Lock()
+		s.reconnecting--
+		s.mutex.Unlock()
+		s.launch(token)
+	})
+}
+
+// launch connects a new bot in the background, resuming token's session if set.
+func (s *Swarm) launch(token string) {
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	id := s.nextID
+	s.nextID++
+	s.connecting++
+	s.wg.Add(1)
+	s.mutex.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+
+		name, behavior, err := s.mix.NewBehavior(id%s.peak, s.peak, s.opts)
+		var bot *BotClient
+		if err == nil {
+			bot, err = NewBotClient(id, s.cfg, name, behavior, token)
+		}
+
+		s.mutex.Lock()
+		s.connecting--
+		if err == nil && s.stopped {
+			// Shut down while dialing
+			s.mutex.Unlock()
+			bot.Stop()
+			return
+		}
+		if err != nil {
+			s.connectFailures++
+			s.mutex.Unlock()
+			return
+		}
+		s.active = append(s.active, bot)
+		s.sessions = append(s.sessions, bot)
+		s.mutex.Unlock()
+
+		bot.Start()
+		monitorTicker := time.NewTicker(monitorCheckInterval)
+		defer monitorTicker.Stop()
+		for range monitorTicker.C {
+			if !bot.IsRunning() {
+				break
+			}
+		}
+	}()
+}
+
+// pruneLocked forgets bots that stopped on their own.
+func (s *Swarm) pruneLocked() {
+	running := s.active[:0]
+	for _, bot := range s.active {
+		if bot.IsRunning() {
+			running = append(running, bot)
+		}
+	}
+	s.active = running
+}
+
+// Stop stops every bot and waits up to timeout for them to finish.
+func (s *Swarm) Stop(timeout time.Duration) bool {
+	s.mutex.Lock()
+	s.stopped = true
+	bots := s.sessions
+	s.mutex.Unlock()
+
+	log.Printf("Stopping %d bots...", len(bots))
+	for _, bot := range bots {
+		bot.Stop()
+	}
+
+	waitChan := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(waitChan)
+	}()
+
+	select {
+	case <-waitChan:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Report summarises every session of the run.
+func (s *Swarm) Report(elapsed time.Duration) Report {
+	s.mutex.Lock()
+	sessions := append([]*BotClient(nil), s.sessions...)
+	failures := s.connectFailures
+	churned := s.churned
+	s.mutex.Unlock()
+
+	report := buildReport(sessions, s.peak, elapsed, integrity)
+	report.ConnectFailures = failures
+	report.Churned = churned
+	if attempts := report.Launched + failures; attempts > 0 {
+		report.DisconnectRate = float64(report.Disconnections+failures) / float64(attempts)
+	}
+	return report
+}
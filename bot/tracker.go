@@ -0,0 +1,235 @@
+package main
+
+import (
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// ackWindow is how long a direction change waits for its snapshot before it is
+// given up on.
+const ackWindow = time.Second
+
+// inputAckTimeout is how long a sent input waits for the server's ack before it
+// is given up on, inputs sent before the bot's player spawned are never acked.
+const inputAckTimeout = 10 * time.Second
+
+// tracker follows a bot's own entity through the decoded snapshots.
+//
+// Without a session the server does not tell a raw TCP client which entity is
+// its own, so the bot works it out: every time it turns, the entities that turn
+// the same way shortly after are candidates, and intersecting a few turns
+// leaves a single entity.
+//
+// Latency is measured from the input acks of binary frames: every input sent
+// is stamped, and the first ack of the bot's entity at or past the stamp
+// completes its sample. JSON frames carry no acks, there a turn showing up in
+// a snapshot acknowledges the input that caused it.
+type tracker struct {
+	tps int
+
+	// Latest snapshot tick and when it arrived, for stamping inputs
+	tick   int
+	tickAt time.Time
+	stamp  int
+
+	scene  string
+	latest snapshot.Snapshot
+
+	own        uint32
+	hasOwn     bool
+	claimed    bool // own came from the session endpoint
+	position   vector.Two
+	candidates map[uint32]struct{}
+
+	directions map[uint32]int8
+	lastDir    int8
+	pending    *directionChange
+
+	inputs []sentInput // Oldest first, waiting for an ack.
+}
+
+// sentInput is an input waiting for the server's ack
+type sentInput struct {
+	stamp int
+	sent  time.Time
+}
+
+// directionChange is a sent turn waiting to show up in a snapshot
+type directionChange struct {
+	stamp   int
+	sent    time.Time
+	dir     int8
+	flipped map[uint32]struct{}
+}
+
+func newTracker(tps int) *tracker {
+	return &tracker{tps: tps, directions: map[uint32]int8{}}
+}
+
+// setTickRate adopts the tick rate the server reports, zero keeps the current one
+func (t *tracker) setTickRate(tps int) {
+	if tps > 0 {
+		t.tps = tps
+	}
+}
+
+// nextStamp estimates the server tick for a message sent now, stamps never go
+// backwards
+func (t *tracker) nextStamp(now time.Time) int {
+	stamp := t.tick
+	if !t.tickAt.IsZero() {
+		stamp += int(now.Sub(t.tickAt).Seconds() * float64(t.tps))
+	}
+	if stamp < t.stamp {
+		stamp = t.stamp
+	}
+	t.stamp = stamp
+	return stamp
+}
+
+// sentInputs notes that inputs went out stamped with stamp. Its ack completes
+// a latency sample, so every input gets a stamp of its own
+func (t *tracker) sentInputs(now time.Time) int {
+	stamp := t.nextStamp(now)
+	if n := len(t.inputs); n > 0 && t.inputs[n-1].stamp >= stamp {
+		stamp = t.inputs[n-1].stamp + 1
+		t.stamp = stamp
+	}
+	t.inputs = append(t.inputs, sentInput{stamp: stamp, sent: now})
+	return stamp
+}
+
+// sent notes a horizontal input, only turns are tracked
+func (t *tracker) sent(stamp int, dir int8, now time.Time) {
+	if dir == t.lastDir {
+		return
+	}
+	t.lastDir = dir
+	t.resolve()
+	t.pending = &directionChange{stamp: stamp, sent: now, dir: dir, flipped: map[uint32]struct{}{}}
+}
+
+// observe applies a decoded frame and returns the latencies of the inputs it
+// acknowledged. binary frames carry input acks, JSON frames fall back to turns
+func (t *tracker) observe(frame snapshot.Frame, binary bool, now time.Time) []time.Duration {
+	snap := frame.Snapshot
+	t.tick = snap.Tick
+	t.tickAt = now
+	t.scene = frame.Scene
+	t.latest = snap
+
+	var latencies []time.Duration
+
+	directions := make(map[uint32]int8, len(snap.Entities))
+	for _, state := range snap.Entities {
+		directions[state.ID] = state.Direction
+
+		if t.hasOwn && state.ID == t.own {
+			t.position = state.Position
+		}
+
+		p := t.pending
+		if p == nil || snap.Tick < p.stamp {
+			continue
+		}
+		prev, seen := t.directions[state.ID]
+		if !seen || prev == p.dir || state.Direction != p.dir {
+			continue
+		}
+		p.flipped[state.ID] = struct{}{}
+		if t.hasOwn && state.ID == t.own {
+			if !binary {
+				latencies = append(latencies, now.Sub(p.sent))
+			}
+			t.pending = nil
+		}
+	}
+	t.directions = directions
+
+	if binary && t.hasOwn {
+		if ack, ok := frame.Ack(t.own); ok {
+			latencies = append(latencies, t.acknowledged(ack, now)...)
+		}
+	}
+
+	// Lost our entity, for example to a scene transfer, start over
+	if _, ok := directions[t.own]; t.hasOwn && !t.claimed && !ok {
+		t.hasOwn = false
+		t.candidates = nil
+	}
+
+	if t.pending != nil && now.Sub(t.pending.sent) > ackWindow {
+		t.resolve()
+	}
+	return latencies
+}
+
+// acknowledged completes the samples of the inputs stamped at or before ack and
+// gives up on inputs that waited too long
+func (t *tracker) acknowledged(ack int, now time.Time) []time.Duration {
+	var latencies []time.Duration
+	kept := t.inputs[:0]
+	for _, input := range t.inputs {
+		switch {
+		case input.stamp <= ack:
+			latencies = append(latencies, now.Sub(input.sent))
+		case now.Sub(input.sent) <= inputAckTimeout:
+			kept = append(kept, input)
+		}
+	}
+	t.inputs = kept
+	return latencies
+}
+
+// claim sets the bot's own entity from its session, turns are no longer
+// needed to find it
+func (t *tracker) claim(id uint32) {
+	t.own = id
+	t.hasOwn = true
+	t.claimed = true
+	t.candidates = nil
+}
+
+// view is what behaviors see of the latest snapshot
+func (t *tracker) view() View {
+	view := View{Scene: t.scene, Tick: t.latest.Tick}
+	for _, state := range t.latest.Entities {
+		if t.hasOwn && state.ID == t.own {
+			view.Self, view.HasSelf = state, true
+			continue
+		}
+		view.Others = append(view.Others, state)
+	}
+	return view
+}
+
+// resolve closes the pending turn, narrowing down the candidates for the bot's
+// own entity
+func (t *tracker) resolve() {
+	p := t.pending
+	t.pending = nil
+	if p == nil || t.hasOwn || len(p.flipped) == 0 {
+		return
+	}
+
+	narrowed := map[uint32]struct{}{}
+	for id := range p.flipped {
+		if _, ok := t.candidates[id]; ok || t.candidates == nil {
+			narrowed[id] = struct{}{}
+		}
+	}
+	// Nothing in common means an earlier guess was wrong
+	if len(narrowed) == 0 {
+		narrowed = p.flipped
+	}
+	t.candidates = narrowed
+
+	if len(narrowed) == 1 {
+		for id := range narrowed {
+			t.own = id
+			t.hasOwn = true
+		}
+	}
+}
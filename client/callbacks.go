@@ -1,17 +1,58 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/blueprint/client"
 	"github.com/TheBitDrifter/bappa/coldbrew"
 	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/interpolation"
+	"github.com/TheBitDrifter/netcode_example/shared/prediction"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// decoder rebuilds full snapshots from the server's binary deltas, one stream per scene
+var decoder snapshot.SceneDecoders
+
+// predictor runs the local player ahead of the server and reconciles it on every snapshot
+var predictor = prediction.NewPredictor(coresystems.DefaultCoreSystems, coldbrew.ForceSetTick)
+
+// desyncs counts predictions that did not match the server's checksums
+var desyncs = &snapshot.DesyncDetector{Log: true}
+
+// remoteBuffer holds recent snapshots so remote players can be rendered slightly in the past
+var remoteBuffer = interpolation.NewDefaultBuffer()
+
+// tickRate is the server's tick rate, the client runs its game loop at the same rate
+var tickRate = prediction.DEFAULT_TICK_RATE
+
+// adoptTickRate switches the game loop, prediction and interpolation to the
+// tick rate the server reports, zero (older servers) is ignored
+func adoptTickRate(rate int) {
+	if rate <= 0 || rate == tickRate {
+		return
+	}
+	log.Printf("Server runs at %d ticks per second", rate)
+	tickRate = rate
+	ebiten.SetTPS(rate)
+	predictor.SetTickRate(rate)
+	remoteBuffer.SetTickRate(rate)
+}
+
 func Derser(nc coldbrew.NetworkClient, data []byte) error {
+	if resetStream.Swap(false) {
+		// A new connection starts its streams over with keyframes
+		decoder = snapshot.SceneDecoders{}
+		remoteBuffer.Reset()
+	}
+
 	activeScenes := nc.ActiveScenes()
 	var scene coldbrew.Scene
 	for s := range activeScenes {
@@ -19,63 +60,102 @@ func Derser(nc coldbrew.NetworkClient, data []byte) error {
 		break
 	}
 	if scene != nil && scene.Ready() {
+		localID, hasLocal := nc.AssociatedEntityID()
 		storage := scene.Storage()
 		if storage != nil {
-			var world warehouse.SerializedStorage
-			err := json.Unmarshal(data, &world)
+			frame, err := decoder.Decode(data)
+			if errors.Is(err, snapshot.ErrMissingBaseline) {
+				// Wait for the next keyframe
+				return nil
+			}
+			if err == nil {
+				adoptTickRate(frame.TickRate)
+			}
 			if err != nil {
-				log.Printf("NetworkClient Update Error: Failed to unmarshal state (%d bytes): %v", len(data), err)
+				log.Printf("NetworkClient Update Error: Failed to decode state (%d bytes): %v", len(data), err)
+			} else if frame.Scene != "" && frame.Scene != scene.Name() {
+				// Only follow streams that carry our player, anything else is stale
+				if hasLocal && frame.Contains(uint32(localID)) {
+					return followScene(nc, storage, frame.Scene, localID)
+				}
 			} else {
+				setDecoded(scene.Name(), frame.Snapshot.Tick)
+
+				// Removals go first, a recycled ID can be removed and spawned
+				// again in the same frame
+				if !frame.Keyframe {
+					removed := []warehouse.Entity{}
+					for _, id := range frame.Removed {
+						// Deltas against an older ack can remove an entity again
+						e, err := storage.Entity(int(id))
+						if err != nil || !e.Valid() {
+							continue
+						}
+						removed = append(removed, e)
+					}
+					err := storage.DestroyEntities(removed...)
+					if err != nil {
+						log.Println(err)
+					}
+				}
 
 				seen := map[int]struct{}{}
 
-				for _, se := range world.Entities {
+				for _, se := range frame.Spawns {
 					seen[int(se.ID)] = struct{}{}
 
-					en, err := storage.ForceSerializedEntityExclude(
-						se, client.Components.SoundBundle,
-						client.Components.SpriteBundle,
-					)
+					remote := !hasLocal || int(se.ID) != localID
+					err := forceSerializedEntity(storage, se, remote)
 					if err != nil {
 						return err
 					}
+				}
 
-					err = se.SetValue(en)
-					if err != nil {
-						return err
-					}
+				snap := frame.Snapshot
+				if len(snap.Entities) == 0 {
+					// JSON frames carry full records only, read the state back from storage
+					snap = captureSnapshot(storage, frame)
+					frame.Acks = captureAcks(storage, frame)
+				}
 
-					if !en.Table().Contains(client.Components.SpriteBundle) {
-						err := en.AddComponentWithValue(client.Components.SpriteBundle, scenes.DEFAULT_PLAYER_SPR_BUNDLE)
-						if err != nil {
-							return err
-						}
+				// Remote players are applied by the interpolation system
+				for _, state := range snap.Entities {
+					seen[int(state.ID)] = struct{}{}
+				}
+				remoteBuffer.Push(snap)
 
-						err = en.AddComponentWithValue(client.Components.SoundBundle, scenes.DEFAULT_PLAYER_SND_BUNDLE)
-						if err != nil {
-							return err
+				if frame.Keyframe {
+					purge := []warehouse.Entity{}
+					query := blueprint.Queries.ActionBuffer
+					cursor := scene.NewCursor(query)
+
+					for range cursor.Next() {
+						e, _ := cursor.CurrentEntity()
+						if _, ok := seen[int(e.ID())]; !ok {
+							purge = append(purge, e)
 						}
 					}
 
+					err := storage.DestroyEntities(purge...)
+					if err != nil {
+						log.Println(err)
+					}
 				}
 
-				purge := []warehouse.Entity{}
-				query := blueprint.Queries.ActionBuffer
-				cursor := scene.NewCursor(query)
+				tick := snap.Tick
 
-				for range cursor.Next() {
-					e, _ := cursor.CurrentEntity()
-					if _, ok := seen[int(e.ID())]; !ok {
-						purge = append(purge, e)
+				if hasLocal {
+					en, err := storage.Entity(localID)
+					if err == nil && en.Valid() {
+						err := reconcileLocalPlayer(scene, en, frame, snap)
+						if err != nil {
+							return err
+						}
+						tick += predictor.LeadTicks
 					}
 				}
 
-				err := storage.DestroyEntities(purge...)
-				if err != nil {
-					log.Println(err)
-				}
-
-				coldbrew.ForceSetTick(world.CurrentTick)
+				coldbrew.ForceSetTick(tick)
 			}
 		} else {
 			log.Println("NetworkClient Update Error: Active scene has nil storage.")
@@ -83,3 +163,117 @@ func Derser(nc coldbrew.NetworkClient, data []byte) error {
 	}
 	return nil
 }
+
+// followScene moves the client into the scene the server transferred the local
+// player to, the new scene is populated by its next keyframe
+func followScene(nc coldbrew.NetworkClient, storage warehouse.Storage, name string, localID int) error {
+	log.Println("Following player to scene:", name)
+
+	carried := []warehouse.Entity{}
+	en, err := storage.Entity(localID)
+	if err == nil && en.Valid() {
+		carried = append(carried, en)
+	}
+
+	_, err = nc.ChangeSceneByName(name, carried...)
+	if err != nil {
+		return err
+	}
+
+	decoder = snapshot.SceneDecoders{}
+	remoteBuffer.Reset()
+	return nil
+}
+
+// reconcileLocalPlayer rewinds the local player to its authoritative state and
+// replays the inputs the server has not processed yet
+func reconcileLocalPlayer(scene coldbrew.Scene, en warehouse.Entity, frame snapshot.Frame, snap snapshot.Snapshot) error {
+	// The association can arrive after the entity was first tagged as remote
+	if en.Table().Contains(components.RemoteTag) {
+		err := en.RemoveComponent(components.RemoteTag)
+		if err != nil {
+			return err
+		}
+	}
+
+	auth, ok := snap.Find(uint32(en.ID()))
+	if !ok {
+		return nil
+	}
+
+	if ack, ok := frame.Ack(auth.ID); ok {
+		predictor.Acknowledge(ack, time.Now())
+	}
+
+	// Check what we predicted for this tick before it gets rewritten
+	if sum, ok := frame.Checksum(auth.ID); ok {
+		if predicted, ok := predictor.Predicted(snap.Tick); ok {
+			desyncs.Check(snap.Tick, predicted, sum, auth, true)
+		}
+	}
+
+	return predictor.Reconcile(scene, en, auth, snap.Tick)
+}
+
+// captureSnapshot builds the authoritative snapshot from the entities a frame
+// wrote into storage
+func captureSnapshot(storage warehouse.Storage, frame snapshot.Frame) snapshot.Snapshot {
+	snap := snapshot.Snapshot{Tick: frame.Snapshot.Tick}
+	for _, se := range frame.Spawns {
+		en, err := storage.Entity(int(se.ID))
+		if err != nil {
+			continue
+		}
+		snap.Entities = append(snap.Entities, snapshot.Capture(en))
+	}
+	snapshot.SortStates(snap.Entities)
+	return snap
+}
+
+// captureAcks reads the input acks from the entities a JSON frame wrote into
+// storage
+func captureAcks(storage warehouse.Storage, frame snapshot.Frame) map[uint32]int {
+	acks := map[uint32]int{}
+	for _, se := range frame.Spawns {
+		en, err := storage.Entity(int(se.ID))
+		if err != nil || !en.Table().Contains(components.InputAckComponent) {
+			continue
+		}
+		acks[uint32(se.ID)] = components.InputAckComponent.GetFromEntity(en).Tick
+	}
+	return acks
+}
+
+// forceSerializedEntity creates or overwrites an entity from a full record and
+// attaches the client only sprite and sound bundles
+func forceSerializedEntity(storage warehouse.Storage, se warehouse.SerializedEntity, remote bool) error {
+	en, err := storage.ForceSerializedEntityExclude(
+		se, client.Components.SoundBundle,
+		client.Components.SpriteBundle,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = se.SetValue(en)
+	if err != nil {
+		return err
+	}
+
+	if !en.Table().Contains(client.Components.SpriteBundle) {
+		err := en.AddComponentWithValue(client.Components.SpriteBundle, scenes.DEFAULT_PLAYER_SPR_BUNDLE)
+		if err != nil {
+			return err
+		}
+
+		err = en.AddComponentWithValue(client.Components.SoundBundle, scenes.DEFAULT_PLAYER_SND_BUNDLE)
+		if err != nil {
+			return err
+		}
+	}
+
+	if remote && !en.Table().Contains(components.RemoteTag) {
+		return en.AddComponent(components.RemoteTag)
+	}
+	return nil
+}
@@ -1,26 +1,34 @@
 package main
 
 import (
+	"flag"
 	"log"
 
-	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/coldbrew"
 	"github.com/TheBitDrifter/bappa/coldbrew/coldbrew_clientsystems"
 	"github.com/TheBitDrifter/bappa/coldbrew/coldbrew_rendersystems"
 
-	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/interpolation"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
 	"github.com/TheBitDrifter/netcode_example/sharedclient"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/assets"
+	"github.com/TheBitDrifter/netcode_example/sharedclient/bindings"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/clientsystems"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/rendersystems"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 func main() {
+	bindingsPath := flag.String("bindings", sharedclient.BINDINGS_PATH, "Key and gamepad bindings file (JSON), F1 in game rebinds and saves to it")
+	sessionAddr := flag.String("session", "", "Session endpoint for resuming after a reconnect (the server's default is "+sharedclient.SESSION_ADDRESS+"), empty disables it")
+	flag.Parse()
+
 	log.Println("Starting Networked Client...")
 
+	keys, err := bindings.Load(*bindingsPath)
+	if err != nil {
+		log.Fatalf("Failed to load bindings: %v", err)
+	}
+
 	client := coldbrew.NewNetworkClient(
 		sharedclient.RESOLUTION_X,
 		sharedclient.RESOLUTION_Y,
@@ -39,19 +47,25 @@ func main() {
 	client.SetResizable(true)
 	client.SetMinimumLoadTime(30)
 
-	log.Println("Registering Scene One...")
-	err := client.RegisterScene(
-		scenes.SceneOne.Name,
-		scenes.SceneOne.Width,
-		scenes.SceneOne.Height,
-		scenes.SceneOne.Plan,
-		rendersystems.DefaultRenderSystems,
-		clientsystems.DefaultClientSystemsNetworked,
-		[]blueprint.CoreSystem{},
-		scenes.SceneOne.Preload...,
-	)
-	if err != nil {
-		log.Fatalf("Failed to register Scene One: %v", err)
+	// The server decides which scene the player is in, so every scene is registered
+	for _, scene := range scenes.Catalog {
+		log.Println("Registering Scene:", scene.Name)
+		err := client.RegisterScene(
+			scene.Name,
+			scene.Width,
+			scene.Height,
+			scene.Plan,
+			rendersystems.DefaultRenderSystems,
+			clientsystems.DefaultClientSystemsNetworked,
+			append(
+				predictor.CoreSystems(),
+				interpolation.System{Buffer: remoteBuffer, TickOffset: predictor.Lead},
+			),
+			scene.Preload...,
+		)
+		if err != nil {
+			log.Fatalf("Failed to register %s: %v", scene.Name, err)
+		}
 	}
 
 	// Register Global Systems
@@ -60,10 +74,16 @@ func main() {
 		coldbrew_rendersystems.GlobalRenderer{},
 		&coldbrew_rendersystems.DebugRenderer{},
 	)
+	keeper := newConnectionKeeper(client, sharedclient.SERVER_ADDRESS, *sessionAddr)
 	client.RegisterGlobalClientSystem(
+		// Reconnects and queues the session proof, codec offer and snapshot
+		// acks before inputs are sent
+		keeper,
+		&streamReporter{keeper: keeper},
 		&coldbrew_clientsystems.InputSenderSystem{},
 		coldbrew_clientsystems.InputBufferSystem{},
 		&coldbrew_clientsystems.CameraSceneAssignerSystem{},
+		&bindings.Rebinder{Players: []*bindings.Bindings{keys}, Paths: []string{*bindingsPath}},
 	)
 
 	log.Println("Activating Camera...")
@@ -77,11 +97,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to activate receiver: %v", err)
 	}
-	receiver1.RegisterKey(ebiten.KeySpace, actions.Jump)
-	receiver1.RegisterKey(ebiten.KeyW, actions.Jump)
-	receiver1.RegisterKey(ebiten.KeyA, actions.Left)
-	receiver1.RegisterKey(ebiten.KeyD, actions.Right)
-	receiver1.RegisterKey(ebiten.KeyS, actions.Down)
+	keys.Apply(receiver1)
 
 	log.Printf("Connecting to Drip server at %s...", sharedclient.SERVER_ADDRESS)
 	err = client.Connect(sharedclient.SERVER_ADDRESS)
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/coldbrew"
+	"github.com/TheBitDrifter/netcode_example/shared/session"
+)
+
+// How long to keep claiming after the proof was sent
+const CLAIM_TIMEOUT = 5 * time.Second
+
+// resetStream is set when reconnecting, the next snapshot starts a new stream
+var resetStream atomic.Bool
+
+// connectionKeeper claims a session for the connection and reconnects with
+// backoff whenever it drops, resuming the player if the server still holds it
+//
+// It runs as a client system so connecting never races the game loop. Only
+// the claim itself, an HTTP request, runs in the background
+type connectionKeeper struct {
+	nc          coldbrew.NetworkClient
+	serverAddr  string
+	sessionAddr string
+
+	backoff      *session.Backoff
+	retryAt      time.Time
+	reconnecting bool
+
+	// generation counts connections, claims of older connections are ignored
+	generation int
+	proof      session.Proof
+	proofSent  bool
+	token      string
+	claims     chan claimResult
+}
+
+type claimResult struct {
+	generation int
+	resp       session.ClaimResponse
+	err        error
+}
+
+// newConnectionKeeper looks after the connection nc already opened to serverAddr
+func newConnectionKeeper(nc coldbrew.NetworkClient, serverAddr, sessionAddr string) *connectionKeeper {
+	k := &connectionKeeper{
+		nc:          nc,
+		serverAddr:  serverAddr,
+		sessionAddr: sessionAddr,
+		backoff:     session.NewBackoff(),
+		claims:      make(chan claimResult, 1),
+	}
+	k.connected()
+	return k
+}
+
+func (k *connectionKeeper) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error {
+	k.receiveClaim()
+
+	if k.nc.Connected() {
+		return k.sendProof(scene)
+	}
+
+	now := time.Now()
+	if now.Before(k.retryAt) {
+		return nil
+	}
+	if !k.reconnecting {
+		log.Println("Connection lost, reconnecting...")
+		k.reconnecting = true
+		// Set before connecting, the first frame of the new connection is
+		// its keyframe and must not be thrown away by a late reset
+		resetStream.Store(true)
+	}
+
+	_ = k.nc.Disconnect()
+	err := k.nc.Connect(k.serverAddr)
+	if err != nil {
+		delay := k.backoff.Next()
+		log.Printf("Reconnect attempt %d failed: %v (retrying in %v)", k.backoff.Attempts(), err, delay.Round(time.Millisecond))
+		k.retryAt = now.Add(delay)
+		return nil
+	}
+	log.Println("Reconnected.")
+	k.reconnecting = false
+	k.backoff.Reset()
+	k.connected()
+	return nil
+}
+
+// connected starts over with a fresh proof for the new connection
+func (k *connectionKeeper) connected() {
+	k.generation++
+	k.proof = session.NewProof()
+	k.proofSent = false
+}
+
+// sendProof queues the proof on the local player once the server associated
+// one, the input sender delivers it over the connection. The claim follows in
+// the background
+func (k *connectionKeeper) sendProof(scene coldbrew.Scene) error {
+	if k.sessionAddr == "" || k.proofSent {
+		return nil
+	}
+	buffer, ok := localBuffer(k.nc, scene)
+	if !ok {
+		// Not spawned in this scene yet
+		return nil
+	}
+
+	buffer.Add(k.proof.Action(scene.CurrentTick()))
+	k.proofSent = true
+
+	generation := k.generation
+	req := session.ClaimRequest{Proof: k.proof.String(), Token: k.token}
+	go func() {
+		resp, err := session.ClaimWithRetry(k.sessionAddr, req, CLAIM_TIMEOUT)
+		k.claims <- claimResult{generation: generation, resp: resp, err: err}
+	}()
+	return nil
+}
+
+// receiveClaim picks up a finished claim without blocking the game loop
+func (k *connectionKeeper) receiveClaim() {
+	var result claimResult
+	select {
+	case result = <-k.claims:
+	default:
+		return
+	}
+	if result.generation != k.generation {
+		return
+	}
+	if result.err != nil {
+		log.Printf("Session: claim failed, reconnects will start over: %v", result.err)
+		return
+	}
+	if result.resp.Resumed {
+		log.Printf("Session resumed in %s", result.resp.Scene)
+	}
+	k.token = result.resp.Token
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/coldbrew"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// decoded is the newest frame Derser decoded for the scene the client is in
+var decoded struct {
+	sync.Mutex
+	ack snapshot.Ack
+}
+
+func setDecoded(scene string, tick int) {
+	decoded.Lock()
+	defer decoded.Unlock()
+	decoded.ack = snapshot.Ack{Scene: scene, Tick: tick}
+}
+
+func lastDecoded() snapshot.Ack {
+	decoded.Lock()
+	defer decoded.Unlock()
+	return decoded.ack
+}
+
+// streamReporter tells the server which codecs the client decodes, once per
+// connection, and acknowledges every frame it decoded so the server computes
+// the next deltas against a frame the client holds
+type streamReporter struct {
+	keeper *connectionKeeper
+
+	offered int // connection generation the offer was sent for
+	acked   snapshot.Ack
+}
+
+func (r *streamReporter) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error {
+	if !r.keeper.nc.Connected() {
+		return nil
+	}
+	buffer, ok := localBuffer(r.keeper.nc, scene)
+	if !ok {
+		return nil
+	}
+
+	if r.offered != r.keeper.generation {
+		r.offered = r.keeper.generation
+		r.acked = snapshot.Ack{}
+		buffer.Add(snapshot.OfferAction(scene.CurrentTick(), snapshot.CodecBinary, snapshot.CodecJSON))
+	}
+
+	ack := lastDecoded()
+	if ack.Tick > 0 && ack != r.acked {
+		r.acked = ack
+		buffer.Add(ack.Action(scene.CurrentTick()))
+	}
+	return nil
+}
+
+// localBuffer returns the action buffer of the local player, once the server
+// associated one and it spawned in scene. Actions queued on it are sent over
+// the connection by the input sender
+func localBuffer(nc coldbrew.NetworkClient, scene coldbrew.Scene) (*input.ActionBuffer, bool) {
+	if !scene.Ready() {
+		return nil, false
+	}
+	id, ok := nc.AssociatedEntityID()
+	if !ok {
+		return nil, false
+	}
+	en, err := scene.Storage().Entity(id)
+	if err != nil || !en.Valid() || !en.Table().Contains(input.Components.ActionBuffer) {
+		return nil, false
+	}
+	return input.Components.ActionBuffer.GetFromEntity(en), true
+}
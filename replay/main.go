@@ -0,0 +1,67 @@
+// Package main replays a server input recording headlessly and reports where
+// the simulation diverged from the recorded state checksums.
+//
+// Usage:
+//
+//	replay [-scene Scene1] recording.jsonl
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/TheBitDrifter/netcode_example/shared/replay"
+)
+
+func main() {
+	sceneName := flag.String("scene", "", "Scene to replay (defaults to every scene in the recording)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] recording\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to open recording: %v", err)
+	}
+	records, err := replay.Load(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to read recording: %v", err)
+	}
+
+	sceneNames := replay.Scenes(records)
+	if *sceneName != "" {
+		sceneNames = []string{*sceneName}
+	}
+
+	diverged := false
+	for _, name := range sceneNames {
+		result, err := replay.Play(records, name)
+		if err != nil {
+			log.Fatalf("Replay of %s failed: %v", name, err)
+		}
+
+		log.Printf("%s: ticks %d-%d, %d checksums checked, %d divergences",
+			result.Scene, result.FirstTick, result.LastTick, result.Checked, len(result.Divergences))
+
+		for _, div := range result.Divergences {
+			log.Printf("  tick %d: expected %016x got %016x (entities %v)", div.Tick, div.Expected, div.Actual, div.Entities)
+		}
+		if len(result.Divergences) > 0 {
+			diverged = true
+		}
+	}
+
+	if diverged {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// inputAckSystem records the newest input stamp received from each player,
+// snapshots send it back so clients can measure their round trip. It must run
+// before anything that consumes actions
+type inputAckSystem struct{}
+
+func (inputAckSystem) Run(scene blueprint.Scene, dt float64) error {
+	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+	for range cursor.Next() {
+		ok, ack := components.InputAckComponent.GetFromCursorSafe(cursor)
+		if !ok {
+			continue
+		}
+		buffer := input.Components.ActionBuffer.GetFromCursor(cursor)
+		for _, action := range buffer.Values {
+			ack.Tick = max(ack.Tick, action.Tick)
+		}
+	}
+	return nil
+}
+
+// streamSystem takes the snapshot acks and codec offers clients send as actions
+// out of the action buffers: acks move the baseline of the connection's stream
+// in this scene, offers pick the connection's codec. It must run before
+// anything that consumes or records actions
+type streamSystem struct {
+	scene string
+}
+
+func (sys streamSystem) Run(scene blueprint.Scene, dt float64) error {
+	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+	for range cursor.Next() {
+		buffer := input.Components.ActionBuffer.GetFromCursor(cursor)
+
+		acked := -1
+		for {
+			action, ok := buffer.ConsumeAction(actions.SnapshotAck)
+			if !ok {
+				break
+			}
+			if tick, ok := snapshot.AckOf(sys.scene, action); ok {
+				acked = max(acked, tick)
+			}
+		}
+		var offer input.StampedAction
+		offered := false
+		for {
+			action, ok := buffer.ConsumeAction(actions.CodecOffer)
+			if !ok {
+				break
+			}
+			offer, offered = action, true
+		}
+		if acked < 0 && !offered {
+			continue
+		}
+
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		conn, ok := connections.Conn(en)
+		if !ok {
+			continue
+		}
+		if offered {
+			connections.SetCodec(conn, snapshot.Negotiate(snapshotCodec, offer))
+		}
+		if acked >= 0 {
+			acknowledge(conn, sys.scene, acked)
+		}
+	}
+	return nil
+}
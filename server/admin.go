@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Stats is the JSON document served by the admin endpoint
+type Stats struct {
+	UptimeSeconds float64       `json:"uptime_seconds"`
+	Clients       []ClientStats `json:"clients"`
+	Scenes        []SceneStats  `json:"scenes"`
+}
+
+type ClientStats struct {
+	ID         string `json:"id"`
+	RemoteAddr string `json:"remote_addr"`
+	Entity     uint32 `json:"entity"`
+	Scene      string `json:"scene"`
+}
+
+type SceneStats struct {
+	Name              string        `json:"name"`
+	Ticks             uint64        `json:"ticks"`
+	Players           int           `json:"players"`
+	Entities          int           `json:"entities"`
+	InputQueueDepth   int           `json:"input_queue_depth"`
+	SnapshotBytes     int           `json:"snapshot_bytes"`
+	SnapshotListeners int           `json:"snapshot_listeners"`
+	SnapshotBytesSent uint64        `json:"snapshot_bytes_sent"`
+	TickSeconds       Percentiles   `json:"tick_seconds"`
+	Systems           []SystemStats `json:"systems"`
+}
+
+type SystemStats struct {
+	Name    string      `json:"name"`
+	Seconds Percentiles `json:"seconds"`
+}
+
+// Stats takes a consistent copy of the current metrics
+func (m *Metrics) Stats() Stats {
+	m.mu.Lock()
+	stats := Stats{UptimeSeconds: time.Since(m.started).Seconds()}
+	playerScenes := map[playerKey]string{}
+
+	for _, name := range m.order {
+		sm := m.scenes[name]
+		scene := SceneStats{
+			Name:              name,
+			Ticks:             sm.ticks,
+			Players:           len(sm.players),
+			Entities:          sm.entities,
+			InputQueueDepth:   sm.inputQueueDepth,
+			SnapshotBytes:     sm.snapshotBytes,
+			SnapshotListeners: sm.snapshotListeners,
+			SnapshotBytesSent: sm.snapshotBytesSent,
+			TickSeconds:       sm.tick.percentiles(),
+		}
+		for i, sys := range sm.systems {
+			scene.Systems = append(scene.Systems, SystemStats{Name: sm.systemNames[i], Seconds: sys.percentiles()})
+		}
+		for key := range sm.players {
+			playerScenes[key] = name
+		}
+		stats.Scenes = append(stats.Scenes, scene)
+	}
+	m.mu.Unlock()
+
+	// Connections whose player is no longer in any scene have disconnected
+	for _, c := range connections.Live() {
+		scene, ok := playerScenes[playerKey{id: c.Entity, recycled: c.Recycled}]
+		if !ok {
+			continue
+		}
+		stats.Clients = append(stats.Clients, ClientStats{
+			ID:         c.Conn.ID(),
+			RemoteAddr: c.Conn.RemoteAddr(),
+			Entity:     c.Entity,
+			Scene:      scene,
+		})
+	}
+
+	sort.Slice(stats.Clients, func(i, j int) bool { return stats.Clients[i].ID < stats.Clients[j].ID })
+	return stats
+}
+
+// AdminHandler serves the metrics as JSON (/stats) and in the Prometheus text
+// format (/metrics)
+func AdminHandler(m *Metrics) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(m.Stats())
+		if err != nil {
+			log.Printf("Admin: failed to write stats: %v", err)
+		}
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheus(w, m.Stats())
+	})
+
+	return mux
+}
+
+// StartAdmin serves the admin endpoint in the background
+func StartAdmin(addr string, m *Metrics) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           AdminHandler(m),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Println("Admin endpoint listening on", addr)
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Admin endpoint stopped: %v", err)
+		}
+	}()
+	return srv
+}
+
+func writePrometheus(w io.Writer, stats Stats) {
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	summary := func(name, labels string, p Percentiles) {
+		fmt.Fprintf(w, "%s{%s,quantile=\"0.5\"} %g\n", name, labels, p.P50)
+		fmt.Fprintf(w, "%s{%s,quantile=\"0.95\"} %g\n", name, labels, p.P95)
+		fmt.Fprintf(w, "%s{%s,quantile=\"0.99\"} %g\n", name, labels, p.P99)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", name, labels, p.Sum)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, p.Count)
+	}
+
+	metric("netcode_uptime_seconds", "gauge", "Seconds since the server started.")
+	fmt.Fprintf(w, "netcode_uptime_seconds %g\n", stats.UptimeSeconds)
+
+	metric("netcode_connected_clients", "gauge", "Connections with a player in a scene.")
+	fmt.Fprintf(w, "netcode_connected_clients %d\n", len(stats.Clients))
+
+	metric("netcode_scene_ticks_total", "counter", "Ticks simulated per scene.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_scene_ticks_total{scene=%q} %d\n", s.Name, s.Ticks)
+	}
+
+	metric("netcode_scene_players", "gauge", "Players per scene.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_scene_players{scene=%q} %d\n", s.Name, s.Players)
+	}
+
+	metric("netcode_scene_entities", "gauge", "Positioned entities per scene.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_scene_entities{scene=%q} %d\n", s.Name, s.Entities)
+	}
+
+	metric("netcode_input_queue_depth", "gauge", "Buffered player actions at the start of the last tick.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_input_queue_depth{scene=%q} %d\n", s.Name, s.InputQueueDepth)
+	}
+
+	metric("netcode_snapshot_bytes", "gauge", "Size of the last snapshot serialized per scene, the largest of the tick when every connection gets its own.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_snapshot_bytes{scene=%q} %d\n", s.Name, s.SnapshotBytes)
+	}
+
+	metric("netcode_snapshot_listeners", "gauge", "Connections sent the last snapshot per scene.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_snapshot_listeners{scene=%q} %d\n", s.Name, s.SnapshotListeners)
+	}
+
+	metric("netcode_snapshot_bytes_sent_total", "counter", "Snapshot bytes sent per scene, summed over every listening connection.")
+	for _, s := range stats.Scenes {
+		fmt.Fprintf(w, "netcode_snapshot_bytes_sent_total{scene=%q} %d\n", s.Name, s.SnapshotBytesSent)
+	}
+
+	metric("netcode_tick_duration_seconds", "summary", "Time spent running a scene's core systems per tick.")
+	for _, s := range stats.Scenes {
+		summary("netcode_tick_duration_seconds", fmt.Sprintf("scene=%q", s.Name), s.TickSeconds)
+	}
+
+	metric("netcode_system_duration_seconds", "summary", "Time spent in each core system per tick.")
+	for _, s := range stats.Scenes {
+		for _, sys := range s.Systems {
+			summary("netcode_system_duration_seconds", fmt.Sprintf("scene=%q,system=%q", s.Name, sys.Name), sys.Seconds)
+		}
+	}
+}
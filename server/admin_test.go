@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+)
+
+type fakeConnection struct {
+	id, addr string
+}
+
+func (c fakeConnection) ID() string         { return c.id }
+func (c fakeConnection) RemoteAddr() string { return c.addr }
+
+// coreSystemNames are the names the admin endpoint reports for the core systems
+func coreSystemNames() []string {
+	names := []string{}
+	for _, sys := range coresystems.DefaultCoreSystems {
+		names = append(names, systemName(sys))
+	}
+	return names
+}
+
+// adminFixture runs Scene1 with its instrumented core systems, a connected
+// player, a player without a connection and a connection whose player was
+// replaced by another entity reusing its ID
+func adminFixture(t *testing.T) *Metrics {
+	t.Helper()
+
+	saved := connections
+	connections = newConnectionRegistry()
+	t.Cleanup(func() { connections = saved })
+
+	scene, ok := scenes.ByName("Scene1")
+	if !ok {
+		t.Fatal("Scene1 is not in the catalog")
+	}
+	physics := DefaultConfig().ScenePhysics(scene.Name)
+
+	m := NewMetrics()
+	systems := m.Instrument(scene.Name, coresystems.NewCoreSystems(physics))
+	sim, err := headless.New(scene.Name, scene.Width, scene.Height, withPhysics(scene.Plan, physics), systems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connected, err := sim.SpawnPlayer(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connections.Add(fakeConnection{id: "a", addr: "10.0.0.1:5000"}, connected)
+
+	gone, err := sim.SpawnPlayer(20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connections.Add(fakeConnection{id: "b", addr: "10.0.0.2:5000"}, gone)
+	err = sim.Storage().DestroyEntities(gone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Likely takes the destroyed player's ID
+	_, err = sim.SpawnPlayer(30, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sim.Run(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.RecordSnapshot("Scene1", 100, 1)
+	m.RecordSnapshot("Scene1", 120, 1)
+	return m
+}
+
+func get(t *testing.T, srv *httptest.Server, path string) string {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", path, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestAdminStats(t *testing.T) {
+	srv := httptest.NewServer(AdminHandler(adminFixture(t)))
+	defer srv.Close()
+
+	var stats Stats
+	err := json.Unmarshal([]byte(get(t, srv, "/stats")), &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats.Clients) != 1 || stats.Clients[0].ID != "a" || stats.Clients[0].Scene != "Scene1" {
+		t.Errorf("clients = %+v, want only connection a in Scene1", stats.Clients)
+	}
+	if len(stats.Scenes) != 1 {
+		t.Fatalf("scenes = %+v, want Scene1", stats.Scenes)
+	}
+
+	scene := stats.Scenes[0]
+	if scene.Ticks != 3 || scene.Players != 2 {
+		t.Errorf("ticks = %d, players = %d, want 3 and 2", scene.Ticks, scene.Players)
+	}
+	if scene.SnapshotBytes != 120 || scene.SnapshotBytesSent != 220 {
+		t.Errorf("snapshot bytes = %d, sent = %d, want 120 and 220", scene.SnapshotBytes, scene.SnapshotBytesSent)
+	}
+	if scene.TickSeconds.Count != 3 || scene.TickSeconds.Sum <= 0 {
+		t.Errorf("tick count = %d, sum = %g, want 3 and a positive sum", scene.TickSeconds.Count, scene.TickSeconds.Sum)
+	}
+
+	names := []string{}
+	for _, sys := range scene.Systems {
+		names = append(names, sys.Name)
+		if sys.Seconds.Count != 3 || sys.Seconds.Sum <= 0 {
+			t.Errorf("%s: count = %d, sum = %g, want 3 and a positive sum", sys.Name, sys.Seconds.Count, sys.Seconds.Sum)
+		}
+	}
+	if want := coreSystemNames(); !slices.Equal(names, want) {
+		t.Errorf("systems = %v, want %v", names, want)
+	}
+}
+
+func TestAdminPrometheus(t *testing.T) {
+	srv := httptest.NewServer(AdminHandler(adminFixture(t)))
+	defer srv.Close()
+
+	body := get(t, srv, "/metrics")
+	for _, want := range []string{
+		"netcode_connected_clients 1\n",
+		`netcode_scene_ticks_total{scene="Scene1"} 3` + "\n",
+		`netcode_snapshot_bytes_sent_total{scene="Scene1"} 220` + "\n",
+		`netcode_tick_duration_seconds{scene="Scene1",quantile="0.99"} `,
+		`netcode_tick_duration_seconds_sum{scene="Scene1"} `,
+		`netcode_tick_duration_seconds_count{scene="Scene1"} 3` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+	}
+
+	for _, name := range coreSystemNames() {
+		labels := fmt.Sprintf("scene=%q,system=%q", "Scene1", name)
+		if want := "netcode_system_duration_seconds_count{" + labels + "} 3\n"; !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+		if sum := sample(t, body, "netcode_system_duration_seconds_sum{"+labels+"}"); sum <= 0 {
+			t.Errorf("%s: sum = %g, want a positive sum", name, sum)
+		}
+	}
+}
+
+// sample returns the value of the series in a Prometheus text body
+func sample(t *testing.T, body, series string) float64 {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		value, ok := strings.CutPrefix(line, series+" ")
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("%s: %v", series, err)
+		}
+		return f
+	}
+	t.Fatalf("/metrics is missing %s", series)
+	return 0
+}
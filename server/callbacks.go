@@ -1,23 +1,226 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"log"
+	"sync"
 
 	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/blueprint/client"
 	"github.com/TheBitDrifter/bappa/drip"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
 	"github.com/TheBitDrifter/bappa/warehouse"
 	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/replay"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
 )
 
+// snapshotCodec is the preferred wire format of outgoing snapshots. The
+// broadcast always uses it, a connection's own stream only once its client
+// offered it (JSON until then)
+var snapshotCodec = snapshot.CodecBinary
+
+// snapshotTickRate tells clients the simulation rate, they derive their dt from it
+var snapshotTickRate = drip.DefaultServerConfig().TPS
+
+// recorder captures applied inputs for offline replay, nil unless -record is set
+var recorder *replay.Recorder
+
+// spawnPolicy picks the spawn point of each new connection's player
+var spawnPolicy = spawn.Default
+
+// teams hands each new connection's player its team
+var teams = spawn.DefaultTeams
+
+// encoders holds the per scene snapshot stream state (delta baselines)
+var (
+	encoders   = map[string]*snapshot.Encoder{}
+	encodersMu sync.Mutex
+)
+
+func encoderFor(scene string) *snapshot.Encoder {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	enc, ok := encoders[scene]
+	if !ok {
+		enc = snapshot.NewEncoder(snapshotCodec, scene)
+		enc.TickRate = snapshotTickRate
+		// Only players of connections need a keyframe when they join a stream
+		enc.Listener = connections.Controls
+		encoders[scene] = enc
+	}
+	return enc
+}
+
+// requestKeyframe makes the next snapshot of a scene a keyframe, on the
+// broadcast stream and on every connection's own stream
+func requestKeyframe(scene string) {
+	encoderFor(scene).RequestKeyframe()
+
+	recipientsMu.Lock()
+	defer recipientsMu.Unlock()
+	for key, stream := range recipientStreams {
+		if key.scene == scene {
+			stream.enc.RequestKeyframe()
+		}
+	}
+}
+
 func SerializeCallback(scene drip.Scene) ([]byte, error) {
+	entities, err := replicatedEntities(scene)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoderFor(scene.Name()).Encode(
+		scene.CurrentTick(),
+		entities,
+		client.Components.SpriteBundle,
+		client.Components.SoundBundle,
+	)
+	if err == nil && metrics != nil {
+		metrics.RecordSnapshot(scene.Name(), len(data), listeners(entities))
+	}
+	return data, err
+}
+
+// interest controls per connection area of interest filtering, entities outside
+// the radius of a connection's player are left out of its snapshots
+var interest = snapshot.Interest{
+	Radius:   DEFAULT_INTEREST_RADIUS,
+	CellSize: snapshot.DEFAULT_INTEREST_CELL_SIZE,
+}
+
+// Per connection serialization state
+var (
+	recipientStreams = map[recipient]*recipientStream{}
+	interestGrids    = map[string]sceneInterestGrid{}
+	recipientsMu     sync.Mutex
+)
+
+// recipient identifies a connection's stream for one scene
+type recipient struct {
+	conn  drip.Connection
+	scene string
+}
+
+type recipientStream struct {
+	enc *snapshot.Encoder
+	// tick the stream was last serialized for
+	tick int
+}
+
+type sceneInterestGrid struct {
+	tick int
+	grid *snapshot.InterestGrid
+}
+
+// connectionSerializer is a drip server that serializes every connection's
+// snapshot on its own instead of broadcasting one Serialize result per scene
+type connectionSerializer interface {
+	SetConnectionSerializer(func(drip.Scene, drip.Connection) ([]byte, error))
+}
+
+// SerializeForConnection serializes the entities relevant to a single
+// connection: those within the interest radius of its player plus any always
+// relevant ones. Entities that leave the area are sent as explicit removals and
+// entities that enter it as spawns
+func SerializeForConnection(scene drip.Scene, conn drip.Connection) ([]byte, error) {
+	recipientsMu.Lock()
+	defer recipientsMu.Unlock()
+
+	tick := scene.CurrentTick()
+	cached, ok := interestGrids[scene.Name()]
+	if !ok || cached.tick != tick {
+		entities, err := replicatedEntities(scene)
+		if err != nil {
+			return nil, err
+		}
+		cached = sceneInterestGrid{tick: tick, grid: snapshot.NewInterestGrid(interest, entities)}
+		interestGrids[scene.Name()] = cached
+		pruneRecipients(scene.Name(), tick)
+	}
+
+	stream := recipientFor(conn, scene.Name())
+	stream.tick = tick
+
+	relevant := []warehouse.Entity{}
+	player, codec, ok := connections.Player(conn)
+	if ok {
+		relevant = cached.grid.Relevant(spatial.Components.Position.GetFromEntity(player).Two)
+	}
+	stream.enc.SetCodec(codec)
+
+	data, err := stream.enc.Encode(
+		tick,
+		relevant,
+		client.Components.SpriteBundle,
+		client.Components.SoundBundle,
+	)
+	if err == nil && metrics != nil {
+		metrics.RecordConnectionSnapshot(scene.Name(), tick, len(data))
+	}
+	return data, err
+}
+
+// recipientFor returns a connection's stream for a scene, it must be called
+// with recipientsMu held
+func recipientFor(conn drip.Connection, scene string) *recipientStream {
+	key := recipient{conn: conn, scene: scene}
+	stream, ok := recipientStreams[key]
+	if !ok {
+		enc := snapshot.NewRecipientEncoder(snapshot.CodecJSON, scene)
+		enc.TickRate = snapshotTickRate
+		stream = &recipientStream{enc: enc}
+		recipientStreams[key] = stream
+	}
+	return stream
+}
+
+// acknowledge moves a connection's stream for a scene on to the frame of tick
+// its client decoded
+func acknowledge(conn drip.Connection, scene string, tick int) {
+	recipientsMu.Lock()
+	defer recipientsMu.Unlock()
+
+	if stream, ok := recipientStreams[recipient{conn: conn, scene: scene}]; ok {
+		stream.enc.Acknowledge(tick)
+	}
+}
+
+// pruneRecipients forgets the streams of a scene that were not serialized on
+// the previous tick, their connection closed or its player left the scene. A
+// player that comes back starts a new stream with a keyframe. Must be called
+// with recipientsMu held
+func pruneRecipients(scene string, tick int) {
+	for key, stream := range recipientStreams {
+		if key.scene == scene && stream.tick < tick-1 {
+			delete(recipientStreams, key)
+		}
+	}
+}
+
+// listeners counts the players that belong to a connection, each is sent the
+// scene's snapshot
+func listeners(players []warehouse.Entity) int {
+	n := 0
+	for _, en := range players {
+		if connections.Controls(en) {
+			n++
+		}
+	}
+	return n
+}
+
+// replicatedEntities collects the player entities sent in snapshots
+func replicatedEntities(scene drip.Scene) ([]warehouse.Entity, error) {
 	query := blueprint.Queries.ActionBuffer
 	cursor := warehouse.Factory.NewCursor(query, scene.Storage())
 
-	sEntities := []warehouse.SerializedEntity{}
+	entities := []warehouse.Entity{}
 
 	for range cursor.Next() {
 
@@ -31,24 +234,9 @@ func SerializeCallback(scene drip.Scene) ([]byte, error) {
 			continue
 		}
 
-		se := e.SerializeExclude(
-			client.Components.SpriteBundle,
-			client.Components.SoundBundle,
-		)
-
-		sEntities = append(sEntities, se)
-	}
-
-	serSto := warehouse.SerializedStorage{
-		Entities:    sEntities,
-		CurrentTick: scene.CurrentTick(),
-		Version:     "net",
+		entities = append(entities, e)
 	}
-	stateForJson, err := warehouse.PrepareForJSONMarshal(serSto)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(stateForJson)
+	return entities, nil
 }
 
 func NewConnectionEntityCreate(conn drip.Connection, s drip.Server) (warehouse.Entity, error) {
@@ -58,19 +246,42 @@ func NewConnectionEntityCreate(conn drip.Connection, s drip.Server) (warehouse.E
 		return nil, errors.New("No active scenes to find player in")
 	}
 
-	scene := serverActiveScenes[0]
-	sto := scene.Storage()
+	// Players start in the first scene that has a spawn point for their team
+	team := teams.Next()
+	var (
+		sto        warehouse.Storage
+		sceneName  string
+		spawnPoint components.PlayerSpawn
+		hasSpawn   bool
+	)
 
-	query := warehouse.Factory.NewQuery().And(components.PlayerSpawnComponent)
-	cursor := warehouse.Factory.NewCursor(query, sto)
+	for _, scene := range serverActiveScenes {
+		spawnPoint, hasSpawn = spawn.Select(scene.Storage(), spawnPolicy, team)
+		if hasSpawn {
+			sto = scene.Storage()
+			sceneName = scene.Name()
+			break
+		}
+	}
 
-	var spawn components.PlayerSpawn
+	if !hasSpawn {
+		sto = serverActiveScenes[0].Storage()
+		sceneName = serverActiveScenes[0].Name()
+	}
 
-	for range cursor.Next() {
-		match := components.PlayerSpawnComponent.GetFromCursor(cursor)
-		spawn = *match
-		break
+	player, err := scenes.NewPlayer(spawnPoint.X, spawnPoint.Y, sto)
+	if err != nil {
+		return nil, err
+	}
+
+	connections.Add(conn, player)
+
+	if recorder != nil {
+		recorder.Associate(conn.ID(), uint32(player.ID()))
+	}
+	if sessions != nil {
+		sessions.Open(conn, player, sceneName)
 	}
 
-	return scenes.NewPlayer(spawn.X, spawn.Y, sto)
+	return player, nil
 }
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/TheBitDrifter/bappa/drip"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
+)
+
+// Config is the effective server configuration
+//
+// Values start from the defaults, are replaced by a config file (TOML or JSON,
+// picked by extension) and finally by any flags that were set explicitly
+type Config struct {
+	Address        string   `toml:"address" json:"address"`
+	TickRate       int      `toml:"tick_rate" json:"tick_rate"`
+	MaxConnections int      `toml:"max_connections" json:"max_connections"`
+	Codec          string   `toml:"codec" json:"codec"`
+	InterestRadius float64  `toml:"interest_radius" json:"interest_radius"`
+	Record         string   `toml:"record" json:"record"`
+	Admin          string   `toml:"admin_address" json:"admin_address"`
+	Sessions       string   `toml:"session_address" json:"session_address"`
+	SessionGrace   Duration `toml:"session_grace" json:"session_grace"`
+	SpawnPolicy    string   `toml:"spawn_policy" json:"spawn_policy"`
+	Teams          []string `toml:"teams" json:"teams"`
+	Scenes         []string `toml:"scenes" json:"scenes"`
+
+	// Physics overrides per scene name, unset fields keep the LDtk level values
+	Physics map[string]PhysicsOverrides `toml:"physics" json:"physics"`
+}
+
+// Duration is a time.Duration written as a string such as "30s"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// PhysicsOverrides replaces individual coresystems.Physics values
+type PhysicsOverrides struct {
+	Gravity          *float64 `toml:"gravity" json:"gravity,omitempty"`
+	Friction         *float64 `toml:"friction" json:"friction,omitempty"`
+	Damp             *float64 `toml:"damp" json:"damp,omitempty"`
+	SpeedX           *float64 `toml:"speed_x" json:"speed_x,omitempty"`
+	JumpForce        *float64 `toml:"jump_force" json:"jump_force,omitempty"`
+	SnapForce        *float64 `toml:"snap_force" json:"snap_force,omitempty"`
+	CoyoteTime       *int     `toml:"coyote_time" json:"coyote_time,omitempty"`
+	InputBufferTicks *int     `toml:"input_buffer_ticks" json:"input_buffer_ticks,omitempty"`
+	AirJumps         *int     `toml:"air_jumps" json:"air_jumps,omitempty"`
+	PlayerCollision  *bool    `toml:"player_collision" json:"player_collision,omitempty"`
+	PushMass         *float64 `toml:"push_mass" json:"push_mass,omitempty"`
+}
+
+// DEFAULT_INTEREST_RADIUS is how far (in pixels) around a connection's player
+// other players are sent to it
+const DEFAULT_INTEREST_RADIUS = 800.0
+
+// DefaultConfig mirrors drip.DefaultServerConfig with every catalog scene
+func DefaultConfig() Config {
+	dripConfig := drip.DefaultServerConfig()
+
+	cfg := Config{
+		Address:        dripConfig.Address,
+		TickRate:       dripConfig.TPS,
+		MaxConnections: dripConfig.MaxConnections,
+		Codec:          string(snapshot.CodecBinary),
+		InterestRadius: DEFAULT_INTEREST_RADIUS,
+		SessionGrace:   Duration{30 * time.Second},
+		SpawnPolicy:    spawn.DEFAULT_POLICY,
+		Physics:        map[string]PhysicsOverrides{},
+	}
+	for _, scene := range scenes.Catalog {
+		cfg.Scenes = append(cfg.Scenes, scene.Name)
+	}
+	return cfg
+}
+
+// LoadConfig reads a config file on top of cfg
+func LoadConfig(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		md, err := toml.NewDecoder(f).Decode(cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	case ".json":
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format (expected .toml or .json)", path)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		errs = append(errs, fmt.Errorf("address %q: %w", cfg.Address, err))
+	}
+	if cfg.TickRate <= 0 {
+		errs = append(errs, fmt.Errorf("tick_rate must be positive, got %d", cfg.TickRate))
+	}
+	if cfg.MaxConnections <= 0 {
+		errs = append(errs, fmt.Errorf("max_connections must be positive, got %d", cfg.MaxConnections))
+	}
+	if _, err := snapshot.ParseCodec(cfg.Codec); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.InterestRadius < 0 {
+		errs = append(errs, fmt.Errorf("interest_radius must not be negative, got %v", cfg.InterestRadius))
+	}
+	if _, err := spawn.New(cfg.SpawnPolicy); err != nil {
+		errs = append(errs, fmt.Errorf("spawn_policy: %w", err))
+	}
+	if cfg.Admin != "" {
+		if _, _, err := net.SplitHostPort(cfg.Admin); err != nil {
+			errs = append(errs, fmt.Errorf("admin_address %q: %w", cfg.Admin, err))
+		}
+	}
+	if cfg.Sessions != "" {
+		if _, _, err := net.SplitHostPort(cfg.Sessions); err != nil {
+			errs = append(errs, fmt.Errorf("session_address %q: %w", cfg.Sessions, err))
+		}
+	}
+	if cfg.SessionGrace.Duration < 0 {
+		errs = append(errs, fmt.Errorf("session_grace must not be negative, got %v", cfg.SessionGrace))
+	}
+	joined := map[string]bool{}
+	for _, team := range cfg.Teams {
+		if team == "" {
+			errs = append(errs, errors.New("teams: team names must not be empty"))
+		}
+		if joined[team] {
+			errs = append(errs, fmt.Errorf("teams: %q listed twice", team))
+		}
+		joined[team] = true
+	}
+
+	if len(cfg.Scenes) == 0 {
+		errs = append(errs, errors.New("scenes: at least one scene is required"))
+	}
+	registered := map[string]bool{}
+	for _, name := range cfg.Scenes {
+		if _, ok := scenes.ByName(name); !ok {
+			errs = append(errs, fmt.Errorf("scenes: unknown scene %q", name))
+		}
+		if registered[name] {
+			errs = append(errs, fmt.Errorf("scenes: %q listed twice", name))
+		}
+		registered[name] = true
+	}
+
+	for name := range cfg.Physics {
+		if !registered[name] {
+			errs = append(errs, fmt.Errorf("physics: %q is not a registered scene", name))
+		}
+	}
+
+	// Every registered scene, so bad LDtk level values are caught as well as overrides
+	checked := map[string]bool{}
+	for _, name := range cfg.Scenes {
+		if _, ok := scenes.ByName(name); !ok || checked[name] {
+			continue
+		}
+		checked[name] = true
+		physics := cfg.ScenePhysics(name)
+		if physics.Gravity < 0 {
+			errs = append(errs, fmt.Errorf("physics.%s.gravity must not be negative", name))
+		}
+		if physics.Damp < 0 || physics.Damp > 1 {
+			errs = append(errs, fmt.Errorf("physics.%s.damp must be between 0 and 1", name))
+		}
+		if physics.SpeedX <= 0 || physics.JumpForce <= 0 {
+			errs = append(errs, fmt.Errorf("physics.%s: speed_x and jump_force must be positive", name))
+		}
+		if physics.CoyoteTime < 0 || physics.InputBufferTicks < 0 {
+			errs = append(errs, fmt.Errorf("physics.%s: tick windows must not be negative", name))
+		}
+		if physics.AirJumps < 0 {
+			errs = append(errs, fmt.Errorf("physics.%s.air_jumps must not be negative", name))
+		}
+		if physics.PushMass <= 0 {
+			errs = append(errs, fmt.Errorf("physics.%s.push_mass must be positive", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ScenePhysics returns the scene's LDtk physics with its overrides applied
+func (cfg Config) ScenePhysics(scene string) coresystems.Physics {
+	physics := scenes.LevelMovementConfig(scene)
+	o, ok := cfg.Physics[scene]
+	if !ok {
+		return physics
+	}
+
+	set(&physics.Gravity, o.Gravity)
+	set(&physics.Friction, o.Friction)
+	set(&physics.Damp, o.Damp)
+	set(&physics.SpeedX, o.SpeedX)
+	set(&physics.JumpForce, o.JumpForce)
+	set(&physics.SnapForce, o.SnapForce)
+	set(&physics.CoyoteTime, o.CoyoteTime)
+	set(&physics.InputBufferTicks, o.InputBufferTicks)
+	set(&physics.AirJumps, o.AirJumps)
+	set(&physics.PlayerCollision, o.PlayerCollision)
+	set(&physics.PushMass, o.PushMass)
+	return physics
+}
+
+func set[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// DripConfig converts the configuration for drip.NewServer
+func (cfg Config) DripConfig() drip.ServerConfig {
+	dripConfig := drip.DefaultServerConfig()
+	dripConfig.Address = cfg.Address
+	dripConfig.TPS = cfg.TickRate
+	dripConfig.MaxConnections = cfg.MaxConnections
+	return dripConfig
+}
+
+// Print writes the effective configuration as TOML, with the resolved physics
+// of every registered scene
+func (cfg Config) Print(w io.Writer) error {
+	effective := struct {
+		Address        string                         `toml:"address"`
+		TickRate       int                            `toml:"tick_rate"`
+		MaxConnections int                            `toml:"max_connections"`
+		Codec          string                         `toml:"codec"`
+		InterestRadius float64                        `toml:"interest_radius"`
+		Record         string                         `toml:"record"`
+		Admin          string                         `toml:"admin_address"`
+		Sessions       string                         `toml:"session_address"`
+		SessionGrace   Duration                       `toml:"session_grace"`
+		SpawnPolicy    string                         `toml:"spawn_policy"`
+		Teams          []string                       `toml:"teams"`
+		Scenes         []string                       `toml:"scenes"`
+		Physics        map[string]coresystems.Physics `toml:"physics"`
+	}{
+		Address:        cfg.Address,
+		TickRate:       cfg.TickRate,
+		MaxConnections: cfg.MaxConnections,
+		Codec:          cfg.Codec,
+		InterestRadius: cfg.InterestRadius,
+		Record:         cfg.Record,
+		Admin:          cfg.Admin,
+		Sessions:       cfg.Sessions,
+		SessionGrace:   cfg.SessionGrace,
+		SpawnPolicy:    cfg.SpawnPolicy,
+		Teams:          cfg.Teams,
+		Scenes:         cfg.Scenes,
+		Physics:        map[string]coresystems.Physics{},
+	}
+	for _, name := range cfg.Scenes {
+		effective.Physics[name] = cfg.ScenePhysics(name)
+	}
+	return toml.NewEncoder(w).Encode(effective)
+}
+
+// parseConfig builds the configuration from the command line, it is validated
+// separately so an invalid configuration can still be printed
+func parseConfig(args []string) (Config, bool, error) {
+	defaults := DefaultConfig()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Config file (.toml or .json)")
+	printConfig := fs.Bool("print-config", false, "Print the effective configuration and exit")
+	address := fs.String("addr", defaults.Address, "Listen address (host:port)")
+	tickRate := fs.Int("tps", defaults.TickRate, "Simulation ticks per second")
+	maxConnections := fs.Int("max-connections", defaults.MaxConnections, "Maximum concurrent connections")
+	codec := fs.String("codec", defaults.Codec, "Preferred snapshot codec (binary or json), clients that don't offer it get json")
+	interestRadius := fs.Float64("interest-radius", defaults.InterestRadius, "Only send players within this many pixels of a connection's player, 0 sends everyone")
+	record := fs.String("record", "", "Record applied inputs to this replay file")
+	admin := fs.String("admin", "", "Serve metrics over HTTP on this address (host:port)")
+	sessionAddr := fs.String("session-addr", defaults.Sessions, "Serve session claims on this address (host:port), reconnects are off without it")
+	sessionGrace := fs.Duration("session-grace", defaults.SessionGrace.Duration, "How long a dropped player is kept for its client to reconnect")
+	spawnPolicy := fs.String("spawn-policy", defaults.SpawnPolicy, "How players pick a spawn point ("+strings.Join(spawn.Names(), ", ")+")")
+	teamList := fs.String("teams", strings.Join(defaults.Teams, ","), "Comma separated teams new players join in turn, spawn points of other teams are skipped")
+	sceneList := fs.String("scenes", strings.Join(defaults.Scenes, ","), "Comma separated scenes to register")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, false, err
+	}
+
+	cfg := defaults
+	if *configPath != "" {
+		err := LoadConfig(*configPath, &cfg)
+		if err != nil {
+			return Config{}, false, err
+		}
+	}
+
+	// Explicit flags win over the file
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Address = *address
+		case "tps":
+			cfg.TickRate = *tickRate
+		case "max-connections":
+			cfg.MaxConnections = *maxConnections
+		case "codec":
+			cfg.Codec = *codec
+		case "interest-radius":
+			cfg.InterestRadius = *interestRadius
+		case "record":
+			cfg.Record = *record
+		case "admin":
+			cfg.Admin = *admin
+		case "session-addr":
+			cfg.Sessions = *sessionAddr
+		case "session-grace":
+			cfg.SessionGrace = Duration{*sessionGrace}
+		case "spawn-policy":
+			cfg.SpawnPolicy = *spawnPolicy
+		case "teams":
+			cfg.Teams = splitList(*teamList)
+		case "scenes":
+			cfg.Scenes = splitList(*sceneList)
+		}
+	})
+
+	return cfg, *printConfig, nil
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
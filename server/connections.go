@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/drip"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// connections remembers which player each connection controls
+var connections = newConnectionRegistry()
+
+// connectionRegistry maps connections to their players
+//
+// drip has no disconnect callback, it destroys a connection's player when the
+// connection closes. A connection is therefore dropped from the registry once
+// its player is no longer valid or its ID was recycled for another entity
+type connectionRegistry struct {
+	mu      sync.Mutex
+	players map[drip.Connection]connectionPlayer
+}
+
+type connectionPlayer struct {
+	entity   warehouse.Entity
+	id       uint32
+	recycled int
+	// codec negotiated for the connection's snapshots
+	codec snapshot.Codec
+}
+
+// ConnectionInfo is a live connection and its player
+type ConnectionInfo struct {
+	Conn     drip.Connection
+	Entity   uint32
+	Recycled int
+}
+
+func newConnectionRegistry() *connectionRegistry {
+	return &connectionRegistry{players: map[drip.Connection]connectionPlayer{}}
+}
+
+// Add registers a new connection's player
+func (r *connectionRegistry) Add(conn drip.Connection, player warehouse.Entity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+	r.players[conn] = connectionPlayer{
+		entity:   player,
+		id:       uint32(player.ID()),
+		recycled: player.Recycled(),
+		codec:    snapshot.CodecJSON,
+	}
+}
+
+// Controls reports whether an entity is the player of a registered connection
+func (r *connectionRegistry) Controls(en warehouse.Entity) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.players {
+		if p.id == uint32(en.ID()) && p.recycled == en.Recycled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Player returns the player of a connection while it still exists, and the
+// codec of its snapshots
+func (r *connectionRegistry) Player(conn drip.Connection) (warehouse.Entity, snapshot.Codec, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.players[conn]
+	if !ok || !p.entity.Valid() || p.entity.Recycled() != p.recycled {
+		return nil, snapshot.CodecJSON, false
+	}
+	return p.entity, p.codec, true
+}
+
+// Conn returns the connection controlling a player
+func (r *connectionRegistry) Conn(en warehouse.Entity) (drip.Connection, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for conn, p := range r.players {
+		if p.id == uint32(en.ID()) && p.recycled == en.Recycled() {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
+// SetCodec sets the codec negotiated for a connection's snapshots
+func (r *connectionRegistry) SetCodec(conn drip.Connection, codec snapshot.Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.players[conn]; ok {
+		p.codec = codec
+		r.players[conn] = p
+	}
+}
+
+// Live returns the connections whose player still exists
+func (r *connectionRegistry) Live() []ConnectionInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+	live := make([]ConnectionInfo, 0, len(r.players))
+	for conn, p := range r.players {
+		live = append(live, ConnectionInfo{Conn: conn, Entity: p.id, Recycled: p.recycled})
+	}
+	return live
+}
+
+// prune must be called with mu held
+func (r *connectionRegistry) prune() {
+	for conn, p := range r.players {
+		if !p.entity.Valid() || p.entity.Recycled() != p.recycled {
+			delete(r.players, conn)
+		}
+	}
+}
@@ -1,36 +1,133 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/drip"
 	"github.com/TheBitDrifter/bappa/drip/drip_seversystems"
+	"github.com/TheBitDrifter/bappa/warehouse"
 	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/replay"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
 )
 
 func main() {
+	cfg, printConfig, err := parseConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if printConfig {
+		err := cfg.Print(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Catch configuration problems before anything starts listening
+	err = cfg.Validate()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if printConfig {
+		return
+	}
+
+	codec, err := snapshot.ParseCodec(cfg.Codec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	snapshotCodec = codec
+	snapshotTickRate = cfg.TickRate
+	log.Println("Snapshot codec:", snapshotCodec)
+	interest.Radius = cfg.InterestRadius
+
+	spawnPolicy, err = spawn.New(cfg.SpawnPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Spawn policy:", cfg.SpawnPolicy)
+	if len(cfg.Teams) > 0 {
+		teams = &spawn.Teams{Names: cfg.Teams}
+		log.Println("Teams:", strings.Join(cfg.Teams, ", "))
+	}
+
+	if cfg.Record != "" {
+		recorder, err = replay.Create(cfg.Record, cfg.TickRate)
+		if err != nil {
+			log.Fatalf("Failed to create replay file: %v", err)
+		}
+		log.Println("Recording inputs to:", cfg.Record)
+	}
+
+	if cfg.Admin != "" {
+		metrics = NewMetrics()
+	}
+
+	// Register the scenes, players move between them via the transfer system
+	directory := coresystems.NewSceneDirectory()
+
+	if cfg.Sessions != "" {
+		sessions = NewSessionManager(cfg.SessionGrace.Duration, directory)
+	}
+
 	drip.Callbacks.NewConnectionCreateEntity = NewConnectionEntityCreate
 	drip.Callbacks.Serialize = SerializeCallback
 
-	config := drip.DefaultServerConfig()
+	server := drip.NewServer(cfg.DripConfig(), drip_seversystems.ActionBufferSystem{})
+
+	// Each connection gets its own stream when drip can serialize per
+	// connection, otherwise every connection in a scene shares the broadcast
+	if cs, ok := server.(connectionSerializer); ok {
+		cs.SetConnectionSerializer(SerializeForConnection)
+		log.Printf("Area of interest radius: %v", cfg.InterestRadius)
+	} else {
+		log.Println("drip broadcasts one snapshot per scene, area of interest filtering is off")
+	}
 
-	server := drip.NewServer(config, drip_seversystems.ActionBufferSystem{})
+	for _, name := range cfg.Scenes {
+		scene, _ := scenes.ByName(name)
+		log.Println("Registering scene:", scene.Name)
 
-	// Register a scene
-	log.Println("Registering scene:", scenes.SceneOne.Name)
-	err := server.RegisterScene(
-		scenes.SceneOne.Name,
-		scenes.SceneOne.Width,
-		scenes.SceneOne.Height,
-		scenes.SceneOne.Plan,
-		coresystems.DefaultCoreSystems,
-	)
-	if err != nil {
-		log.Fatalf("Failed to register scene: %v", err)
+		systems := append(
+			coresystems.NewCoreSystems(cfg.ScenePhysics(scene.Name)),
+			coresystems.NewPlayerSceneTransferSystem(scene.Name, directory),
+		)
+		if recorder != nil {
+			// Record before movement consumes the buffered actions
+			systems = append([]blueprint.CoreSystem{recorder.System(scene.Name)}, systems...)
+		}
+		systems = append([]blueprint.CoreSystem{streamSystem{scene: scene.Name}, inputAckSystem{}}, systems...)
+		if sessions != nil {
+			// Session proofs are taken out before anything else sees the actions
+			systems = append([]blueprint.CoreSystem{sessions.System(scene.Name)}, systems...)
+		}
+		if metrics != nil {
+			systems = metrics.Instrument(scene.Name, systems)
+		}
+		err = server.RegisterScene(
+			scene.Name,
+			scene.Width,
+			scene.Height,
+			withPhysics(scene.Plan, cfg.ScenePhysics(scene.Name)),
+			systems,
+		)
+		if err != nil {
+			log.Fatalf("Failed to register scene: %v", err)
+		}
 	}
 
 	// Start the server
@@ -39,6 +136,15 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 
+	var admin *http.Server
+	if metrics != nil {
+		admin = StartAdmin(cfg.Admin, metrics)
+	}
+	var sessionServer *http.Server
+	if sessions != nil {
+		sessionServer = StartSessions(cfg.Sessions, sessions)
+	}
+
 	// Create a channel to receive OS signals
 	quit := make(chan os.Signal, 1)
 
@@ -56,4 +162,33 @@ func main() {
 	} else {
 		log.Println("Server stopped gracefully.")
 	}
+
+	if admin != nil {
+		if err := admin.Close(); err != nil {
+			log.Printf("Error stopping admin endpoint: %v", err)
+		}
+	}
+	if sessionServer != nil {
+		if err := sessionServer.Close(); err != nil {
+			log.Printf("Error stopping session endpoint: %v", err)
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Printf("Error closing replay file: %v", err)
+		}
+	}
+}
+
+// withPhysics applies the configured physics once the scene plan has run, new
+// players copy it and replicate it to their clients
+func withPhysics(plan blueprint.Plan, physics coresystems.Physics) blueprint.Plan {
+	return func(width, height int, sto warehouse.Storage) error {
+		err := plan(width, height, sto)
+		if err != nil {
+			return err
+		}
+		return scenes.SetSceneMovementConfig(sto, physics)
+	}
 }
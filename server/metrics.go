@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+)
+
+// METRIC_SAMPLES is how many recent ticks percentiles are computed over
+const METRIC_SAMPLES = 1024
+
+// metrics collects live server statistics for the admin endpoint, it is nil
+// unless an admin address is configured
+var metrics *Metrics
+
+// Metrics holds per scene tick, system and snapshot statistics
+type Metrics struct {
+	mu      sync.Mutex
+	started time.Time
+	scenes  map[string]*sceneMetrics
+	order   []string
+}
+
+type sceneMetrics struct {
+	ticks     uint64
+	tickStart time.Time
+	tick      *samples
+
+	systemNames []string
+	systems     []*samples
+
+	players         map[playerKey]struct{}
+	entities        int
+	inputQueueDepth int
+
+	snapshotBytes     int
+	snapshotListeners int
+	snapshotBytesSent uint64
+	snapshotTick      int
+}
+
+// playerKey identifies a player across scenes, IDs alone are reused once an
+// entity is destroyed
+type playerKey struct {
+	id       uint32
+	recycled int
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		started: time.Now(),
+		scenes:  map[string]*sceneMetrics{},
+	}
+}
+
+// Instrument wraps a scene's systems so every tick and system run is timed
+func (m *Metrics) Instrument(scene string, systems []blueprint.CoreSystem) []blueprint.CoreSystem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sm := &sceneMetrics{tick: newSamples(METRIC_SAMPLES)}
+	m.scenes[scene] = sm
+	m.order = append(m.order, scene)
+
+	instrumented := []blueprint.CoreSystem{tickStartSystem{metrics: m, scene: sm}}
+	for i, sys := range systems {
+		sm.systemNames = append(sm.systemNames, systemName(sys))
+		sm.systems = append(sm.systems, newSamples(METRIC_SAMPLES))
+		instrumented = append(instrumented, timedSystem{metrics: m, scene: sm, index: i, system: sys})
+	}
+	return append(instrumented, tickEndSystem{metrics: m, scene: sm})
+}
+
+// RecordSnapshot counts a snapshot of n bytes serialized for a scene this tick,
+// every one of the scene's listeners is sent a copy
+func (m *Metrics) RecordSnapshot(scene string, n, listeners int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sm, ok := m.scenes[scene]
+	if !ok {
+		return
+	}
+	sm.snapshotBytes = n
+	sm.snapshotListeners = listeners
+	sm.snapshotBytesSent += uint64(n * listeners)
+}
+
+// RecordConnectionSnapshot counts a snapshot of n bytes serialized for a single
+// connection of a scene at tick
+func (m *Metrics) RecordConnectionSnapshot(scene string, tick, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sm, ok := m.scenes[scene]
+	if !ok {
+		return
+	}
+	if sm.snapshotTick != tick {
+		sm.snapshotTick = tick
+		sm.snapshotBytes = 0
+		sm.snapshotListeners = 0
+	}
+	sm.snapshotBytes = max(sm.snapshotBytes, n)
+	sm.snapshotListeners++
+	sm.snapshotBytesSent += uint64(n)
+}
+
+func systemName(sys blueprint.CoreSystem) string {
+	name := fmt.Sprintf("%T", sys)
+	return strings.TrimPrefix(name, "*")
+}
+
+// tickStartSystem runs first in a scene, it starts the tick timer and samples
+// the scene's entity counts and input queue depth
+type tickStartSystem struct {
+	metrics *Metrics
+	scene   *sceneMetrics
+}
+
+var positionQuery = warehouse.Factory.NewQuery().And(spatial.Components.Position)
+
+func (s tickStartSystem) Run(scene blueprint.Scene, dt float64) error {
+	players := map[playerKey]struct{}{}
+	depth := 0
+	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		players[playerKey{id: uint32(en.ID()), recycled: en.Recycled()}] = struct{}{}
+		depth += len(input.Components.ActionBuffer.GetFromCursor(cursor).Values)
+	}
+	entities := scene.NewCursor(positionQuery).TotalMatched()
+
+	s.metrics.mu.Lock()
+	defer s.metrics.mu.Unlock()
+	s.scene.tickStart = time.Now()
+	s.scene.players = players
+	s.scene.entities = entities
+	s.scene.inputQueueDepth = depth
+	return nil
+}
+
+type tickEndSystem struct {
+	metrics *Metrics
+	scene   *sceneMetrics
+}
+
+func (s tickEndSystem) Run(scene blueprint.Scene, dt float64) error {
+	s.metrics.mu.Lock()
+	defer s.metrics.mu.Unlock()
+	s.scene.ticks++
+	s.scene.tick.add(time.Since(s.scene.tickStart))
+	return nil
+}
+
+type timedSystem struct {
+	metrics *Metrics
+	scene   *sceneMetrics
+	index   int
+	system  blueprint.CoreSystem
+}
+
+func (s timedSystem) Run(scene blueprint.Scene, dt float64) error {
+	start := time.Now()
+	err := s.system.Run(scene, dt)
+	elapsed := time.Since(start)
+
+	s.metrics.mu.Lock()
+	s.scene.systems[s.index].add(elapsed)
+	s.metrics.mu.Unlock()
+	return err
+}
+
+// samples is a ring buffer of recent durations, with running totals over
+// every duration ever added
+type samples struct {
+	values []time.Duration
+	next   int
+	full   bool
+	count  uint64
+	sum    time.Duration
+}
+
+func newSamples(capacity int) *samples {
+	return &samples{values: make([]time.Duration, capacity)}
+}
+
+func (s *samples) add(d time.Duration) {
+	s.values[s.next] = d
+	s.count++
+	s.sum += d
+	s.next++
+	if s.next == len(s.values) {
+		s.next = 0
+		s.full = true
+	}
+}
+
+// Percentiles summarises the recent samples, Count and Sum cover every sample
+type Percentiles struct {
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// percentiles returns the quantiles in seconds
+func (s *samples) percentiles() Percentiles {
+	n := s.next
+	if s.full {
+		n = len(s.values)
+	}
+	if n == 0 {
+		return Percentiles{}
+	}
+
+	sorted := slices.Clone(s.values[:n])
+	slices.Sort(sorted)
+	at := func(q float64) float64 {
+		return sorted[int(q*float64(n-1))].Seconds()
+	}
+	return Percentiles{
+		P50:   at(0.50),
+		P95:   at(0.95),
+		P99:   at(0.99),
+		Max:   sorted[n-1].Seconds(),
+		Count: s.count,
+		Sum:   s.sum.Seconds(),
+	}
+}
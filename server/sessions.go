@@ -0,0 +1,386 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/drip"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/session"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	SESSION_TOKEN_BYTES = 16
+	// DISCONNECT_AFTER_TICKS is how many ticks a player can be missing from the
+	// scene it was last seen in before its connection counts as dropped. It
+	// covers the tick a scene transfer takes
+	DISCONNECT_AFTER_TICKS = 15
+)
+
+// sessions lets reconnecting clients resume their player, it is nil unless a
+// session address is configured
+var sessions *SessionManager
+
+// SessionManager issues a token per connection and keeps the player of a
+// dropped connection for a grace period
+//
+// drip removes a connection's player when the connection closes, so the
+// manager remembers every player's latest state and scene. When a player goes
+// missing a stand-in player is parked at its last state until the grace period
+// ends or the client comes back with its token, in which case the state is
+// moved onto the new connection's player.
+type SessionManager struct {
+	Grace     time.Duration
+	Directory *coresystems.SceneDirectory
+
+	mu      sync.Mutex
+	byToken map[string]*playerSession
+	byConn  map[drip.Connection]*playerSession
+	byProof map[string]*playerSession // Proofs received over drip, not yet claimed
+	expired []*playerSession          // Waiting for their parked player to be removed
+}
+
+type playerSession struct {
+	token   string
+	conn    drip.Connection
+	player  warehouse.Entity
+	claimed bool
+
+	// Latest state of the player, refreshed every tick
+	scene    string
+	state    snapshot.EntityState
+	movement components.MovementConfig
+	missing  int // Ticks of scene the player was not seen in
+
+	disconnected time.Time // Zero while connected
+	parked       warehouse.Entity
+	parkedScene  string
+	parkDone     bool
+
+	// takeover is the new connection's player waiting to receive this
+	// session's state
+	takeover warehouse.Entity
+}
+
+func NewSessionManager(grace time.Duration, directory *coresystems.SceneDirectory) *SessionManager {
+	return &SessionManager{
+		Grace:     grace,
+		Directory: directory,
+		byToken:   map[string]*playerSession{},
+		byConn:    map[drip.Connection]*playerSession{},
+		byProof:   map[string]*playerSession{},
+	}
+}
+
+// Open starts a session for a new connection's player
+func (m *SessionManager) Open(conn drip.Connection, player warehouse.Entity, scene string) {
+	token := newToken()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := &playerSession{
+		token:  token,
+		conn:   conn,
+		player: player,
+		scene:  scene,
+	}
+	m.byToken[token] = s
+	m.byConn[conn] = s
+}
+
+func newToken() string {
+	buf := make([]byte, SESSION_TOKEN_BYTES)
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Claim hands the connection that sent req.Proof its session token. With a
+// valid token from a dropped session, the connection takes that session over
+// instead
+func (m *SessionManager) Claim(req session.ClaimRequest) (session.ClaimResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.byProof[req.Proof]
+	if !ok || !s.disconnected.IsZero() {
+		return session.ClaimResponse{}, session.ErrUnknownConnection
+	}
+	delete(m.byProof, req.Proof)
+	if s.claimed {
+		return session.ClaimResponse{}, session.ErrAlreadyClaimed
+	}
+	s.claimed = true
+
+	old, ok := m.byToken[req.Token]
+	resumable := ok && old != s && !old.disconnected.IsZero() && old.takeover == nil &&
+		time.Since(old.disconnected) <= m.Grace
+	if !resumable {
+		return session.ClaimResponse{Token: s.token, Entity: uint32(s.player.ID()), Scene: s.scene}, nil
+	}
+
+	// The old session continues on the new connection, its state is applied
+	// by the system of the scene the new player is in
+	delete(m.byToken, s.token)
+	if m.byConn[old.conn] == old {
+		delete(m.byConn, old.conn)
+	}
+	old.conn = s.conn
+	old.claimed = true
+	old.takeover = s.player
+	m.byConn[s.conn] = old
+
+	log.Printf("Session resumed by %s within %v of disconnecting", s.conn.RemoteAddr(), time.Since(old.disconnected).Round(time.Millisecond))
+	return session.ClaimResponse{Token: old.token, Entity: uint32(s.player.ID()), Scene: old.scene, Resumed: true}, nil
+}
+
+// System tracks the session players of a scene, parks dropped players and
+// applies takeovers. It must run first, it takes the session proofs out of the
+// action buffers before anything reads them
+func (m *SessionManager) System(scene string) blueprint.CoreSystem {
+	return sessionSystem{manager: m, scene: scene}
+}
+
+type sessionSystem struct {
+	manager *SessionManager
+	scene   string
+}
+
+func (sys sessionSystem) Run(scene blueprint.Scene, dt float64) error {
+	m := sys.manager
+	now := time.Now()
+
+	present := map[uint32]warehouse.Entity{}
+	proofs := map[warehouse.Entity]session.Proof{}
+	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		present[uint32(en.ID())] = en
+
+		buffer := input.Components.ActionBuffer.GetFromCursor(cursor)
+		for {
+			action, ok := buffer.ConsumeAction(actions.SessionProof)
+			if !ok {
+				break
+			}
+			proofs[en] = session.ProofOf(action)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drip delivers actions to the connection's own player, so a proof names
+	// the connection that sent it
+	for en, proof := range proofs {
+		for _, s := range m.byConn {
+			if s.player == nil || s.takeover != nil || !s.disconnected.IsZero() {
+				continue
+			}
+			if s.player.ID() == en.ID() && s.player.Recycled() == en.Recycled() {
+				m.byProof[proof.String()] = s
+				break
+			}
+		}
+	}
+
+	for _, s := range m.byToken {
+		if s.takeover != nil {
+			if en, ok := present[uint32(s.takeover.ID())]; ok {
+				err := sys.takeOver(scene, s, en, now)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if en, ok := present[uint32(s.player.ID())]; ok && en.Recycled() == s.player.Recycled() {
+			if !s.disconnected.IsZero() && now.Sub(s.disconnected) <= m.Grace {
+				// Missing for a while but never gone, the parked stand-in is released below
+				log.Printf("Session player %d is back after %v", s.player.ID(), now.Sub(s.disconnected).Round(time.Millisecond))
+				s.disconnected = time.Time{}
+				s.parkDone = false
+			}
+			if s.disconnected.IsZero() {
+				s.player = en
+				s.scene = sys.scene
+				s.state = snapshot.Capture(en)
+				s.movement = *components.MovementConfigComponent.GetFromEntity(en)
+				s.missing = 0
+				continue
+			}
+		}
+
+		if s.disconnected.IsZero() && s.scene == sys.scene {
+			s.missing++
+			if s.missing > DISCONNECT_AFTER_TICKS {
+				s.disconnected = now
+				log.Printf("Session player %d dropped, keeping it for %v", s.player.ID(), m.Grace)
+			}
+		}
+
+		if !s.disconnected.IsZero() && !s.parkDone && s.scene == sys.scene {
+			err := sys.park(scene, s)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !s.disconnected.IsZero() && now.Sub(s.disconnected) > m.Grace {
+			delete(m.byToken, s.token)
+			if m.byConn[s.conn] == s {
+				delete(m.byConn, s.conn)
+			}
+			m.expired = append(m.expired, s)
+		}
+	}
+
+	// Release parked players of resumed and expired sessions
+	kept := m.expired[:0]
+	for _, s := range m.expired {
+		if s.parked != nil && s.parkedScene == sys.scene {
+			err := releaseParked(scene, s)
+			if err != nil {
+				return err
+			}
+		}
+		if s.parked != nil {
+			kept = append(kept, s)
+		}
+	}
+	m.expired = kept
+	for _, s := range m.byToken {
+		if s.disconnected.IsZero() && s.parked != nil && s.parkedScene == sys.scene {
+			err := releaseParked(scene, s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// park spawns a stand-in player at the dropped player's last state
+func (sys sessionSystem) park(scene blueprint.Scene, s *playerSession) error {
+	s.parkDone = true
+	if sys.manager.Grace <= 0 {
+		return nil
+	}
+
+	en, err := scenes.NewPlayer(s.state.Position.X, s.state.Position.Y, scene.Storage())
+	if err != nil {
+		return err
+	}
+	*components.MovementConfigComponent.GetFromEntity(en) = s.movement
+	s.parked = en
+	s.parkedScene = sys.scene
+	return snapshot.Apply(en, s.state)
+}
+
+// takeOver moves a resumed session's state onto the new connection's player,
+// sending it to the session's scene if it spawned elsewhere
+func (sys sessionSystem) takeOver(scene blueprint.Scene, s *playerSession, en warehouse.Entity, now time.Time) error {
+	state := s.state
+	state.ID = uint32(en.ID())
+	err := snapshot.Apply(en, state)
+	if err != nil {
+		return err
+	}
+	*components.MovementConfigComponent.GetFromEntity(en) = s.movement
+
+	s.player = en
+	s.takeover = nil
+	s.disconnected = time.Time{}
+	s.parkDone = false
+	s.missing = 0
+
+	// The player keeps its entity, so the stream would not see a new listener
+	defer func() { requestKeyframe(s.scene) }()
+
+	if s.scene == sys.scene {
+		return nil
+	}
+	dest, ok := sys.manager.Directory.Storage(s.scene)
+	if !ok {
+		log.Printf("Session scene %q is not running, resuming in %s", s.scene, sys.scene)
+		s.scene = sys.scene
+		return nil
+	}
+	return scene.Storage().TransferEntities(dest, en)
+}
+
+func releaseParked(scene blueprint.Scene, s *playerSession) error {
+	parked := s.parked
+	s.parked = nil
+	if !parked.Valid() {
+		return nil
+	}
+	return scene.Storage().DestroyEntities(parked)
+}
+
+// SessionHandler serves session claims
+func SessionHandler(m *SessionManager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+session.CLAIM_PATH, func(w http.ResponseWriter, r *http.Request) {
+		var req session.ClaimRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil || req.Proof == "" {
+			http.Error(w, "expected a JSON claim with a proof", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := m.Claim(req)
+		switch {
+		case errors.Is(err, session.ErrUnknownConnection):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, session.ErrAlreadyClaimed):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			log.Printf("Sessions: failed to write claim: %v", err)
+		}
+	})
+	return mux
+}
+
+// StartSessions serves the session endpoint in the background
+func StartSessions(addr string, m *SessionManager) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           SessionHandler(m),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Println("Session endpoint listening on", addr)
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Session endpoint stopped: %v", err)
+		}
+	}()
+	return srv
+}
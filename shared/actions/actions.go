@@ -5,8 +5,39 @@ import (
 )
 
 var (
-	Left  = input.NewAction()
-	Right = input.NewAction()
-	Jump  = input.NewAction()
-	Down  = input.NewAction()
+	Left   = input.NewAction()
+	Right  = input.NewAction()
+	Jump   = input.NewAction()
+	Down   = input.NewAction()
+	Dash   = input.NewAction()
+	Crouch = input.NewAction()
 )
+
+// SessionProof carries a session.Proof over the drip connection, it is not a
+// player input and has no name
+var SessionProof = input.NewAction()
+
+// SnapshotAck tells the server which snapshot of a scene's stream the client
+// holds, see snapshot.Ack. It is not a player input and has no name
+var SnapshotAck = input.NewAction()
+
+// CodecOffer lists the snapshot codecs the client decodes, see
+// snapshot.OfferAction. It is not a player input and has no name
+var CodecOffer = input.NewAction()
+
+// Unbound is registered for keys and buttons taken off a player in game,
+// receivers can't forget a key. It has no name and nothing acts on it
+var Unbound = input.NewAction()
+
+// ByName maps the names used in config and script files to actions
+var ByName = map[string]input.Action{
+	"left":   Left,
+	"right":  Right,
+	"jump":   Jump,
+	"down":   Down,
+	"dash":   Dash,
+	"crouch": Crouch,
+}
+
+// Names lists the action names in a fixed order
+var Names = []string{"left", "right", "jump", "down", "dash", "crouch"}
@@ -44,3 +44,38 @@ var FallAnimation = client.AnimationData{
 	Freeze:         true,
 	PositionOffset: vector.Two{X: 0, Y: 10},
 }
+
+// WallSlideAnimation holds the first falling frame while sliding down a wall
+var WallSlideAnimation = client.AnimationData{
+	Name:           "wall_slide",
+	RowIndex:       3,
+	FrameCount:     1,
+	FrameWidth:     144,
+	FrameHeight:    116,
+	Speed:          5,
+	Freeze:         true,
+	PositionOffset: vector.Two{X: 0, Y: 10},
+}
+
+// DashAnimation plays the run cycle at double speed
+var DashAnimation = client.AnimationData{
+	Name:        "dash",
+	RowIndex:    1,
+	FrameCount:  8,
+	FrameWidth:  144,
+	FrameHeight: 116,
+	Speed:       2,
+}
+
+// CrouchAnimation holds the first idle frame, lifted so the feet stay on the
+// ground of the shorter crouching shape
+var CrouchAnimation = client.AnimationData{
+	Name:           "crouch",
+	RowIndex:       0,
+	FrameCount:     1,
+	FrameWidth:     144,
+	FrameHeight:    116,
+	Speed:          8,
+	Freeze:         true,
+	PositionOffset: vector.Two{X: 0, Y: -12},
+}
@@ -6,8 +6,15 @@ import (
 
 var (
 	OnGroundComponent            = warehouse.FactoryNewComponent[OnGround]()
+	OnWallComponent              = warehouse.FactoryNewComponent[OnWall]()
 	IgnorePlatformComponent      = warehouse.FactoryNewComponent[IgnorePlatform]()
 	PlayerSceneTransferComponent = warehouse.FactoryNewComponent[PlayerSceneTransfer]()
 	JumpStateComponent           = warehouse.FactoryNewComponent[JumpState]()
+	DashStateComponent           = warehouse.FactoryNewComponent[DashState]()
+	CrouchStateComponent         = warehouse.FactoryNewComponent[CrouchState]()
 	PlayerSpawnComponent         = warehouse.FactoryNewComponent[PlayerSpawn]()
+	MovementConfigComponent      = warehouse.FactoryNewComponent[MovementConfig]()
+	SceneMovementConfigComponent = warehouse.FactoryNewComponent[SceneMovementConfig]()
+	MovingPlatformComponent      = warehouse.FactoryNewComponent[MovingPlatform]()
+	InputAckComponent            = warehouse.FactoryNewComponent[InputAck]()
 )
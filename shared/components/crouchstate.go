@@ -0,0 +1,9 @@
+package components
+
+// CrouchState tracks whether a player is crouching, their shape is shorter
+// while they do
+type CrouchState struct {
+	Crouching   bool
+	LastHeld    int     // Stamp of the latest crouch action
+	StandHeight float64 // Shape height to stand back up to
+}
@@ -0,0 +1,8 @@
+package components
+
+// DashState tracks a player's dash and their dash input
+type DashState struct {
+	LastDash  int // Tick the latest dash started
+	LastHeld  int // Stamp of the latest dash action
+	Direction int // -1 dashing left, 1 dashing right
+}
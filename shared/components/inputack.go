@@ -0,0 +1,7 @@
+package components
+
+// InputAck is the newest input stamp the server has received for a player,
+// clients time their round trip with it
+type InputAck struct {
+	Tick int
+}
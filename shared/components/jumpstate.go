@@ -1,5 +1,19 @@
 package components
 
+// JumpState tracks a player's jumps and their jump input
+//
+// Whether jump is held or freshly pressed is kept here rather than read from
+// the action buffer, which only holds the inputs of the current tick. Snapshots
+// carry the JumpState, so a reconciled client knows whether jump was held
 type JumpState struct {
 	LastJump int
+	// LastHeld is the stamp of the latest jump action, they come in every tick
+	// the input is held
+	LastHeld int
+	// LastPress is the stamp of the latest jump action that began a press
+	LastPress int
+	// AirJumps counts the jumps used mid-air since landing
+	AirJumps int
+	// WallLockout is the tick horizontal input is accepted again after a wall jump
+	WallLockout int
 }
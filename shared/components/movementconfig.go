@@ -0,0 +1,24 @@
+package components
+
+// MovementConfig tunes the physics and movement of a single entity
+//
+// Players get one in NewPlayer (copied from their scene's SceneMovementConfig)
+// and it is replicated with the rest of the player, so the server and the
+// predicting client always simulate with the same values
+type MovementConfig struct {
+	Gravity          float64 `toml:"gravity" json:"gravity"`
+	Friction         float64 `toml:"friction" json:"friction"`
+	Damp             float64 `toml:"damp" json:"damp"`
+	SpeedX           float64 `toml:"speed_x" json:"speed_x"`
+	JumpForce        float64 `toml:"jump_force" json:"jump_force"`
+	SnapForce        float64 `toml:"snap_force" json:"snap_force"`
+	CoyoteTime       int     `toml:"coyote_time" json:"coyote_time"`
+	InputBufferTicks int     `toml:"input_buffer_ticks" json:"input_buffer_ticks"`
+	AirJumps         int     `toml:"air_jumps" json:"air_jumps"`
+	PlayerCollision  bool    `toml:"player_collision" json:"player_collision"`
+	PushMass         float64 `toml:"push_mass" json:"push_mass"`
+}
+
+// SceneMovementConfig is the scene wide MovementConfig, held by a single
+// settings entity that the scene plan creates from the LDtk level fields
+type SceneMovementConfig MovementConfig
@@ -0,0 +1,25 @@
+package components
+
+import "github.com/TheBitDrifter/bappa/blueprint/vector"
+
+// Easing shapes how a moving platform speeds up and slows down between points
+type Easing string
+
+const (
+	EaseLinear    Easing = "linear"
+	EaseInOut     Easing = "ease-in-out"
+	EaseInOutSine Easing = "sine"
+)
+
+// MovingPlatform moves terrain through Path, pausing Wait ticks at every point
+//
+// Its position is worked out from the scene tick alone (see
+// coresystems.PlatformPositionAt), so the server and the clients move their own
+// copies in step and platforms are never sent in snapshots
+type MovingPlatform struct {
+	Path   []vector.Two
+	Speed  float64 // Pixels per tick
+	Easing Easing
+	Wait   int  // Ticks spent at each point
+	Loop   bool // Return from the last point straight to the first, instead of back along the path
+}
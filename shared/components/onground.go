@@ -6,4 +6,7 @@ type OnGround struct {
 	LastTouch   int
 	Landed      int
 	SlopeNormal vector.Two
+	// PlatformVel is how far the moving platform under the player moved this
+	// tick, zero on static terrain
+	PlatformVel vector.Two
 }
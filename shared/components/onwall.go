@@ -0,0 +1,8 @@
+package components
+
+// OnWall is added to players touching the side of block terrain
+type OnWall struct {
+	LastTouch int
+	Grabbed   int // Tick the player first touched the wall
+	Side      int // -1 when the wall is on the player's left, 1 on the right
+}
@@ -1,5 +1,11 @@
 package components
 
-import "github.com/TheBitDrifter/bappa/blueprint/vector"
-
-type PlayerSpawn vector.Two
+// PlayerSpawn is a point new players can enter the scene at
+//
+// Team limits the point to players of that team (empty is open to everyone),
+// Weight biases the random spawn policy and breaks ties for the others
+type PlayerSpawn struct {
+	X, Y   float64
+	Team   string
+	Weight float64
+}
@@ -14,8 +14,24 @@ type platTag struct{}
 
 type musicTag struct{}
 
+// dynamicTerrainTag marks terrain that moves, the collision broadphase
+// re-indexes it every tick instead of once per scene
+type dynamicTerrainTag struct{}
+
+// remoteTag marks players on a networked client that belong to other connections,
+// they are driven by snapshots rather than simulated locally
+type remoteTag struct{}
+
+// sceneArrivalTag marks players a client handed over to another scene, they
+// take on that scene's MovementConfig once they are in it
+type sceneArrivalTag struct{}
+
 var (
 	BlockTerrainTag = warehouse.FactoryNewComponent[blockTag]()
 	PlatformTag     = warehouse.FactoryNewComponent[platTag]()
 	MusicTag        = warehouse.FactoryNewComponent[musicTag]()
+	RemoteTag       = warehouse.FactoryNewComponent[remoteTag]()
+	SceneArrivalTag = warehouse.FactoryNewComponent[sceneArrivalTag]()
+
+	DynamicTerrainTag = warehouse.FactoryNewComponent[dynamicTerrainTag]()
 )
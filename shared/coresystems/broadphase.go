@@ -0,0 +1,169 @@
+package coresystems
+
+import (
+	"math"
+	"sort"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+const (
+	BROADPHASE_CELL_SIZE = 128.0 // Width and height of a broadphase grid cell
+	BROADPHASE_MARGIN    = 16.0  // Padding around a query, covers movement while resolving
+)
+
+// TerrainIndex is a uniform grid broadphase over the terrain matching a query,
+// so collision systems only narrowphase check the terrain near each player
+//
+// Static terrain is indexed the first time a scene is updated and again only
+// when its terrain count changes. Terrain with the DynamicTerrainTag moves, so
+// it is re-indexed on every update
+type TerrainIndex struct {
+	CellSize float64
+
+	staticQuery  warehouse.QueryNode
+	dynamicQuery warehouse.QueryNode
+	grids        map[warehouse.Storage]*terrainGrid
+}
+
+type gridCell struct {
+	X, Y int
+}
+
+type terrainGrid struct {
+	static      map[gridCell][]warehouse.Entity
+	dynamic     map[gridCell][]warehouse.Entity
+	staticCount int
+
+	found []warehouse.Entity
+	seen  map[int]struct{}
+}
+
+// Bounds is an axis aligned box in world space
+type Bounds struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+func NewTerrainIndex(tag warehouse.Component) *TerrainIndex {
+	return &TerrainIndex{
+		CellSize: BROADPHASE_CELL_SIZE,
+		staticQuery: warehouse.Factory.NewQuery().And(
+			tag, spatial.Components.Shape, warehouse.Factory.NewQuery().Not(components.DynamicTerrainTag),
+		),
+		dynamicQuery: warehouse.Factory.NewQuery().And(tag, spatial.Components.Shape, components.DynamicTerrainTag),
+		grids:        map[warehouse.Storage]*terrainGrid{},
+	}
+}
+
+// Update brings the scene's grid up to date, call it once per tick before querying
+func (idx *TerrainIndex) Update(scene blueprint.Scene) error {
+	grid, ok := idx.grids[scene.Storage()]
+	if !ok {
+		grid = &terrainGrid{staticCount: -1, seen: map[int]struct{}{}}
+		idx.grids[scene.Storage()] = grid
+	}
+
+	staticCursor := scene.NewCursor(idx.staticQuery)
+	if count := staticCursor.TotalMatched(); count != grid.staticCount {
+		grid.static = map[gridCell][]warehouse.Entity{}
+		err := idx.insertAll(grid.static, staticCursor)
+		if err != nil {
+			return err
+		}
+		grid.staticCount = count
+	}
+
+	grid.dynamic = map[gridCell][]warehouse.Entity{}
+	return idx.insertAll(grid.dynamic, scene.NewCursor(idx.dynamicQuery))
+}
+
+func (idx *TerrainIndex) insertAll(cells map[gridCell][]warehouse.Entity, cursor *warehouse.Cursor) error {
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		bounds := shapeBounds(
+			spatial.Components.Shape.GetFromCursor(cursor),
+			spatial.Components.Position.GetFromCursor(cursor),
+		)
+		idx.eachCell(bounds, func(c gridCell) {
+			cells[c] = append(cells[c], en)
+		})
+	}
+	return nil
+}
+
+// Query returns the terrain whose cells overlap bounds, ordered by entity ID so
+// results don't depend on how the grid was filled. The slice is reused by the
+// next query of the scene
+func (idx *TerrainIndex) Query(scene blueprint.Scene, bounds Bounds) []warehouse.Entity {
+	grid, ok := idx.grids[scene.Storage()]
+	if !ok {
+		return nil
+	}
+
+	grid.found = grid.found[:0]
+	clear(grid.seen)
+	collect := func(entities []warehouse.Entity) {
+		for _, en := range entities {
+			id := int(en.ID())
+			if _, dup := grid.seen[id]; dup || !en.Valid() {
+				continue
+			}
+			grid.seen[id] = struct{}{}
+			grid.found = append(grid.found, en)
+		}
+	}
+	idx.eachCell(bounds, func(c gridCell) {
+		collect(grid.static[c])
+		collect(grid.dynamic[c])
+	})
+
+	sort.Slice(grid.found, func(i, j int) bool { return grid.found[i].ID() < grid.found[j].ID() })
+	return grid.found
+}
+
+func (idx *TerrainIndex) eachCell(bounds Bounds, fn func(gridCell)) {
+	size := idx.CellSize
+	if size <= 0 {
+		size = BROADPHASE_CELL_SIZE
+	}
+	minX, minY := int(math.Floor(bounds.MinX/size)), int(math.Floor(bounds.MinY/size))
+	maxX, maxY := int(math.Floor(bounds.MaxX/size)), int(math.Floor(bounds.MaxY/size))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			fn(gridCell{x, y})
+		}
+	}
+}
+
+// shapeBounds is the world box of a shape, from its transformed vertices when it
+// has them
+func shapeBounds(shape *spatial.Shape, position *spatial.Position) Bounds {
+	vertices := shape.Polygon.WorldVertices
+	if len(vertices) == 0 {
+		return playerBounds(shape, position, 0)
+	}
+	b := Bounds{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, v := range vertices {
+		b.MinX, b.MaxX = min(b.MinX, v.X), max(b.MaxX, v.X)
+		b.MinY, b.MaxY = min(b.MinY, v.Y), max(b.MaxY, v.Y)
+	}
+	return b
+}
+
+// playerBounds is the box around a centered shape, padded by margin
+func playerBounds(shape *spatial.Shape, position *spatial.Position, margin float64) Bounds {
+	halfW := shape.LocalAAB.Width/2 + margin
+	halfH := shape.LocalAAB.Height/2 + margin
+	return Bounds{
+		MinX: position.X - halfW,
+		MinY: position.Y - halfH,
+		MaxX: position.X + halfW,
+		MaxY: position.Y + halfH,
+	}
+}
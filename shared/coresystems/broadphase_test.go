@@ -0,0 +1,106 @@
+package coresystems_test
+
+import (
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+)
+
+const (
+	benchPlayers     = 120
+	benchLevelWidth  = 16000
+	benchLevelHeight = 1600
+	benchFloorTop    = 1500.0
+	benchTile        = 32.0
+)
+
+// largeLevel is a tiled floor across the whole level with rows of floating
+// blocks and platforms above it, about two thousand pieces of terrain
+func largeLevel(width, height int, sto warehouse.Storage) error {
+	blocks, err := sto.NewOrExistingArchetype(scenes.BlockTerrainComposition...)
+	if err != nil {
+		return err
+	}
+	newBlock := func(x, y float64) error {
+		return blocks.Generate(1,
+			spatial.NewPosition(x, y),
+			spatial.NewRectangle(benchTile, benchTile),
+			motion.NewDynamics(0),
+		)
+	}
+
+	for x := benchTile / 2; x < float64(width); x += benchTile {
+		err := newBlock(x, benchFloorTop+benchTile/2)
+		if err != nil {
+			return err
+		}
+	}
+	for row := range 4 {
+		y := benchFloorTop - 200 - float64(row)*250
+		for x := 100.0 + float64(row)*40; x < float64(width); x += 160 {
+			err := newBlock(x, y)
+			if err != nil {
+				return err
+			}
+		}
+		for x := 180.0 + float64(row)*40; x < float64(width); x += 320 {
+			err := scenes.NewPlatformRotated(sto, x, y-100, 0)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// benchmarkTerrain steps 120 players on largeLevel with the terrain broadphase
+// using cells of cellSize
+func benchmarkTerrain(b *testing.B, cellSize float64) {
+	systems := coresystems.NewCoreSystems(coresystems.DefaultPhysics())
+	for _, sys := range systems {
+		switch sys := sys.(type) {
+		case *coresystems.PlayerBlockCollisionSystem:
+			sys.Index().CellSize = cellSize
+		case *coresystems.PlayerPlatformCollisionSystem:
+			sys.Index().CellSize = cellSize
+		}
+	}
+
+	sim, err := headless.New("bench", benchLevelWidth, benchLevelHeight, largeLevel, systems)
+	if err != nil {
+		b.Fatal(err)
+	}
+	spacing := float64(benchLevelWidth) / benchPlayers
+	for i := range benchPlayers {
+		_, err := sim.SpawnPlayer(spacing/2+float64(i)*spacing, 100)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	// Players are spread over the floor and the blocks by now
+	err = sim.Run(120)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		err := sim.Step()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTerrainBroadphase compares a tick with the default grid against a
+// single cell holding all the terrain, where every player is checked against
+// every block and platform as before the broadphase
+func BenchmarkTerrainBroadphase(b *testing.B) {
+	b.Run("grid", func(b *testing.B) { benchmarkTerrain(b, coresystems.BROADPHASE_CELL_SIZE) })
+	b.Run("single cell", func(b *testing.B) { benchmarkTerrain(b, benchLevelWidth*4) })
+}
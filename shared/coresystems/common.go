@@ -1,18 +1,25 @@
 package coresystems
 
 import (
-	"github.com/TheBitDrifter/bappa/blueprint"
-	"github.com/TheBitDrifter/bappa/tteokbokki/tteo_coresystems"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
 )
 
-var DefaultCoreSystems = []blueprint.CoreSystem{
-	GravitySystem{},                      // Apply gravity forces
-	FrictionSystem{},                     // Apply Friction forces
-	PlayerMovementSystem{},               // Apply player input forces
-	tteo_coresystems.IntegrationSystem{}, // Update velocities and positions
-	tteo_coresystems.TransformSystem{},   // Update collision shapes
-	PlayerBlockCollisionSystem{},         // Handle collisions
-	NewPlayerPlatformCollisionSystem(),   // Handle collisions — func returns ptr because system is not pure (has state)
-	OnGroundClearingSystem{},             // Clear onGround
-	IgnorePlatformClearingSystem{},       // Clear ignorePlatform
+var DefaultCoreSystems = NewCoreSystems(DefaultPhysics())
+
+// Queries for locally simulated entities
+//
+// On a networked client the players of other connections carry the RemoteTag,
+// they are driven by snapshots so the core systems leave them alone
+var (
+	localPlayersQuery  = newLocalQuery(input.Components.ActionBuffer)
+	localDynamicsQuery = newLocalQuery(motion.Components.Dynamics)
+)
+
+// newLocalQuery matches entities with all the given components that are not remote
+func newLocalQuery(items ...interface{}) warehouse.QueryNode {
+	items = append(items, warehouse.Factory.NewQuery().Not(components.RemoteTag))
+	return warehouse.Factory.NewQuery().And(items...)
 }
@@ -10,16 +10,24 @@ const (
 	DEFAULT_DAMP     = 0.9
 )
 
-type FrictionSystem struct{}
+// FrictionSystem slows dynamics horizontally, Friction and Damp are used when
+// neither the entity nor the scene has a movement config
+type FrictionSystem struct {
+	Friction, Damp float64
+}
+
+func (sys FrictionSystem) Run(scene blueprint.Scene, dt float64) error {
+	defaults := scenePhysics(scene, Physics{Friction: sys.Friction, Damp: sys.Damp})
 
-func (FrictionSystem) Run(scene blueprint.Scene, dt float64) error {
-	cursor := scene.NewCursor(blueprint.Queries.Dynamics)
+	cursor := scene.NewCursor(localDynamicsQuery)
 	for range cursor.Next() {
 		dyn := motion.Components.Dynamics.GetFromCursor(cursor)
-		friction := motion.Forces.Generator.NewHorizontalFrictionForce(dyn.Vel, DEFAULT_FRICTION)
+		physics := entityPhysics(cursor, defaults)
+
+		friction := motion.Forces.Generator.NewHorizontalFrictionForce(dyn.Vel, physics.Friction)
 		motion.Forces.AddForce(dyn, friction)
 
-		motion.Forces.Generator.ApplyHorizontalDamping(dyn, DEFAULT_DAMP)
+		motion.Forces.Generator.ApplyHorizontalDamping(dyn, physics.Damp)
 	}
 	return nil
 }
@@ -10,16 +10,23 @@ const (
 	PIXELS_PER_METER = 50.0
 )
 
-type GravitySystem struct{}
+// GravitySystem pulls dynamics down, Gravity (m/s²) is used when neither the
+// entity nor the scene has a movement config
+type GravitySystem struct {
+	Gravity float64
+}
+
+func (sys GravitySystem) Run(scene blueprint.Scene, dt float64) error {
+	defaults := scenePhysics(scene, Physics{Gravity: sys.Gravity})
 
-func (GravitySystem) Run(scene blueprint.Scene, dt float64) error {
-	cursor := scene.NewCursor(blueprint.Queries.Dynamics)
+	cursor := scene.NewCursor(localDynamicsQuery)
 	for range cursor.Next() {
 		dyn := motion.Components.Dynamics.GetFromCursor(cursor)
+		physics := entityPhysics(cursor, defaults)
 
 		mass := 1 / dyn.InverseMass
 
-		gravity := motion.Forces.Generator.NewGravityForce(mass, DEFAULT_GRAVITY, PIXELS_PER_METER)
+		gravity := motion.Forces.Generator.NewGravityForce(mass, physics.Gravity, PIXELS_PER_METER)
 
 		motion.Forces.AddForce(dyn, gravity)
 	}
@@ -0,0 +1,172 @@
+package coresystems
+
+import (
+	"math"
+	"sort"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+// CARRY_TOLERANCE is how far a grounded player's feet can be from a moving
+// platform's top and still ride it
+const CARRY_TOLERANCE = 4.0
+
+var (
+	movingPlatformQuery = warehouse.Factory.NewQuery().And(
+		components.MovingPlatformComponent,
+		spatial.Components.Position,
+		spatial.Components.Shape,
+	)
+	groundedPlayersQuery = newLocalQuery(input.Components.ActionBuffer, components.OnGroundComponent)
+)
+
+// MovingPlatformSystem puts every MovingPlatform where it is at the current
+// tick and carries the players standing on it, recording the platform's
+// movement in their OnGround
+//
+// Everything is derived from the tick, so rolling back and replaying ticks on
+// a predicting client moves platforms and riders exactly like the server did
+type MovingPlatformSystem struct{}
+
+type carrier struct {
+	id          int
+	top         float64
+	left, right float64
+	delta       vector.Two
+}
+
+func (MovingPlatformSystem) Run(scene blueprint.Scene, dt float64) error {
+	tick := scene.CurrentTick()
+
+	carriers := []carrier{}
+	cursor := scene.NewCursor(movingPlatformQuery)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		platform := components.MovingPlatformComponent.GetFromCursor(cursor)
+		shape := spatial.Components.Shape.GetFromCursor(cursor)
+
+		prev := PlatformPositionAt(*platform, tick-1)
+		next := PlatformPositionAt(*platform, tick)
+		spatial.Components.Position.GetFromCursor(cursor).Two = next
+
+		carriers = append(carriers, carrier{
+			id:    int(en.ID()),
+			top:   prev.Y - shape.LocalAAB.Height/2,
+			left:  prev.X - shape.LocalAAB.Width/2,
+			right: prev.X + shape.LocalAAB.Width/2,
+			delta: vector.Two{X: next.X - prev.X, Y: next.Y - prev.Y},
+		})
+	}
+	if len(carriers) == 0 {
+		return nil
+	}
+	sort.Slice(carriers, func(i, j int) bool { return carriers[i].id < carriers[j].id })
+
+	playerCursor := scene.NewCursor(groundedPlayersQuery)
+	for range playerCursor.Next() {
+		onGround := components.OnGroundComponent.GetFromCursor(playerCursor)
+		onGround.PlatformVel = vector.Two{}
+		if tick-onGround.LastTouch > 1 {
+			continue
+		}
+
+		position := spatial.Components.Position.GetFromCursor(playerCursor)
+		bounds := playerBounds(spatial.Components.Shape.GetFromCursor(playerCursor), position, 0)
+		for _, c := range carriers {
+			onTop := math.Abs(bounds.MaxY-c.top) <= CARRY_TOLERANCE
+			if !onTop || bounds.MaxX <= c.left || bounds.MinX >= c.right {
+				continue
+			}
+			position.X += c.delta.X
+			position.Y += c.delta.Y
+			onGround.PlatformVel = c.delta
+			break
+		}
+	}
+	return nil
+}
+
+// PlatformPositionAt is where a moving platform is at tick
+//
+// The platform travels each leg of its path at Speed, eased, then waits at the
+// leg's end. Without Loop it runs back along the path after the last point
+func PlatformPositionAt(platform components.MovingPlatform, tick int) vector.Two {
+	path := platform.Path
+	if len(path) == 0 {
+		return vector.Two{}
+	}
+	if len(path) == 1 || platform.Speed <= 0 {
+		return path[0]
+	}
+
+	legs := len(path) - 1
+	if platform.Loop {
+		legs = len(path)
+	} else {
+		legs *= 2
+	}
+	wait := max(platform.Wait, 0)
+
+	cycle := 0
+	for i := range legs {
+		from, to := platformLeg(path, platform.Loop, i)
+		cycle += legTicks(from, to, platform.Speed) + wait
+	}
+
+	t := tick % cycle
+	if t < 0 {
+		t += cycle
+	}
+	for i := range legs {
+		from, to := platformLeg(path, platform.Loop, i)
+		duration := legTicks(from, to, platform.Speed)
+		if t < duration {
+			f := ease(platform.Easing, float64(t)/float64(duration))
+			return vector.Two{X: from.X + (to.X-from.X)*f, Y: from.Y + (to.Y-from.Y)*f}
+		}
+		t -= duration
+		if t < wait {
+			return to
+		}
+		t -= wait
+	}
+	return path[0]
+}
+
+// platformLeg returns the points leg i runs between
+func platformLeg(path []vector.Two, loop bool, i int) (vector.Two, vector.Two) {
+	n := len(path)
+	if loop {
+		return path[i], path[(i+1)%n]
+	}
+	if i < n-1 {
+		return path[i], path[i+1]
+	}
+	// Coming back
+	j := 2*(n-1) - i
+	return path[j], path[j-1]
+}
+
+// legTicks is how many ticks a leg takes, at least one
+func legTicks(from, to vector.Two, speed float64) int {
+	return max(int(math.Ceil(math.Hypot(to.X-from.X, to.Y-from.Y)/speed)), 1)
+}
+
+func ease(easing components.Easing, f float64) float64 {
+	switch easing {
+	case components.EaseInOut:
+		return f * f * (3 - 2*f)
+	case components.EaseInOutSine:
+		return 0.5 - 0.5*math.Cos(math.Pi*f)
+	default:
+		return f
+	}
+}
@@ -0,0 +1,97 @@
+package coresystems_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+)
+
+// A path of two legs, 10 ticks along x and 5 ticks up at speed 10
+var (
+	pathA = vector.Two{X: 0, Y: 0}
+	pathB = vector.Two{X: 100, Y: 0}
+	pathC = vector.Two{X: 100, Y: 50}
+)
+
+func TestPlatformPositionAt(t *testing.T) {
+	corner := []vector.Two{pathA, pathB, pathC}
+	line := []vector.Two{pathA, pathB}
+
+	tests := []struct {
+		name     string
+		platform components.MovingPlatform
+		tick     int
+		want     vector.Two
+	}{
+		// Ping-pong runs A B C B A, 30 ticks
+		{"ping-pong start", components.MovingPlatform{Path: corner, Speed: 10}, 0, pathA},
+		{"ping-pong first leg", components.MovingPlatform{Path: corner, Speed: 10}, 5, vector.Two{X: 50}},
+		{"ping-pong second leg", components.MovingPlatform{Path: corner, Speed: 10}, 12, vector.Two{X: 100, Y: 20}},
+		{"ping-pong last point", components.MovingPlatform{Path: corner, Speed: 10}, 15, pathC},
+		{"ping-pong coming back", components.MovingPlatform{Path: corner, Speed: 10}, 17, vector.Two{X: 100, Y: 30}},
+		{"ping-pong back along the path", components.MovingPlatform{Path: corner, Speed: 10}, 25, vector.Two{X: 50}},
+		{"ping-pong next cycle", components.MovingPlatform{Path: corner, Speed: 10}, 35, vector.Two{X: 50}},
+
+		// Loop runs A B C A, the last leg is 112 pixels and takes 12 ticks
+		{"loop last point", components.MovingPlatform{Path: corner, Speed: 10, Loop: true}, 15, pathC},
+		{"loop straight back", components.MovingPlatform{Path: corner, Speed: 10, Loop: true}, 21, vector.Two{X: 50, Y: 25}},
+		{"loop next cycle", components.MovingPlatform{Path: corner, Speed: 10, Loop: true}, 27, pathA},
+
+		// Waiting 3 ticks at each end, 26 ticks
+		{"arrives before waiting", components.MovingPlatform{Path: line, Speed: 10, Wait: 3}, 10, pathB},
+		{"waits at the end", components.MovingPlatform{Path: line, Speed: 10, Wait: 3}, 12, pathB},
+		{"leaves after waiting", components.MovingPlatform{Path: line, Speed: 10, Wait: 3}, 14, vector.Two{X: 90}},
+		{"waits at the start", components.MovingPlatform{Path: line, Speed: 10, Wait: 3}, 25, pathA},
+
+		// Easing keeps the endpoints and the midpoint
+		{"ease-in-out start", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOut}, 0, pathA},
+		{"ease-in-out quarter", components.MovingPlatform{Path: line, Speed: 100.0 / 4 / 4, Easing: components.EaseInOut}, 4, vector.Two{X: 15.625}},
+		{"ease-in-out middle", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOut}, 5, vector.Two{X: 50}},
+		{"ease-in-out end", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOut}, 10, pathB},
+		{"sine start", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOutSine}, 0, pathA},
+		{"sine middle", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOutSine}, 5, vector.Two{X: 50}},
+		{"sine end", components.MovingPlatform{Path: line, Speed: 10, Easing: components.EaseInOutSine}, 10, pathB},
+
+		// Negative ticks wrap into the previous cycle, 20 ticks
+		{"negative tick", components.MovingPlatform{Path: line, Speed: 10}, -5, vector.Two{X: 50}},
+		{"one tick before zero", components.MovingPlatform{Path: line, Speed: 10}, -1, vector.Two{X: 10}},
+		{"negative whole cycle", components.MovingPlatform{Path: line, Speed: 10}, -20, pathA},
+
+		// A repeated point is a leg of a single tick, A A B B A A runs 22 ticks
+		{"zero-length leg", components.MovingPlatform{Path: []vector.Two{pathA, pathA, pathB}, Speed: 10}, 0, pathA},
+		{"after a zero-length leg", components.MovingPlatform{Path: []vector.Two{pathA, pathA, pathB}, Speed: 10}, 6, vector.Two{X: 50}},
+		{"back to the repeated point", components.MovingPlatform{Path: []vector.Two{pathA, pathA, pathB}, Speed: 10}, 21, pathA},
+
+		{"single point", components.MovingPlatform{Path: []vector.Two{pathC}, Speed: 10}, 7, pathC},
+		{"no speed", components.MovingPlatform{Path: corner}, 7, pathA},
+		{"no path", components.MovingPlatform{Speed: 10}, 7, vector.Two{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := coresystems.PlatformPositionAt(tt.platform, tt.tick)
+			if math.Abs(got.X-tt.want.X) > 1e-9 || math.Abs(got.Y-tt.want.Y) > 1e-9 {
+				t.Errorf("PlatformPositionAt(%d) = %v, want %v", tt.tick, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPlatformPositionAtZeroLengthLeg makes sure a repeated point never divides
+// by a zero distance anywhere in the cycle
+func TestPlatformPositionAtZeroLengthLeg(t *testing.T) {
+	for _, easing := range []components.Easing{components.EaseLinear, components.EaseInOut, components.EaseInOutSine} {
+		for _, loop := range []bool{false, true} {
+			platform := components.MovingPlatform{Path: []vector.Two{pathA, pathB, pathB}, Speed: 10, Easing: easing, Loop: loop, Wait: 2}
+			for tick := -30; tick < 60; tick++ {
+				got := coresystems.PlatformPositionAt(platform, tick)
+				if math.IsNaN(got.X) || math.IsNaN(got.Y) || got.X < 0 || got.X > 100 || got.Y != 0 {
+					t.Fatalf("%s, loop %v: tick %d is at %v, off the path", easing, loop, tick, got)
+				}
+			}
+		}
+	}
+}
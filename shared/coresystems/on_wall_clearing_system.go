@@ -0,0 +1,32 @@
+package coresystems
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+type OnWallClearingSystem struct{}
+
+func (OnWallClearingSystem) Run(scene blueprint.Scene, dt float64) error {
+	// Same as onGround, it only has to outlive the wall coyote window
+	const expirationTicks = 15
+
+	onWallQuery := warehouse.Factory.NewQuery().And(components.OnWallComponent)
+	onWallCursor := scene.NewCursor(onWallQuery)
+
+	for range onWallCursor.Next() {
+		onWall := components.OnWallComponent.GetFromCursor(onWallCursor)
+
+		if scene.CurrentTick()-onWall.LastTouch > expirationTicks {
+			wallEntity, _ := onWallCursor.CurrentEntity()
+
+			// We can't mutate while iterating so we enqueue the changes instead
+			err := wallEntity.EnqueueRemoveComponent(components.OnWallComponent)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
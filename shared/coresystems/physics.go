@@ -0,0 +1,91 @@
+package coresystems
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/tteokbokki/tteo_coresystems"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+// Physics holds the tuning values used by the core systems
+//
+// The systems prefer an entity's MovementConfig, then the scene's
+// SceneMovementConfig, and only fall back to the values they were built with
+type Physics = components.MovementConfig
+
+// DefaultPhysics returns the package constants as a Physics value
+func DefaultPhysics() Physics {
+	return Physics{
+		Gravity:          DEFAULT_GRAVITY,
+		Friction:         DEFAULT_FRICTION,
+		Damp:             DEFAULT_DAMP,
+		SpeedX:           SPEED_X,
+		JumpForce:        JUMP_FORCE,
+		SnapForce:        SNAP_FORCE,
+		CoyoteTime:       COYOTE_TIME,
+		InputBufferTicks: INPUT_BUFFER_TICKS,
+		AirJumps:         AIR_JUMPS,
+		PlayerCollision:  PLAYER_COLLISION,
+		PushMass:         PUSH_MASS,
+	}
+}
+
+// NewCoreSystems builds the default system list with the given fallback physics
+//
+// Each call returns fresh systems, so scenes don't share the stateful ones
+func NewCoreSystems(physics Physics) []blueprint.CoreSystem {
+	return []blueprint.CoreSystem{
+		MovingPlatformSystem{},                                         // Move platforms and carry their riders
+		GravitySystem{Gravity: physics.Gravity},                        // Apply gravity forces
+		FrictionSystem{Friction: physics.Friction, Damp: physics.Damp}, // Apply Friction forces
+		NewPlayerMovementSystem(physics),                               // Apply player input forces
+		tteo_coresystems.IntegrationSystem{},                           // Update velocities and positions
+		tteo_coresystems.TransformSystem{},                             // Update collision shapes
+		NewPlayerBlockCollisionSystem(),                                // Handle collisions — ptr, the broadphase grid is state
+		NewPlayerPlatformCollisionSystem(),                             // Handle collisions — func returns ptr because system is not pure (has state)
+		PlayerCollisionSystem{Physics: physics},                        // Separate players (when enabled)
+		OnGroundClearingSystem{},                                       // Clear onGround
+		OnWallClearingSystem{},                                         // Clear onWall
+		IgnorePlatformClearingSystem{},                                 // Clear ignorePlatform
+	}
+}
+
+var sceneMovementConfigQuery = warehouse.Factory.NewQuery().And(components.SceneMovementConfigComponent)
+
+// scenePhysics returns the scene's SceneMovementConfig, or fallback if it has none
+func scenePhysics(scene blueprint.Scene, fallback Physics) Physics {
+	if physics, ok := StoragePhysics(scene.Storage()); ok {
+		return physics
+	}
+	return fallback
+}
+
+// StoragePhysics returns the SceneMovementConfig held by a scene's storage
+func StoragePhysics(sto warehouse.Storage) (Physics, bool) {
+	cursor := warehouse.Factory.NewCursor(sceneMovementConfigQuery, sto)
+	for range cursor.Next() {
+		physics := Physics(*components.SceneMovementConfigComponent.GetFromCursor(cursor))
+		cursor.Reset()
+		return physics, true
+	}
+	return Physics{}, false
+}
+
+// AdoptScenePhysics replaces a player's MovementConfig with the one of the
+// scene it is moving to. Players carry their config, so without this they
+// would keep the physics of the scene they spawned in
+func AdoptScenePhysics(en warehouse.Entity, dest warehouse.Storage) {
+	physics, ok := StoragePhysics(dest)
+	if !ok || !en.Table().Contains(components.MovementConfigComponent) {
+		return
+	}
+	*components.MovementConfigComponent.GetFromEntity(en) = physics
+}
+
+// entityPhysics returns the MovementConfig of the cursor's entity, or fallback
+func entityPhysics(cursor *warehouse.Cursor, fallback Physics) Physics {
+	if ok, physics := components.MovementConfigComponent.GetFromCursorSafe(cursor); ok {
+		return *physics
+	}
+	return fallback
+}
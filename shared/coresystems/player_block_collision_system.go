@@ -8,19 +8,39 @@ import (
 	"github.com/TheBitDrifter/netcode_example/shared/components"
 )
 
-type PlayerBlockCollisionSystem struct{}
+// PlayerBlockCollisionSystem resolves players against block terrain, only
+// checking the blocks the broadphase finds near each player
+type PlayerBlockCollisionSystem struct {
+	index *TerrainIndex
+}
+
+func NewPlayerBlockCollisionSystem() *PlayerBlockCollisionSystem {
+	return &PlayerBlockCollisionSystem{index: NewTerrainIndex(components.BlockTerrainTag)}
+}
 
-func (s PlayerBlockCollisionSystem) Run(scene blueprint.Scene, dt float64) error {
-	blockTerrainQuery := warehouse.Factory.NewQuery().And(components.BlockTerrainTag)
-	blockTerrainCursor := scene.NewCursor(blockTerrainQuery)
-	playerCursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+// Index is the system's broadphase, e.g. to tune its CellSize
+func (s *PlayerBlockCollisionSystem) Index() *TerrainIndex {
+	return s.index
+}
 
-	// Outer loop is blocks
-	for range blockTerrainCursor.Next() {
-		// Inner is players
-		for range playerCursor.Next() {
+func (s *PlayerBlockCollisionSystem) Run(scene blueprint.Scene, dt float64) error {
+	err := s.index.Update(scene)
+	if err != nil {
+		return err
+	}
+	playerCursor := scene.NewCursor(localPlayersQuery)
+
+	// Outer loop is players
+	for range playerCursor.Next() {
+		bounds := playerBounds(
+			spatial.Components.Shape.GetFromCursor(playerCursor),
+			spatial.Components.Position.GetFromCursor(playerCursor),
+			BROADPHASE_MARGIN,
+		)
+		// Inner is the blocks near them
+		for _, block := range s.index.Query(scene, bounds) {
 			// Delegate to helper
-			err := s.resolve(scene, blockTerrainCursor, playerCursor) // Now pass in the scene
+			err := s.resolve(scene, block, playerCursor)
 			if err != nil {
 				return err
 			}
@@ -29,16 +49,16 @@ func (s PlayerBlockCollisionSystem) Run(scene blueprint.Scene, dt float64) error
 	return nil
 }
 
-func (PlayerBlockCollisionSystem) resolve(scene blueprint.Scene, blockCursor, playerCursor *warehouse.Cursor) error {
+func (*PlayerBlockCollisionSystem) resolve(scene blueprint.Scene, block warehouse.Entity, playerCursor *warehouse.Cursor) error {
 	// Get the player pos, shape, and dynamics
 	playerPosition := spatial.Components.Position.GetFromCursor(playerCursor)
 	playerShape := spatial.Components.Shape.GetFromCursor(playerCursor)
 	playerDynamics := motion.Components.Dynamics.GetFromCursor(playerCursor)
 
 	// Get the block pos, shape, and dynamics
-	blockPosition := spatial.Components.Position.GetFromCursor(blockCursor)
-	blockShape := spatial.Components.Shape.GetFromCursor(blockCursor)
-	blockDynamics := motion.Components.Dynamics.GetFromCursor(blockCursor)
+	blockPosition := spatial.Components.Position.GetFromEntity(block)
+	blockShape := spatial.Components.Shape.GetFromEntity(block)
+	blockDynamics := motion.Components.Dynamics.GetFromEntity(block)
 
 	// Check grounded state
 	playerAlreadyGrounded, onGround := components.OnGroundComponent.GetFromCursorSafe(playerCursor)
@@ -90,7 +110,7 @@ func (PlayerBlockCollisionSystem) resolve(scene blueprint.Scene, blockCursor, pl
 		}
 		currentTick := scene.CurrentTick()
 
-		isWall := !playerOnTopOfBlock && !blockOnTopOfPlayer
+		isWall := !playerOnTopOfBlock && !blockOnTopOfPlayer && vertical
 		if isWall {
 			wallDirectionV := spatial.NewDirectionLeft()
 			wallDirection := &wallDirectionV
@@ -99,6 +119,28 @@ func (PlayerBlockCollisionSystem) resolve(scene blueprint.Scene, blockCursor, pl
 				wallDirection.SetRight()
 			}
 
+			// Update onWall accordingly (create or update)
+			playerAlreadyOnWall, onWall := components.OnWallComponent.GetFromCursorSafe(playerCursor)
+			if !playerAlreadyOnWall {
+				playerEntity, err := playerCursor.CurrentEntity()
+				if err != nil {
+					return err
+				}
+				err = playerEntity.EnqueueAddComponentWithValue(
+					components.OnWallComponent,
+					components.OnWall{LastTouch: currentTick, Grabbed: currentTick, Side: int(wallDirection.AsFloat())},
+				)
+				if err != nil {
+					return err
+				}
+			} else {
+				// Left the wall since the last touch, so this is a new grab
+				if onWall.LastTouch < currentTick-1 {
+					onWall.Grabbed = currentTick
+				}
+				onWall.LastTouch = currentTick
+				onWall.Side = int(wallDirection.AsFloat())
+			}
 		}
 
 		// Ensure the player is on top of the terrain before marking them as grounded
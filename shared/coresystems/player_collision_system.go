@@ -0,0 +1,182 @@
+package coresystems
+
+import (
+	"math"
+	"sort"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+const (
+	PLAYER_COLLISION = false // Players pass through each other unless enabled per scene or entity
+	PUSH_MASS        = 10.0  // Mass players push each other with
+)
+
+var collidingPlayersQuery = warehouse.Factory.NewQuery().And(
+	components.MovementConfigComponent,
+	spatial.Components.Position,
+	spatial.Components.Shape,
+	motion.Components.Dynamics,
+)
+
+// PlayerCollisionSystem keeps players that enable PlayerCollision from
+// overlapping each other
+//
+// Side on overlaps are split by PushMass, so a heavier player moves less and
+// walking into a lighter one pushes it along. A player coming down on another's
+// head stands on it and gets OnGround like it would on terrain
+//
+// On a networked client every pair is resolved exactly like on the server and
+// only the corrections of remote players are dropped: the server moves them and
+// their snapshots follow, while the local player gets the share of the push the
+// server gives it
+//
+// The outcome does not depend on cursor order: pairs are visited by entity ID
+// and every correction is worked out from the positions at the start of the
+// step, then applied all at once
+type PlayerCollisionSystem struct {
+	Physics Physics
+}
+
+type collidingPlayer struct {
+	entity   warehouse.Entity
+	id       int
+	position *spatial.Position
+	shape    *spatial.Shape
+	dynamics *motion.Dynamics
+	mass     float64
+	remote   bool
+
+	start    vector.Two
+	startVel vector.Two
+	push     vector.Two
+	velDelta vector.Two
+	onHead   bool
+}
+
+func (sys PlayerCollisionSystem) Run(scene blueprint.Scene, dt float64) error {
+	defaults := scenePhysics(scene, sys.Physics)
+
+	players := []*collidingPlayer{}
+	cursor := scene.NewCursor(collidingPlayersQuery)
+	for range cursor.Next() {
+		physics := entityPhysics(cursor, defaults)
+		if !physics.PlayerCollision {
+			continue
+		}
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+
+		p := &collidingPlayer{
+			entity:   en,
+			id:       int(en.ID()),
+			position: spatial.Components.Position.GetFromCursor(cursor),
+			shape:    spatial.Components.Shape.GetFromCursor(cursor),
+			dynamics: motion.Components.Dynamics.GetFromCursor(cursor),
+			mass:     physics.PushMass,
+			remote:   en.Table().Contains(components.RemoteTag),
+		}
+		if p.mass <= 0 {
+			p.mass = PUSH_MASS
+		}
+		p.start = p.position.Two
+		p.startVel = p.dynamics.Vel
+		players = append(players, p)
+	}
+	if len(players) < 2 {
+		return nil
+	}
+
+	sort.Slice(players, func(i, j int) bool { return players[i].id < players[j].id })
+
+	for i, a := range players {
+		for _, b := range players[i+1:] {
+			if a.remote && b.remote {
+				continue
+			}
+			separate(a, b)
+		}
+	}
+
+	currentTick := scene.CurrentTick()
+	for _, p := range players {
+		if p.remote {
+			continue
+		}
+		p.position.X += p.push.X
+		p.position.Y += p.push.Y
+		p.dynamics.Vel.X += p.velDelta.X
+		p.dynamics.Vel.Y += p.velDelta.Y
+
+		if !p.onHead {
+			continue
+		}
+		// Still grounded from the last tick (or from terrain this tick), refresh it
+		if p.entity.Table().Contains(components.OnGroundComponent) {
+			onGround := components.OnGroundComponent.GetFromEntity(p.entity)
+			if currentTick-onGround.LastTouch <= 1 {
+				onGround.LastTouch = currentTick
+				onGround.SlopeNormal = vector.Two{X: 0, Y: 1}
+				continue
+			}
+		}
+		// We cannot mutate during a cursor iteration, so we use the enqueue API
+		err := p.entity.EnqueueAddComponentWithValue(
+			components.OnGroundComponent,
+			components.OnGround{LastTouch: currentTick, Landed: currentTick, SlopeNormal: vector.Two{X: 0, Y: 1}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// separate works out the corrections for one overlapping pair, a has the lower ID
+func separate(a, b *collidingPlayer) {
+	dx := b.start.X - a.start.X
+	dy := b.start.Y - a.start.Y
+	overlapX := (a.shape.LocalAAB.Width+b.shape.LocalAAB.Width)/2 - math.Abs(dx)
+	overlapY := (a.shape.LocalAAB.Height+b.shape.LocalAAB.Height)/2 - math.Abs(dy)
+	if overlapX <= 0 || overlapY <= 0 {
+		return
+	}
+
+	// Vertical overlaps put the upper player on the lower one's head
+	if overlapY < overlapX {
+		upper := a
+		if dy < 0 {
+			upper = b
+		}
+		upper.push.Y -= overlapY
+		if upper.startVel.Y > 0 {
+			upper.velDelta.Y -= upper.startVel.Y
+		}
+		upper.onHead = true
+		return
+	}
+
+	// Side on overlaps push both players apart, a goes left when they line up
+	dir := 1.0
+	if dx < 0 {
+		dir = -1
+	}
+	a.push.X -= dir * overlapX * b.mass / (a.mass + b.mass)
+	b.push.X += dir * overlapX * a.mass / (a.mass + b.mass)
+
+	// Players closing in on each other move on together, keeping momentum
+	closing := (b.startVel.X - a.startVel.X) * dir
+	if closing >= 0 {
+		return
+	}
+	shared := (a.mass*a.startVel.X + b.mass*b.startVel.X) / (a.mass + b.mass)
+	a.velDelta.X += shared - a.startVel.X
+	b.velDelta.X += shared - b.startVel.X
+}
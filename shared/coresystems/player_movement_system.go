@@ -19,38 +19,98 @@ const (
 	JUMP_FORCE         = 320.0 // Upward force applied when jumping
 	COYOTE_TIME        = 10    // Ticks after leaving ground where jump is still allowed
 	INPUT_BUFFER_TICKS = 5     // Ticks before landing where a jump input is remembered
+	WALL_SLIDE_SPEED   = 60.0  // Fastest a player falls while holding into a wall
+	WALL_JUMP_SPEED_X  = 200.0 // Horizontal push away from the wall when wall jumping
+	WALL_JUMP_LOCKOUT  = 10    // Ticks after a wall jump where horizontal input is ignored
+	JUMP_CUT           = 0.5   // Fraction of the jump force kept when jump is released early
+	HOLD_GRACE         = 1     // Ticks without an action before a held input counts as released
+	AIR_JUMPS          = 0     // Extra jumps allowed mid-air before landing
+	DASH_SPEED         = 400.0 // Horizontal speed held for the length of a dash
+	DASH_TICKS         = 8     // How long a dash lasts
+	DASH_COOLDOWN      = 45    // Ticks from the start of a dash until the next one
+	CROUCH_HEIGHT      = 34.0  // Height of a crouching player's shape
+	CROUCH_SPEED       = 0.5   // Fraction of the horizontal speed kept while crouching
 )
 
 // PlayerMovementSystem handles all player movement mechanics including horizontal
 // movement on flat ground and slopes, jumping with coyote time + early jump buffering,
-// and platform drop-through functionality.
-type PlayerMovementSystem struct{}
+// wall sliding and wall jumping, dashing, crouching and platform drop-through functionality.
+//
+// Players are tuned by their MovementConfig, Physics is the fallback for
+// entities and scenes without one
+type PlayerMovementSystem struct {
+	Physics Physics
+
+	blocks *TerrainIndex // Checked for room to stand up from a crouch
+}
+
+func NewPlayerMovementSystem(physics Physics) PlayerMovementSystem {
+	return PlayerMovementSystem{Physics: physics, blocks: NewTerrainIndex(components.BlockTerrainTag)}
+}
 
 func (sys PlayerMovementSystem) Run(scene blueprint.Scene, dt float64) error {
-	sys.handleHorizontal(scene)
-	sys.handleJump(scene)
+	defaults := scenePhysics(scene, sys.Physics)
+
+	dropUnbound(scene)
+	err := sys.handleCrouch(scene)
+	if err != nil {
+		return err
+	}
+	sys.handleDash(scene)
+	sys.handleHorizontal(scene, defaults)
+	sys.handleJump(scene, defaults, dt)
 	return sys.handleDown(scene)
 }
 
+// dropUnbound throws away the actions of keys and buttons that were unbound in
+// game, they would pile up in the buffer otherwise
+func dropUnbound(scene blueprint.Scene) {
+	cursor := scene.NewCursor(localPlayersQuery)
+	for range cursor.Next() {
+		incomingActions := input.Components.ActionBuffer.GetFromCursor(cursor)
+		for {
+			if _, ok := incomingActions.ConsumeAction(actions.Unbound); !ok {
+				break
+			}
+		}
+	}
+}
+
 // handleHorizontal processes left/right movement with different behaviors for:
 // - Air movement
 // - Flat ground movement
 // - Uphill/downhill slope movement with proper tangent calculations
-func (PlayerMovementSystem) handleHorizontal(scene blueprint.Scene) {
-	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+// - Wall sliding while airborne and holding into a wall
+func (PlayerMovementSystem) handleHorizontal(scene blueprint.Scene, defaults Physics) {
+	cursor := scene.NewCursor(localPlayersQuery)
 	currentTick := scene.CurrentTick()
 
 	for range cursor.Next() {
 		dyn := motion.Components.Dynamics.GetFromCursor(cursor)
 		incomingActions := input.Components.ActionBuffer.GetFromCursor(cursor)
 		direction := spatial.Components.Direction.GetFromCursor(cursor)
+		jumpState := components.JumpStateComponent.GetFromCursor(cursor)
+		dash := components.DashStateComponent.GetFromCursor(cursor)
+		crouch := components.CrouchStateComponent.GetFromCursor(cursor)
+		physics := entityPhysics(cursor, defaults)
 
 		_, pressedLeft := incomingActions.ConsumeAction(actions.Left)
+		_, pressedRight := incomingActions.ConsumeAction(actions.Right)
+
+		// Right after a wall jump the push away from the wall wins over input,
+		// and dashes hold their own velocity
+		if currentTick < jumpState.WallLockout || dashing(dash, currentTick) {
+			continue
+		}
+
+		// Crouching players shuffle along
+		if crouch.Crouching {
+			physics.SpeedX *= CROUCH_SPEED
+		}
+
 		if pressedLeft {
 			direction.SetLeft()
 		}
-
-		_, pressedRight := incomingActions.ConsumeAction(actions.Right)
 		if pressedRight {
 			direction.SetRight()
 		}
@@ -66,21 +126,29 @@ func (PlayerMovementSystem) handleHorizontal(scene blueprint.Scene) {
 		// Default to airborne movement if no ground component exists
 		if !isGrounded {
 			if isMovingHorizontal {
-				dyn.Vel.X = SPEED_X * direction.AsFloat()
+				dyn.Vel.X = physics.SpeedX * direction.AsFloat()
+			}
+
+			// Holding into a wall slows the fall to a slide
+			touchingWall, onWall := components.OnWallComponent.GetFromCursorSafe(cursor)
+			holdingIntoWall := touchingWall && currentTick-1 == onWall.LastTouch &&
+				isMovingHorizontal && int(direction.AsFloat()) == onWall.Side
+			if holdingIntoWall {
+				dyn.Vel.Y = math.Min(dyn.Vel.Y, WALL_SLIDE_SPEED)
 			}
 			continue
 		}
 
 		// Apply small downward force to keep player attached to slopes when grounded
 		// Only applies if player has been on ground for a while and touched ground last tick
-		dyn.Vel.Y = math.Max(dyn.Vel.Y, SNAP_FORCE) // Apply downward force
+		dyn.Vel.Y = math.Max(dyn.Vel.Y, physics.SnapForce) // Apply downward force
 
 		// Check if player is on a flat surface (normal pointing straight up)
 		flat := onGround.SlopeNormal.X == 0 && onGround.SlopeNormal.Y == 1
 		if flat {
 			// Same as air movement on flat ground
 			if isMovingHorizontal {
-				dyn.Vel.X = SPEED_X * direction.AsFloat()
+				dyn.Vel.X = physics.SpeedX * direction.AsFloat()
 			}
 			// Skip slope handling
 			continue
@@ -100,26 +168,158 @@ func (PlayerMovementSystem) handleHorizontal(scene blueprint.Scene) {
 
 			if isUphill {
 				// When going uphill, only set X velocity and let physics handle Y
-				dyn.Vel.X = slopeDir.X * SPEED_X
+				dyn.Vel.X = slopeDir.X * physics.SpeedX
 			} else {
 				// When going downhill, help player follow the slope with both X and Y velocities
-				dyn.Vel.X = slopeDir.X * SPEED_X
-				dyn.Vel.Y = slopeDir.Y * SPEED_X
+				dyn.Vel.X = slopeDir.X * physics.SpeedX
+				dyn.Vel.Y = slopeDir.Y * physics.SpeedX
+			}
+		}
+	}
+}
+
+// handleDash starts a dash on a fresh press once the cooldown is over
+// A dash holds the player at DASH_SPEED in the direction they face for
+// DASH_TICKS, ignoring gravity
+func (PlayerMovementSystem) handleDash(scene blueprint.Scene) {
+	cursor := scene.NewCursor(localPlayersQuery)
+	currentTick := scene.CurrentTick()
+
+	for range cursor.Next() {
+		dash := components.DashStateComponent.GetFromCursor(cursor)
+		incomingActions := input.Components.ActionBuffer.GetFromCursor(cursor)
+
+		if stampedAction, actionReceived := incomingActions.ConsumeAction(actions.Dash); actionReceived {
+			// Holding dash doesn't dash again when the cooldown ends
+			pressed := stampedAction.Tick > dash.LastHeld+1
+			dash.LastHeld = max(dash.LastHeld, stampedAction.Tick)
+
+			offCooldown := dash.LastDash == 0 || currentTick-dash.LastDash >= DASH_COOLDOWN
+			if pressed && offCooldown {
+				direction := spatial.Components.Direction.GetFromCursor(cursor)
+				dash.LastDash = currentTick
+				dash.Direction = int(direction.AsFloat())
+			}
+		}
+
+		if !dashing(dash, currentTick) {
+			continue
+		}
+		// Gravity and friction already pushed their forces this tick, drop them
+		// so the integration doesn't pull the dash off its line
+		dyn := motion.Components.Dynamics.GetFromCursor(cursor)
+		dyn.SumForces = vector.Two{}
+		dyn.Accel = vector.Two{}
+		dyn.Vel.X = float64(dash.Direction) * DASH_SPEED
+		dyn.Vel.Y = 0
+	}
+}
+
+// dashing reports whether a dash is still going
+func dashing(dash *components.DashState, currentTick int) bool {
+	return dash.LastDash != 0 && currentTick-dash.LastDash < DASH_TICKS
+}
+
+// handleCrouch shrinks grounded players holding crouch to CROUCH_HEIGHT, and
+// stands them back up once they let go, unless block terrain is in the way
+func (sys PlayerMovementSystem) handleCrouch(scene blueprint.Scene) error {
+	cursor := scene.NewCursor(localPlayersQuery)
+	currentTick := scene.CurrentTick()
+
+	// The blocks are only indexed on ticks someone tries to stand up
+	blocks := sys.blocks
+	indexed := false
+
+	for range cursor.Next() {
+		crouch := components.CrouchStateComponent.GetFromCursor(cursor)
+		incomingActions := input.Components.ActionBuffer.GetFromCursor(cursor)
+
+		if stampedAction, actionReceived := incomingActions.ConsumeAction(actions.Crouch); actionReceived {
+			crouch.LastHeld = max(crouch.LastHeld, stampedAction.Tick)
+		}
+		held := crouch.LastHeld != 0 && currentTick-crouch.LastHeld <= HOLD_GRACE
+
+		position := spatial.Components.Position.GetFromCursor(cursor)
+		shape := spatial.Components.Shape.GetFromCursor(cursor)
+
+		switch {
+		case held && !crouch.Crouching:
+			// Only from the ground, and only players taller than a crouch
+			grounded, onGround := components.OnGroundComponent.GetFromCursorSafe(cursor)
+			if !grounded || currentTick-1 != onGround.LastTouch || shape.LocalAAB.Height <= CROUCH_HEIGHT {
+				continue
+			}
+			crouch.Crouching = true
+			crouch.StandHeight = shape.LocalAAB.Height
+
+			// Shrink towards the feet
+			position.Y += (crouch.StandHeight - CROUCH_HEIGHT) / 2
+			CrouchShape(shape, *crouch)
+
+		case !held && crouch.Crouching:
+			// The standing shape, keeping clear of the ground underfoot
+			standing := playerBounds(shape, position, 0)
+			standing.MinY = standing.MaxY - crouch.StandHeight
+			standing.MaxY -= 2
+			if !indexed {
+				if blocks == nil {
+					blocks = NewTerrainIndex(components.BlockTerrainTag)
+				}
+				err := blocks.Update(scene)
+				if err != nil {
+					return err
+				}
+				indexed = true
 			}
+			if blocked(scene, blocks, standing) {
+				continue
+			}
+			crouch.Crouching = false
+
+			position.Y -= (crouch.StandHeight - CROUCH_HEIGHT) / 2
+			CrouchShape(shape, *crouch)
 		}
 	}
+	return nil
+}
+
+// CrouchShape gives a player's shape the height that matches their crouch state
+func CrouchShape(shape *spatial.Shape, crouch components.CrouchState) {
+	height := crouch.StandHeight
+	if crouch.Crouching {
+		height = CROUCH_HEIGHT
+	}
+	if height <= 0 || shape.LocalAAB.Height == height {
+		return
+	}
+	*shape = spatial.NewRectangle(shape.LocalAAB.Width, height)
+}
+
+// blocked reports whether any block terrain the broadphase finds near bounds
+// overlaps them
+func blocked(scene blueprint.Scene, blocks *TerrainIndex, bounds Bounds) bool {
+	for _, block := range blocks.Query(scene, bounds) {
+		b := shapeBounds(
+			spatial.Components.Shape.GetFromEntity(block),
+			spatial.Components.Position.GetFromEntity(block),
+		)
+		if b.MinX < bounds.MaxX && b.MaxX > bounds.MinX && b.MinY < bounds.MaxY && b.MaxY > bounds.MinY {
+			return true
+		}
+	}
+	return false
 }
 
 // handleJump processes jump inputs with coyote time and input buffering features
-// Coyote time: Player can jump shortly after leaving a platform
+// Coyote time: Player can jump shortly after leaving a platform (or a wall)
 // Input buffering: Jump inputs are remembered and applied when landing
-func (PlayerMovementSystem) handleJump(scene blueprint.Scene) {
-	// Create query for players eligible to jump (have ground and input components)
-	playersEligibleToJumpQuery := warehouse.Factory.NewQuery()
-	playersEligibleToJumpQuery.And(components.OnGroundComponent, input.Components.ActionBuffer)
-
-	// Get all entities that match the query
-	cursor := scene.NewCursor(playersEligibleToJumpQuery)
+// Variable height: Letting go of jump while rising cuts the jump short
+// Air jumps: Players with AirJumps can jump again mid-air with a fresh press
+//
+// The jump input is tracked on the JumpState instead of being left in the
+// action buffer, so whether it is held is replicated like the rest of the jump
+func (PlayerMovementSystem) handleJump(scene blueprint.Scene, defaults Physics, dt float64) {
+	cursor := scene.NewCursor(localPlayersQuery)
 	currentTick := scene.CurrentTick()
 
 	for range cursor.Next() {
@@ -127,55 +327,131 @@ func (PlayerMovementSystem) handleJump(scene blueprint.Scene) {
 		dyn := motion.Components.Dynamics.GetFromCursor(cursor)
 		incomingActions := input.Components.ActionBuffer.GetFromCursor(cursor)
 		jumpState := components.JumpStateComponent.GetFromCursor(cursor)
+		physics := entityPhysics(cursor, defaults)
 
-		// OnGroundComponent is guaranteed to exist because of our query
-		onGround := components.OnGroundComponent.GetFromCursor(cursor)
+		grounded, onGround := components.OnGroundComponent.GetFromCursorSafe(cursor)
+		groundedLastTick := grounded && currentTick-1 == onGround.LastTouch
 
-		// Check for jump action
-		if stampedAction, actionReceived := incomingActions.ConsumeAction(actions.Jump); actionReceived {
+		// Landing gives the air jumps back
+		if groundedLastTick {
+			jumpState.AirJumps = 0
+		}
 
-			// Coyote time: Allow jumping within certain ticks of leaving ground
-			playerGroundedWithinCoyoteTime := currentTick-onGround.LastTouch <= COYOTE_TIME
-
-			// Action buffering checks:
-			//
-			// 1. Was action received before touching ground?
-			jumpIsBeforeGroundTouch := stampedAction.Tick <= onGround.LastTouch
-			// 2. Was action within the buffer window?
-			jumpWithinBufferWindow := onGround.LastTouch-stampedAction.Tick <= INPUT_BUFFER_TICKS
-			// Combined buffer condition
-			validBufferedJumpInput := jumpIsBeforeGroundTouch && jumpWithinBufferWindow
-
-			// Direct jump: action received while already on ground
-			directJumpAction := stampedAction.Tick >= onGround.LastTouch
-
-			// Prevent double jumps: Make sure player hasn't jumped since last touching ground
-			playerHasNotJumpedSinceGroundTouch := jumpState.LastJump < onGround.LastTouch
-
-			// Player can jump if:
-			// 1. They haven't jumped since touching ground, AND
-			// 2a. They are in coyote time with a direct action, OR
-			// 2b. They have a valid buffered action from before landing
-			canJump := playerHasNotJumpedSinceGroundTouch &&
-				((playerGroundedWithinCoyoteTime && directJumpAction) || validBufferedJumpInput)
-
-			if canJump {
-				// Apply upward velocity and acceleration for jump
-				dyn.Vel.Y = -JUMP_FORCE
-				dyn.Accel.Y = -JUMP_FORCE
-				// Record jump time
-				jumpState.LastJump = currentTick
+		// Jump actions arrive every tick the input is held, a press is one that
+		// doesn't follow on from the last
+		pressed := false
+		if stampedAction, actionReceived := incomingActions.ConsumeAction(actions.Jump); actionReceived {
+			pressed = stampedAction.Tick > jumpState.LastHeld+1
+			jumpState.LastHeld = max(jumpState.LastHeld, stampedAction.Tick)
+			if pressed {
+				jumpState.LastPress = stampedAction.Tick
+			}
+		}
+		held := currentTick-jumpState.LastHeld <= HOLD_GRACE
+
+		// Every input jumps at most once, holding jump still hops again on landing
+		inputUnused := jumpState.LastHeld > jumpState.LastJump
+
+		switch {
+		case !inputUnused:
+		case grounded && canGroundJump(jumpState, onGround, physics, currentTick):
+			// Apply upward velocity and acceleration for jump
+			dyn.Vel.Y = -physics.JumpForce
+			dyn.Accel.Y = -physics.JumpForce
+			// Jumping off a moving platform keeps its momentum, rising platforms launch higher
+			if dt > 0 {
+				dyn.Vel.X += onGround.PlatformVel.X / dt
+				dyn.Vel.Y += math.Min(onGround.PlatformVel.Y, 0) / dt
 			}
+			// Record jump time
+			jumpState.LastJump = currentTick
+		case !groundedLastTick && wallJump(cursor, jumpState, physics, currentTick):
+		case !groundedLastTick && pressed && jumpState.AirJumps < physics.AirJumps:
+			dyn.Vel.Y = -physics.JumpForce
+			dyn.Accel.Y = -physics.JumpForce
+			jumpState.AirJumps++
+			jumpState.LastJump = currentTick
+		}
+
+		// Letting go while still rising from a jump caps the upward velocity
+		jumpedSinceGroundTouch := !grounded || jumpState.LastJump >= onGround.LastTouch
+		if !held && !groundedLastTick && jumpedSinceGroundTouch && dyn.Vel.Y < 0 {
+			dyn.Vel.Y = math.Max(dyn.Vel.Y, -physics.JumpForce*JUMP_CUT)
 		}
 	}
 }
 
+// canGroundJump checks the held jump input against coyote time and buffering
+func canGroundJump(jumpState *components.JumpState, onGround *components.OnGround, physics Physics, currentTick int) bool {
+	inputTick := jumpState.LastHeld
+
+	// Coyote time: Allow jumping within certain ticks of leaving ground
+	playerGroundedWithinCoyoteTime := currentTick-onGround.LastTouch <= physics.CoyoteTime
+
+	// Action buffering checks:
+	//
+	// 1. Was action received before touching ground?
+	jumpIsBeforeGroundTouch := inputTick <= onGround.LastTouch
+	// 2. Was action within the buffer window?
+	jumpWithinBufferWindow := onGround.LastTouch-inputTick <= physics.InputBufferTicks
+	// Combined buffer condition
+	validBufferedJumpInput := jumpIsBeforeGroundTouch && jumpWithinBufferWindow
+
+	// Direct jump: action received while already on ground
+	directJumpAction := inputTick >= onGround.LastTouch
+
+	// Prevent double jumps: Make sure player hasn't jumped since last touching ground
+	playerHasNotJumpedSinceGroundTouch := jumpState.LastJump < onGround.LastTouch
+
+	// Player can jump if:
+	// 1. They haven't jumped since touching ground, AND
+	// 2a. They are in coyote time with a direct action, OR
+	// 2b. They have a valid buffered action from before landing
+	return playerHasNotJumpedSinceGroundTouch &&
+		((playerGroundedWithinCoyoteTime && directJumpAction) || validBufferedJumpInput)
+}
+
+// wallJump jumps an airborne player off a wall they touched within coyote time,
+// reporting whether they did. It takes a fresh press, holding jump from an
+// earlier jump doesn't climb walls. The push away from the wall ignores
+// horizontal input for a few ticks so it sticks
+func wallJump(cursor *warehouse.Cursor, jumpState *components.JumpState, physics Physics, currentTick int) bool {
+	touchingWall, onWall := components.OnWallComponent.GetFromCursorSafe(cursor)
+	if !touchingWall {
+		return false
+	}
+
+	// Off the wall for too long, already jumped since touching it, no press
+	// since the last jump, or the press is older than the buffer window
+	if currentTick-onWall.LastTouch > physics.CoyoteTime || jumpState.LastJump >= onWall.LastTouch {
+		return false
+	}
+	if jumpState.LastPress <= jumpState.LastJump || onWall.LastTouch-jumpState.LastPress > physics.InputBufferTicks {
+		return false
+	}
+
+	// Push away from the wall and face that way
+	dyn := motion.Components.Dynamics.GetFromCursor(cursor)
+	direction := spatial.Components.Direction.GetFromCursor(cursor)
+	dyn.Vel.X = -float64(onWall.Side) * WALL_JUMP_SPEED_X
+	dyn.Vel.Y = -physics.JumpForce
+	dyn.Accel.Y = -physics.JumpForce
+	if onWall.Side > 0 {
+		direction.SetLeft()
+	} else {
+		direction.SetRight()
+	}
+
+	jumpState.LastJump = currentTick
+	jumpState.WallLockout = currentTick + WALL_JUMP_LOCKOUT
+	return true
+}
+
 // handleDown processes down input for platform drop-through functionality
 // This allows players to press down to fall through one-way platforms
 func (PlayerMovementSystem) handleDown(scene blueprint.Scene) error {
 	// Create query for players eligible to drop (have ground and input components)
-	playersEligibleToDropQuery := warehouse.Factory.NewQuery()
-	playersEligibleToDropQuery.And(components.OnGroundComponent, input.Components.ActionBuffer)
+	playersEligibleToDropQuery := newLocalQuery(components.OnGroundComponent, input.Components.ActionBuffer)
 
 	cursor := scene.NewCursor(playersEligibleToDropQuery)
 	currentTick := scene.CurrentTick()
@@ -15,9 +15,11 @@ import (
 // PlayerPlatformCollisionSystem handles collisions between players and one-way platforms.
 // It tracks historical player positions to determine if the player approached from above.
 // This is necessary since collision detection at a discrete step doesn't provide approach direction.
+// Only the platforms the broadphase finds near a player are checked.
 type PlayerPlatformCollisionSystem struct {
 	playerPositionHistory map[uint64][]vector.Two
 	maxPositionsToTrack   int
+	index                 *TerrainIndex
 }
 
 func NewPlayerPlatformCollisionSystem() *PlayerPlatformCollisionSystem {
@@ -25,46 +27,56 @@ func NewPlayerPlatformCollisionSystem() *PlayerPlatformCollisionSystem {
 	return &PlayerPlatformCollisionSystem{
 		playerPositionHistory: make(map[uint64][]vector.Two),
 		maxPositionsToTrack:   trackCount,
+		index:                 NewTerrainIndex(components.PlatformTag),
 	}
 }
 
+// Index is the system's broadphase, e.g. to tune its CellSize
+func (s *PlayerPlatformCollisionSystem) Index() *TerrainIndex {
+	return s.index
+}
+
 func (s *PlayerPlatformCollisionSystem) Run(scene blueprint.Scene, dt float64) error {
-	platformTerrainQuery := warehouse.Factory.NewQuery().And(components.PlatformTag)
-	platformCursor := scene.NewCursor(platformTerrainQuery)
-	playerCursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+	err := s.index.Update(scene)
+	if err != nil {
+		return err
+	}
+	playerCursor := scene.NewCursor(localPlayersQuery)
 
-	for range platformCursor.Next() {
-		for range playerCursor.Next() {
-			playerEntity, err := playerCursor.CurrentEntity()
-			if err != nil {
-				return err
-			}
-			playerID := uint64(playerEntity.ID())
+	for range playerCursor.Next() {
+		playerEntity, err := playerCursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		playerID := uint64(playerEntity.ID())
+
+		playerPos := spatial.Components.Position.GetFromCursor(playerCursor)
+		bounds := playerBounds(spatial.Components.Shape.GetFromCursor(playerCursor), playerPos, BROADPHASE_MARGIN)
 
-			err = s.resolve(scene, platformCursor, playerCursor, playerID)
+		for _, platform := range s.index.Query(scene, bounds) {
+			err = s.resolve(scene, platform, playerCursor, playerID)
 			if err != nil {
 				return err
 			}
-
-			// Track the full position (X and Y) for this specific player
-			playerPos := spatial.Components.Position.GetFromCursor(playerCursor)
-			s.trackPosition(playerID, playerPos.Two)
 		}
+
+		// Track the full position (X and Y) for this specific player, once per tick
+		s.trackPosition(playerID, playerPos.Two)
 	}
 	return nil
 }
 
-func (s *PlayerPlatformCollisionSystem) resolve(scene blueprint.Scene, platformCursor, playerCursor *warehouse.Cursor, playerID uint64) error {
+func (s *PlayerPlatformCollisionSystem) resolve(scene blueprint.Scene, platformEntity warehouse.Entity, playerCursor *warehouse.Cursor, playerID uint64) error {
 	// Get the player state
 	playerShape := spatial.Components.Shape.GetFromCursor(playerCursor)
 	playerPosition := spatial.Components.Position.GetFromCursor(playerCursor)
 	playerDynamics := motion.Components.Dynamics.GetFromCursor(playerCursor)
 
 	// Get the platform state
-	platformShape := spatial.Components.Shape.GetFromCursor(platformCursor)
-	platformPosition := spatial.Components.Position.GetFromCursor(platformCursor)
-	platformRotation := float64(*spatial.Components.Rotation.GetFromCursor(platformCursor))
-	platformDynamics := motion.Components.Dynamics.GetFromCursor(platformCursor)
+	platformShape := spatial.Components.Shape.GetFromEntity(platformEntity)
+	platformPosition := spatial.Components.Position.GetFromEntity(platformEntity)
+	platformRotation := float64(*spatial.Components.Rotation.GetFromEntity(platformEntity))
+	platformDynamics := motion.Components.Dynamics.GetFromEntity(platformEntity)
 
 	// Check for collision
 	if ok, collisionResult := spatial.Detector.Check(
@@ -74,10 +86,6 @@ func (s *PlayerPlatformCollisionSystem) resolve(scene blueprint.Scene, platformC
 		// Check if were ignoring the current platform (dropping down)
 		ignoringPlatforms, ignorePlatform := components.IgnorePlatformComponent.GetFromCursorSafe(playerCursor)
 
-		platformEntity, err := platformCursor.CurrentEntity()
-		if err != nil {
-			return err
-		}
 		if ignoringPlatforms {
 			for _, ignored := range ignorePlatform.Items {
 				if ignored.EntityID == int(platformEntity.ID()) && ignored.Recycled == platformEntity.Recycled() {
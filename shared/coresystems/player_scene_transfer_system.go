@@ -0,0 +1,119 @@
+package coresystems
+
+import (
+	"log"
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+// SceneDirectory lets core systems look up the storage of other scenes by name
+type SceneDirectory struct {
+	mu       sync.Mutex
+	storages map[string]warehouse.Storage
+}
+
+func NewSceneDirectory() *SceneDirectory {
+	return &SceneDirectory{storages: map[string]warehouse.Storage{}}
+}
+
+// Register records the storage backing a scene
+func (d *SceneDirectory) Register(name string, sto warehouse.Storage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.storages[name] = sto
+}
+
+// Storage returns the storage for a scene, if that scene has been registered
+func (d *SceneDirectory) Storage(name string) (warehouse.Storage, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sto, ok := d.storages[name]
+	return sto, ok
+}
+
+// PlayerSceneTransferSystem is the authoritative counterpart of the client's
+// CollisionPlayerTransferSystem. Players touching a PlayerSceneTransfer shape are
+// moved into the destination scene's storage at the target position.
+//
+// It is server only (not part of DefaultCoreSystems) since predicting clients
+// must wait for the server to move their entity
+type PlayerSceneTransferSystem struct {
+	Scene     string
+	Directory *SceneDirectory
+}
+
+type pendingSceneTransfer struct {
+	playerEntity warehouse.Entity
+	transfer     components.PlayerSceneTransfer
+}
+
+func NewPlayerSceneTransferSystem(scene string, directory *SceneDirectory) PlayerSceneTransferSystem {
+	return PlayerSceneTransferSystem{Scene: scene, Directory: directory}
+}
+
+func (s PlayerSceneTransferSystem) Run(scene blueprint.Scene, dt float64) error {
+	// Scenes announce themselves the first time they run
+	s.Directory.Register(s.Scene, scene.Storage())
+
+	// We transfer after the loop
+	// So we collect pending here
+	var pending []pendingSceneTransfer
+
+	collisionTransferQuery := warehouse.Factory.NewQuery().And(
+		spatial.Components.Shape,
+		components.PlayerSceneTransferComponent,
+	)
+	playerWithShapeQuery := warehouse.Factory.NewQuery().And(
+		spatial.Components.Shape,
+		input.Components.ActionBuffer,
+	)
+
+	collisionTransferCursor := scene.NewCursor(collisionTransferQuery)
+	playerWithShapeCursor := scene.NewCursor(playerWithShapeQuery)
+
+	for range collisionTransferCursor.Next() {
+		transferPos := spatial.Components.Position.GetFromCursor(collisionTransferCursor)
+		transferCollider := spatial.Components.Shape.GetFromCursor(collisionTransferCursor)
+
+		for range playerWithShapeCursor.Next() {
+			playerPos := spatial.Components.Position.GetFromCursor(playerWithShapeCursor)
+			playerCollider := spatial.Components.Shape.GetFromCursor(playerWithShapeCursor)
+
+			if ok, _ := spatial.Detector.Check(*playerCollider, *transferCollider, playerPos, transferPos); ok {
+				playerEn, err := playerWithShapeCursor.CurrentEntity()
+				if err != nil {
+					return err
+				}
+				pending = append(pending, pendingSceneTransfer{
+					playerEntity: playerEn,
+					transfer:     *components.PlayerSceneTransferComponent.GetFromCursor(collisionTransferCursor),
+				})
+			}
+		}
+	}
+
+	// Process transfers after loop
+	for _, p := range pending {
+		dest, ok := s.Directory.Storage(p.transfer.Dest)
+		if !ok {
+			log.Printf("Scene transfer from %s to unknown scene %q skipped", s.Scene, p.transfer.Dest)
+			continue
+		}
+
+		playerPos := spatial.Components.Position.GetFromEntity(p.playerEntity)
+		playerPos.X = p.transfer.X
+		playerPos.Y = p.transfer.Y
+		AdoptScenePhysics(p.playerEntity, dest)
+
+		err := scene.Storage().TransferEntities(dest, p.playerEntity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
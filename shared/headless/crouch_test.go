@@ -0,0 +1,68 @@
+package headless_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+)
+
+// A ceiling low enough to clear a crouching player but not a standing one
+const (
+	ceilingBottom = floorTop - (coresystems.CROUCH_HEIGHT+2*playerHalfHeight)/2
+	ceilingHeight = 20.0
+)
+
+func TestCrouchUnderLowCeiling(t *testing.T) {
+	tests := []struct {
+		name          string
+		ceiling       bool
+		wantCrouching bool
+	}{
+		{name: "stands up in the open", ceiling: false, wantCrouching: false},
+		{name: "stays down under a ceiling", ceiling: true, wantCrouching: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player, start := standOnFloor(t, sim)
+			standingY := sim.State(player).Position.Y
+			sim.Hold(player, start, start+10, actions.Crouch)
+			run(t, sim, 10)
+
+			if !sim.State(player).Crouch.Crouching {
+				t.Fatal("holding crouch on the floor didn't crouch")
+			}
+
+			// The ceiling only goes up once the player is crouched under it
+			if tt.ceiling {
+				blocks, err := sim.Storage().NewOrExistingArchetype(scenes.BlockTerrainComposition...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = blocks.Generate(1,
+					spatial.NewPosition(sim.State(player).Position.X, ceilingBottom-ceilingHeight/2),
+					spatial.NewRectangle(100, ceilingHeight),
+					motion.NewDynamics(0),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			run(t, sim, 10)
+
+			state := sim.State(player)
+			if state.Crouch.Crouching != tt.wantCrouching {
+				t.Fatalf("crouching = %v after letting go, want %v", state.Crouch.Crouching, tt.wantCrouching)
+			}
+			if !tt.wantCrouching && math.Abs(state.Position.Y-standingY) > 0.01 {
+				t.Errorf("stood up at y %v, want back at %v", state.Position.Y, standingY)
+			}
+		})
+	}
+}
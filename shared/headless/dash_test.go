@@ -0,0 +1,48 @@
+package headless_test
+
+import (
+	"testing"
+
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+)
+
+func TestDashCooldown(t *testing.T) {
+	tests := []struct {
+		name     string
+		held     bool // Dash is held from the first press to the end
+		again    int  // Ticks after the first press dash is pressed again, 0 for never
+		wantLast int  // Ticks after the first press the latest dash started
+	}{
+		{name: "single press", wantLast: 0},
+		{name: "held through the cooldown", held: true, wantLast: 0},
+		{name: "pressed during the cooldown", again: coresystems.DASH_COOLDOWN - 5, wantLast: 0},
+		{name: "pressed after the cooldown", again: coresystems.DASH_COOLDOWN, wantLast: coresystems.DASH_COOLDOWN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player, start := standOnFloor(t, sim)
+			end := start + coresystems.DASH_COOLDOWN + 20
+			if tt.held {
+				sim.Hold(player, start, end, actions.Dash)
+			} else {
+				sim.Press(player, start, actions.Dash)
+			}
+			if tt.again != 0 {
+				sim.Press(player, start+tt.again, actions.Dash)
+			}
+
+			run(t, sim, 1)
+			if vx := sim.State(player).Velocity.X; vx != coresystems.DASH_SPEED {
+				t.Fatalf("velocity X = %v after pressing dash, want %v", vx, coresystems.DASH_SPEED)
+			}
+			run(t, sim, end-start-1)
+
+			if got := sim.State(player).Dash.LastDash; got != start+tt.wantLast {
+				t.Errorf("latest dash started at tick %d, want %d", got, start+tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Package headless runs the core systems without a client or server.
+//
+// A Simulation owns its own storage, builds it from a plan (or an LDtk scene from
+// the catalog) and steps the core systems with a fixed dt. Inputs are scheduled
+// per tick ahead of time, so a run is fully deterministic and the resulting
+// player state can be inspected after every step.
+package headless
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/table"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
+)
+
+const (
+	DEFAULT_TICK_RATE = 60
+	FIXED_DT          = 1.0 / DEFAULT_TICK_RATE
+)
+
+var ErrUnknownScene = errors.New("headless: unknown scene")
+
+// Simulation is a headless scene, it satisfies blueprint.Scene so the core
+// systems can run against it directly
+type Simulation struct {
+	Systems []blueprint.CoreSystem
+	DT      float64
+
+	name          string
+	width, height int
+	storage       warehouse.Storage
+	tick          int
+	inputs        map[int][]scheduledInput
+}
+
+type scheduledInput struct {
+	entity warehouse.Entity
+	action input.StampedAction
+}
+
+// New builds a simulation from a plan, running the given systems (usually a
+// fresh coresystems.NewCoreSystems, systems must not be shared between
+// simulations)
+func New(name string, width, height int, plan blueprint.Plan, systems []blueprint.CoreSystem) (*Simulation, error) {
+	schema := table.Factory.NewSchema()
+	sim := &Simulation{
+		Systems: systems,
+		DT:      FIXED_DT,
+		name:    name,
+		width:   width,
+		height:  height,
+		storage: warehouse.Factory.NewStorage(schema),
+		inputs:  map[int][]scheduledInput{},
+	}
+
+	if plan != nil {
+		err := plan(width, height, sim.storage)
+		if err != nil {
+			return nil, fmt.Errorf("headless: building %s: %w", name, err)
+		}
+	}
+	return sim, nil
+}
+
+// FromScene builds a simulation for a scene in the catalog, running its own
+// set of core systems with the level's physics
+//
+// Some core systems hold state (the collision broadphase and platform
+// tracking), so every simulation gets fresh ones and can be stepped in
+// parallel with others
+func FromScene(name string) (*Simulation, error) {
+	scene, ok := scenes.ByName(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScene, name)
+	}
+	systems := coresystems.NewCoreSystems(scenes.LevelMovementConfig(name))
+	return New(scene.Name, scene.Width, scene.Height, scene.Plan, systems)
+}
+
+// SpawnPlayer adds a player at the given position
+func (sim *Simulation) SpawnPlayer(x, y float64) (warehouse.Entity, error) {
+	return scenes.NewPlayer(x, y, sim.storage)
+}
+
+// SpawnPlayerAtStart adds a player at the scene's first PlayerSpawn
+func (sim *Simulation) SpawnPlayerAtStart() (warehouse.Entity, error) {
+	query := warehouse.Factory.NewQuery().And(components.PlayerSpawnComponent)
+	cursor := sim.NewCursor(query)
+
+	for range cursor.Next() {
+		spawn := components.PlayerSpawnComponent.GetFromCursor(cursor)
+		x, y := spawn.X, spawn.Y
+		cursor.Reset()
+		return sim.SpawnPlayer(x, y)
+	}
+	return nil, fmt.Errorf("headless: %s has no PlayerSpawn", sim.name)
+}
+
+// SpawnPlayerWith adds a player of team at a spawn point chosen by policy
+func (sim *Simulation) SpawnPlayerWith(policy spawn.Policy, team string) (warehouse.Entity, error) {
+	point, ok := spawn.Select(sim.storage, policy, team)
+	if !ok {
+		return nil, fmt.Errorf("headless: %s has no PlayerSpawn", sim.name)
+	}
+	return sim.SpawnPlayer(point.X, point.Y)
+}
+
+// Press schedules actions for an entity on a tick
+func (sim *Simulation) Press(en warehouse.Entity, tick int, actions ...input.Action) {
+	for _, action := range actions {
+		sim.inputs[tick] = append(sim.inputs[tick], scheduledInput{
+			entity: en,
+			action: input.StampedAction{Tick: tick, Val: action},
+		})
+	}
+}
+
+// Hold schedules actions for an entity on every tick in [from, to)
+func (sim *Simulation) Hold(en warehouse.Entity, from, to int, actions ...input.Action) {
+	for tick := from; tick < to; tick++ {
+		sim.Press(en, tick, actions...)
+	}
+}
+
+// Deliver adds already stamped actions to an entity's buffer for the current tick
+func (sim *Simulation) Deliver(en warehouse.Entity, actions ...input.StampedAction) {
+	buffer := input.Components.ActionBuffer.GetFromEntity(en)
+	buffer.Add(actions...)
+}
+
+// SetTickRate steps the simulation with the dt of a server running at rate
+// ticks per second, zero is ignored
+func (sim *Simulation) SetTickRate(rate int) {
+	if rate > 0 {
+		sim.DT = 1 / float64(rate)
+	}
+}
+
+// SetTick moves the simulation clock, for example to line up with a recording
+func (sim *Simulation) SetTick(tick int) {
+	sim.tick = tick
+}
+
+// Step delivers the inputs scheduled for the current tick, runs every system
+// once and advances the tick
+func (sim *Simulation) Step() error {
+	for _, in := range sim.inputs[sim.tick] {
+		if !in.entity.Valid() {
+			continue
+		}
+		buffer := input.Components.ActionBuffer.GetFromEntity(in.entity)
+		buffer.Add(in.action)
+	}
+	delete(sim.inputs, sim.tick)
+
+	for _, sys := range sim.Systems {
+		err := sys.Run(sim, sim.DT)
+		if err != nil {
+			return fmt.Errorf("headless: tick %d: %w", sim.tick, err)
+		}
+	}
+	sim.tick++
+	return nil
+}
+
+// Run steps the simulation n times
+func (sim *Simulation) Run(n int) error {
+	for range n {
+		err := sim.Step()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunUntil steps until done reports true, giving up after max ticks
+func (sim *Simulation) RunUntil(max int, done func(*Simulation) bool) (bool, error) {
+	for range max {
+		if done(sim) {
+			return true, nil
+		}
+		err := sim.Step()
+		if err != nil {
+			return false, err
+		}
+	}
+	return done(sim), nil
+}
+
+// State returns the replicated state of a player, the same fields a snapshot carries
+func (sim *Simulation) State(en warehouse.Entity) snapshot.EntityState {
+	return snapshot.Capture(en)
+}
+
+// Grounded reports whether a player touched the ground on the previous tick
+func (sim *Simulation) Grounded(en warehouse.Entity) bool {
+	if !en.Table().Contains(components.OnGroundComponent) {
+		return false
+	}
+	onGround := components.OnGroundComponent.GetFromEntity(en)
+	return onGround.LastTouch == sim.tick-1
+}
+
+// TouchingWall reports whether a player touched a wall on the previous tick
+func (sim *Simulation) TouchingWall(en warehouse.Entity) bool {
+	if !en.Table().Contains(components.OnWallComponent) {
+		return false
+	}
+	onWall := components.OnWallComponent.GetFromEntity(en)
+	return onWall.LastTouch == sim.tick-1
+}
+
+// blueprint.Scene
+
+func (sim *Simulation) NewCursor(query warehouse.QueryNode) *warehouse.Cursor {
+	return warehouse.Factory.NewCursor(query, sim.storage)
+}
+
+func (sim *Simulation) CurrentTick() int {
+	return sim.tick
+}
+
+func (sim *Simulation) LastSelectedTick() int {
+	return 0
+}
+
+func (sim *Simulation) Storage() warehouse.Storage {
+	return sim.storage
+}
+
+func (sim *Simulation) Width() int {
+	return sim.width
+}
+
+func (sim *Simulation) Height() int {
+	return sim.height
+}
+
+func (sim *Simulation) Name() string {
+	return sim.name
+}
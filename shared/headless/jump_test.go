@@ -0,0 +1,99 @@
+package headless_test
+
+import (
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+)
+
+// standOnFloor spawns a player above the floor and steps until they land,
+// returning the tick they can first jump on
+func standOnFloor(t *testing.T, sim *headless.Simulation) (warehouse.Entity, int) {
+	t.Helper()
+	player := spawn(t, sim, 100, floorTop-playerHalfHeight)
+	landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+	if err != nil || !landed {
+		t.Fatalf("player never landed (%v)", err)
+	}
+	return player, sim.CurrentTick()
+}
+
+// untilLanded steps until the player leaves the ground and lands again,
+// returning the highest point reached in between
+func untilLanded(t *testing.T, sim *headless.Simulation, player warehouse.Entity) float64 {
+	t.Helper()
+	left, err := sim.RunUntil(30, func(sim *headless.Simulation) bool { return !sim.Grounded(player) })
+	if err != nil || !left {
+		t.Fatalf("player never left the ground (%v)", err)
+	}
+	apex := sim.State(player).Position.Y
+	landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool {
+		apex = min(apex, sim.State(player).Position.Y)
+		return sim.Grounded(player)
+	})
+	if err != nil || !landed {
+		t.Fatalf("player never landed (%v)", err)
+	}
+	return apex
+}
+
+func TestJumpCut(t *testing.T) {
+	// jumpApex holds jump for hold ticks from the ground and returns the apex
+	jumpApex := func(hold int) float64 {
+		sim := newSim(t)
+		player, start := standOnFloor(t, sim)
+		sim.Hold(player, start, start+hold, actions.Jump)
+		return untilLanded(t, sim, player)
+	}
+
+	full := jumpApex(120)
+	cut := jumpApex(3)
+	if full >= floorTop-playerHalfHeight {
+		t.Fatalf("holding jump peaked at y %v, never left the floor", full)
+	}
+	// Y grows downwards, a lower jump peaks at a larger Y
+	if cut <= full {
+		t.Errorf("releasing early peaked at y %v, want lower than the full jump at %v", cut, full)
+	}
+}
+
+func TestAirJumpsResetOnLanding(t *testing.T) {
+	sim := newSim(t)
+	player, start := standOnFloor(t, sim)
+	config := components.MovementConfigComponent.GetFromEntity(player)
+	config.AirJumps = 1
+
+	// jumpTwice jumps from the ground, then presses jump again on the way up
+	// and once more after that
+	jumpTwice := func(start int) {
+		t.Helper()
+		sim.Press(player, start, actions.Jump)
+		sim.Press(player, start+10, actions.Jump)
+		sim.Press(player, start+20, actions.Jump)
+		run(t, sim, 11)
+
+		jump := sim.State(player).Jump
+		if jump.AirJumps != 1 || jump.LastJump != start+10 {
+			t.Fatalf("air jumps = %d, last jump %d, want 1 at tick %d", jump.AirJumps, jump.LastJump, start+10)
+		}
+		run(t, sim, 10)
+		if jump := sim.State(player).Jump; jump.LastJump != start+10 {
+			t.Fatalf("jumped again at tick %d with no air jumps left", jump.LastJump)
+		}
+	}
+
+	jumpTwice(start)
+	landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+	if err != nil || !landed {
+		t.Fatalf("player never landed (%v)", err)
+	}
+	run(t, sim, 1)
+	if got := sim.State(player).Jump.AirJumps; got != 0 {
+		t.Fatalf("air jumps = %d after landing, want 0", got)
+	}
+
+	jumpTwice(sim.CurrentTick())
+}
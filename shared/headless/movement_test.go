@@ -0,0 +1,308 @@
+package headless_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	levelWidth  = 1600
+	levelHeight = 800
+
+	// The floor spans x 0 to floorRight with its top at floorTop
+	floorTop   = 480.0
+	floorRight = 800.0
+
+	// A flat one way platform above the floor
+	platformX = 400.0
+	platformY = 300.0
+
+	// A double ramp resting on the floor
+	rampX = 600.0
+
+	playerHalfHeight = 29.0
+)
+
+var physics = coresystems.DefaultPhysics()
+
+// testLevel has a floor that ends in a ledge, a one way platform and a ramp
+func testLevel(width, height int, sto warehouse.Storage) error {
+	blocks, err := sto.NewOrExistingArchetype(scenes.BlockTerrainComposition...)
+	if err != nil {
+		return err
+	}
+	err = blocks.Generate(1,
+		spatial.NewPosition(floorRight/2, floorTop+20),
+		spatial.NewRectangle(floorRight, 40),
+		motion.NewDynamics(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = scenes.NewPlatformRotated(sto, platformX, platformY, 0)
+	if err != nil {
+		return err
+	}
+	return scenes.NewRamp(sto, rampX, floorTop-23)
+}
+
+func newSim(t *testing.T) *headless.Simulation {
+	t.Helper()
+	sim, err := headless.New("movement", levelWidth, levelHeight, testLevel, coresystems.NewCoreSystems(physics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+func spawn(t *testing.T, sim *headless.Simulation, x, y float64) warehouse.Entity {
+	t.Helper()
+	player, err := sim.SpawnPlayer(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return player
+}
+
+func run(t *testing.T, sim *headless.Simulation, ticks int) {
+	t.Helper()
+	err := sim.Run(ticks)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// landingTick drops a player from (x, y) and returns the tick they touch down
+func landingTick(t *testing.T, x, y float64) int {
+	t.Helper()
+	sim := newSim(t)
+	player := spawn(t, sim, x, y)
+	landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+	if err != nil || !landed {
+		t.Fatalf("player dropped from (%v, %v) never landed (%v)", x, y, err)
+	}
+	return sim.State(player).OnGround.LastTouch
+}
+
+// ledgeTick walks a player off the end of the floor and returns the last tick
+// they touched it
+func ledgeTick(t *testing.T) int {
+	t.Helper()
+	sim := newSim(t)
+	player := spawn(t, sim, floorRight-60, floorTop-playerHalfHeight)
+	sim.Hold(player, 0, 300, actions.Right)
+	left, err := sim.RunUntil(300, func(sim *headless.Simulation) bool {
+		return sim.State(player).Position.X > floorRight && !sim.Grounded(player)
+	})
+	if err != nil || !left {
+		t.Fatalf("player never walked off the ledge (%v)", err)
+	}
+	return sim.State(player).OnGround.LastTouch
+}
+
+func TestJumpBuffering(t *testing.T) {
+	const dropX, dropY = 100.0, 300.0
+	landed := landingTick(t, dropX, dropY)
+
+	tests := []struct {
+		name      string
+		early     int // Ticks before landing jump is pressed
+		wantsJump bool
+	}{
+		{name: "pressed on the landing tick", early: 0, wantsJump: true},
+		{name: "pressed just before landing", early: 2, wantsJump: true},
+		{name: "pressed at the edge of the buffer", early: physics.InputBufferTicks, wantsJump: true},
+		{name: "pressed too early", early: physics.InputBufferTicks + 3, wantsJump: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player := spawn(t, sim, dropX, dropY)
+			sim.Press(player, landed-tt.early, actions.Jump)
+			run(t, sim, landed+4)
+
+			jumped := sim.State(player).Jump.LastJump == landed+1
+			if jumped != tt.wantsJump {
+				t.Errorf("jumped = %v (last jump %d, landed %d), want %v",
+					jumped, sim.State(player).Jump.LastJump, landed, tt.wantsJump)
+			}
+		})
+	}
+}
+
+func TestCoyoteTime(t *testing.T) {
+	lastTouch := ledgeTick(t)
+
+	tests := []struct {
+		name      string
+		late      int // Ticks after leaving the ledge jump is pressed
+		wantsJump bool
+	}{
+		{name: "pressed right after leaving", late: 1, wantsJump: true},
+		{name: "pressed at the end of coyote time", late: physics.CoyoteTime, wantsJump: true},
+		{name: "pressed after coyote time", late: physics.CoyoteTime + 2, wantsJump: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player := spawn(t, sim, floorRight-60, floorTop-playerHalfHeight)
+			sim.Hold(player, 0, lastTouch+40, actions.Right)
+			pressed := lastTouch + tt.late
+			sim.Press(player, pressed, actions.Jump)
+			run(t, sim, pressed+2)
+
+			jumped := sim.State(player).Jump.LastJump == pressed
+			if jumped != tt.wantsJump {
+				t.Errorf("jumped = %v (last jump %d, pressed %d), want %v",
+					jumped, sim.State(player).Jump.LastJump, pressed, tt.wantsJump)
+			}
+		})
+	}
+}
+
+func TestSlopeNormal(t *testing.T) {
+	tests := []struct {
+		name string
+		x    float64
+		flat bool
+	}{
+		{name: "flat floor", x: 100, flat: true},
+		{name: "left side of the ramp", x: rampX - 90},
+		{name: "right side of the ramp", x: rampX + 90},
+	}
+
+	normals := map[string]float64{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player := spawn(t, sim, tt.x, floorTop-150)
+			landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+			if err != nil || !landed {
+				t.Fatalf("player never landed (%v)", err)
+			}
+
+			normal := sim.State(player).OnGround.SlopeNormal
+			if length := math.Hypot(normal.X, normal.Y); math.Abs(length-1) > 1e-6 {
+				t.Errorf("normal %+v is not a unit vector", normal)
+			}
+			if tt.flat && (normal.X != 0 || normal.Y != 1) {
+				t.Errorf("flat ground normal = %+v, want (0, 1)", normal)
+			}
+			if !tt.flat && normal.X == 0 {
+				t.Errorf("ramp normal = %+v, want a slope", normal)
+			}
+			normals[tt.name] = normal.X
+		})
+	}
+
+	left, right := normals["left side of the ramp"], normals["right side of the ramp"]
+	if left*right >= 0 {
+		t.Errorf("ramp sides should slope opposite ways, got normal X %v and %v", left, right)
+	}
+}
+
+func TestPlatformDropThrough(t *testing.T) {
+	tests := []struct {
+		name      string
+		pressDown bool
+		wantFloor bool
+	}{
+		{name: "stands on the platform", pressDown: false, wantFloor: false},
+		{name: "down drops through", pressDown: true, wantFloor: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			player := spawn(t, sim, platformX, platformY-80)
+			landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+			if err != nil || !landed {
+				t.Fatalf("player never landed on the platform (%v)", err)
+			}
+			if y := sim.State(player).Position.Y; y > platformY {
+				t.Fatalf("player landed at y %v, below the platform at %v", y, platformY)
+			}
+
+			if tt.pressDown {
+				sim.Hold(player, sim.CurrentTick(), sim.CurrentTick()+3, actions.Down)
+			}
+			run(t, sim, 120)
+
+			onFloor := sim.State(player).Position.Y > platformY
+			if onFloor != tt.wantFloor {
+				t.Errorf("on the floor = %v (y %v), want %v", onFloor, sim.State(player).Position.Y, tt.wantFloor)
+			}
+		})
+	}
+}
+
+func TestDashHoldsHeight(t *testing.T) {
+	sim := newSim(t)
+	// Past the end of the floor, falling
+	player := spawn(t, sim, floorRight+400, 100)
+	run(t, sim, 10)
+
+	sim.Press(player, sim.CurrentTick(), actions.Dash)
+	before := sim.State(player).Position
+	run(t, sim, coresystems.DASH_TICKS)
+	after := sim.State(player).Position
+
+	if after.Y != before.Y {
+		t.Errorf("dash moved from y %v to %v, want it level", before.Y, after.Y)
+	}
+	if after.X == before.X {
+		t.Errorf("dash didn't move the player")
+	}
+}
+
+// TestFromSceneSimulationsAreIndependent steps two simulations of the same
+// scene in lock step, each must end where a simulation stepped alone does
+func TestFromSceneSimulationsAreIndependent(t *testing.T) {
+	build := func() (*headless.Simulation, warehouse.Entity) {
+		sim, err := headless.FromScene(scenes.SCENE_ONE_NAME)
+		if err != nil {
+			t.Fatal(err)
+		}
+		player, err := sim.SpawnPlayerAtStart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.Hold(player, 10, 90, actions.Right)
+		sim.Press(player, 40, actions.Jump)
+		return sim, player
+	}
+
+	alone, alonePlayer := build()
+	run(t, alone, 120)
+	want := alone.State(alonePlayer)
+
+	first, firstPlayer := build()
+	second, secondPlayer := build()
+	for range 120 {
+		run(t, first, 1)
+		run(t, second, 1)
+	}
+
+	for name, got := range map[string]snapshot.EntityState{
+		"first":  first.State(firstPlayer),
+		"second": second.State(secondPlayer),
+	} {
+		got.ID, want.ID = 0, 0
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s interleaved simulation ended on %+v\nwant %+v", name, got, want)
+		}
+	}
+}
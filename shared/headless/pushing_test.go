@@ -0,0 +1,205 @@
+package headless_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// A heavy player standing left of a light one walks right into it
+const (
+	pusherX     = 200.0
+	pushedX     = 240.0
+	pusherMass  = 20.0
+	pushedMass  = 5.0
+	pushTicks   = 90
+	playerWidth = 18.0
+)
+
+var pushPhysics = func() coresystems.Physics {
+	p := physics
+	p.PlayerCollision = true
+	return p
+}()
+
+// probe records the players' states every time it runs
+type probe struct {
+	players []warehouse.Entity
+	states  map[int][]snapshot.EntityState
+}
+
+func newProbe() *probe {
+	return &probe{states: map[int][]snapshot.EntityState{}}
+}
+
+func (p *probe) Run(scene blueprint.Scene, dt float64) error {
+	states := []snapshot.EntityState{}
+	for _, en := range p.players {
+		states = append(states, snapshot.Capture(en))
+	}
+	p.states[scene.CurrentTick()] = states
+	return nil
+}
+
+// pushRun is the server's side of a push, with both players' states recorded
+// right before and after the player collision step, pusher first
+type pushRun struct {
+	pusher, pushed warehouse.Entity
+	before, after  *probe
+}
+
+// spawnPushers spawns the pusher and the pushed player, the pushed one first
+// when pushedFirst so it comes first in ID and cursor order
+func spawnPushers(t *testing.T, sim *headless.Simulation, pushedFirst bool) (pusher, pushed warehouse.Entity) {
+	t.Helper()
+	if pushedFirst {
+		pushed = spawnWithMass(t, sim, pushedX, pushedMass)
+		pusher = spawnWithMass(t, sim, pusherX, pusherMass)
+		return pusher, pushed
+	}
+	pusher = spawnWithMass(t, sim, pusherX, pusherMass)
+	pushed = spawnWithMass(t, sim, pushedX, pushedMass)
+	return pusher, pushed
+}
+
+func spawnWithMass(t *testing.T, sim *headless.Simulation, x, mass float64) warehouse.Entity {
+	t.Helper()
+	player := spawn(t, sim, x, floorTop-playerHalfHeight)
+	config := components.MovementConfigComponent.GetFromEntity(player)
+	*config = pushPhysics
+	config.PushMass = mass
+	return player
+}
+
+// runPush holds right on the pusher for pushTicks with the full core systems
+func runPush(t *testing.T, pushedFirst bool) pushRun {
+	t.Helper()
+	before, after := newProbe(), newProbe()
+	systems := []blueprint.CoreSystem{}
+	for _, sys := range coresystems.NewCoreSystems(pushPhysics) {
+		if _, ok := sys.(coresystems.PlayerCollisionSystem); ok {
+			systems = append(systems, before, sys, after)
+			continue
+		}
+		systems = append(systems, sys)
+	}
+	sim, err := headless.New("pushing", levelWidth, levelHeight, testLevel, systems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := pushRun{before: before, after: after}
+	r.pusher, r.pushed = spawnPushers(t, sim, pushedFirst)
+	before.players = []warehouse.Entity{r.pusher, r.pushed}
+	after.players = before.players
+
+	sim.Hold(r.pusher, 0, pushTicks, actions.Right)
+	run(t, sim, pushTicks)
+	return r
+}
+
+// withoutIDs blanks the IDs, which depend on the spawn order
+func withoutIDs(states []snapshot.EntityState) []snapshot.EntityState {
+	out := []snapshot.EntityState{}
+	for _, state := range states {
+		state.ID = 0
+		out = append(out, state)
+	}
+	return out
+}
+
+func TestPushingIgnoresCursorOrder(t *testing.T) {
+	pusherFirst := runPush(t, false)
+	pushedFirst := runPush(t, true)
+
+	for tick := range pushTicks {
+		got := withoutIDs(pushedFirst.after.states[tick])
+		want := withoutIDs(pusherFirst.after.states[tick])
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("tick %d: spawning the pushed player first gave\n%+v\nwant %+v", tick, got, want)
+		}
+	}
+
+	final := pusherFirst.after.states[pushTicks-1]
+	pusher, pushed := final[0], final[1]
+	if pushed.Position.X <= pushedX+playerWidth {
+		t.Errorf("pushed player ended at x %v, want it pushed well past %v", pushed.Position.X, pushedX)
+	}
+	if gap := pushed.Position.X - pusher.Position.X; gap < playerWidth-1e-9 {
+		t.Errorf("players still overlap, %v apart", gap)
+	}
+}
+
+// TestPushPredictionMatchesServer replays the server's push on a client that
+// runs the collision step with the other player remote. Every tick starts from
+// the server's states before the step, the local player must come out of it
+// exactly like on the server
+func TestPushPredictionMatchesServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		pushedFirst  bool
+		remotePusher bool
+	}{
+		{name: "local pusher", pushedFirst: false, remotePusher: false},
+		{name: "local pusher spawned last", pushedFirst: true, remotePusher: false},
+		{name: "local pushed player", pushedFirst: false, remotePusher: true},
+		{name: "local pushed player spawned first", pushedFirst: true, remotePusher: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := runPush(t, tt.pushedFirst)
+
+			client, err := headless.New("pushing", levelWidth, levelHeight, testLevel,
+				[]blueprint.CoreSystem{coresystems.PlayerCollisionSystem{Physics: pushPhysics}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pusher, pushed := spawnPushers(t, client, tt.pushedFirst)
+			players := []warehouse.Entity{pusher, pushed}
+			local, remote := 0, 1
+			if tt.remotePusher {
+				local, remote = 1, 0
+			}
+			err = players[remote].AddComponent(components.RemoteTag)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pushes := 0
+			for tick := range pushTicks {
+				before := server.before.states[tick]
+				for i, en := range players {
+					err := snapshot.Apply(en, before[i])
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				client.SetTick(tick)
+				err := client.Step()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := snapshot.Capture(players[local])
+				want := server.after.states[tick][local]
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("tick %d: predicted\n%+v\nserver %+v", tick, got, want)
+				}
+				if want.Position.X != before[local].Position.X {
+					pushes++
+				}
+			}
+			if pushes == 0 {
+				t.Fatal("the players never collided")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package headless_test
+
+import (
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+)
+
+// A wall standing on the floor, left of the platform
+const (
+	wallX      = 200.0
+	wallWidth  = 40.0
+	wallHeight = 400.0
+)
+
+// wallLevel is testLevel with a tall wall on the floor
+func wallLevel(width, height int, sto warehouse.Storage) error {
+	err := testLevel(width, height, sto)
+	if err != nil {
+		return err
+	}
+	blocks, err := sto.NewOrExistingArchetype(scenes.BlockTerrainComposition...)
+	if err != nil {
+		return err
+	}
+	return blocks.Generate(1,
+		spatial.NewPosition(wallX, floorTop-wallHeight/2),
+		spatial.NewRectangle(wallWidth, wallHeight),
+		motion.NewDynamics(0),
+	)
+}
+
+// jumpAtWall stands a player left of the wall, then holds right and jumps at
+// the tick after it returns
+func jumpAtWall(t *testing.T) (*headless.Simulation, warehouse.Entity, int) {
+	t.Helper()
+	sim, err := headless.New("walls", levelWidth, levelHeight, wallLevel, coresystems.NewCoreSystems(physics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := spawn(t, sim, wallX-wallWidth/2-40, floorTop-playerHalfHeight)
+	landed, err := sim.RunUntil(300, func(sim *headless.Simulation) bool { return sim.Grounded(player) })
+	if err != nil || !landed {
+		t.Fatalf("player never landed (%v)", err)
+	}
+	start := sim.CurrentTick()
+	sim.Hold(player, start, start+120, actions.Right)
+	return sim, player, start
+}
+
+// reachWall steps until the airborne player touches the wall, returning the
+// tick of the touch
+func reachWall(t *testing.T, sim *headless.Simulation, player warehouse.Entity) int {
+	t.Helper()
+	reached, err := sim.RunUntil(120, func(sim *headless.Simulation) bool {
+		return sim.TouchingWall(player) && !sim.Grounded(player)
+	})
+	if err != nil || !reached {
+		t.Fatalf("player never reached the wall mid-air (%v)", err)
+	}
+	return sim.CurrentTick() - 1
+}
+
+func wallJumped(sim *headless.Simulation, player warehouse.Entity) bool {
+	return sim.State(player).Jump.WallLockout != 0
+}
+
+func TestWallJumpNeedsFreshPress(t *testing.T) {
+	t.Run("holding jump from the ground", func(t *testing.T) {
+		sim, player, start := jumpAtWall(t)
+		sim.Hold(player, start, start+120, actions.Jump)
+		reachWall(t, sim, player)
+		run(t, sim, physics.CoyoteTime+2)
+
+		if wallJumped(sim, player) {
+			t.Errorf("held jump wall jumped (last jump %d)", sim.State(player).Jump.LastJump)
+		}
+	})
+
+	t.Run("pressing again on the wall", func(t *testing.T) {
+		sim, player, start := jumpAtWall(t)
+		sim.Press(player, start, actions.Jump)
+		reachWall(t, sim, player)
+		sim.Press(player, sim.CurrentTick(), actions.Jump)
+		run(t, sim, 2)
+
+		if !wallJumped(sim, player) {
+			t.Fatalf("a fresh press on the wall didn't wall jump")
+		}
+		if vx := sim.State(player).Velocity.X; vx >= 0 {
+			t.Errorf("wall jump velocity X = %v, want a push away from the wall", vx)
+		}
+	})
+}
+
+func TestWallJumpBuffering(t *testing.T) {
+	sim, player, start := jumpAtWall(t)
+	sim.Press(player, start, actions.Jump)
+	touched := reachWall(t, sim, player)
+
+	tests := []struct {
+		name      string
+		early     int // Ticks before touching the wall jump is pressed again
+		wantsJump bool
+	}{
+		{name: "pressed on the touch", early: 0, wantsJump: true},
+		{name: "pressed at the edge of the buffer", early: physics.InputBufferTicks, wantsJump: true},
+		{name: "pressed too early", early: physics.InputBufferTicks + 2, wantsJump: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if touched-tt.early <= start+1 {
+				t.Skipf("the wall is reached %d ticks after jumping, too soon to press again", touched-start)
+			}
+			sim, player, start := jumpAtWall(t)
+			sim.Press(player, start, actions.Jump)
+			sim.Press(player, touched-tt.early, actions.Jump)
+			run(t, sim, touched-start+2)
+
+			if got := wallJumped(sim, player); got != tt.wantsJump {
+				t.Errorf("wall jumped = %v, want %v", got, tt.wantsJump)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package interpolation smooths remote players on the networked client.
+//
+// Snapshots are buffered by tick and remote entities are shown a fixed delay in
+// the past, so there is (usually) a snapshot on either side of the render tick
+// to interpolate between. When packets stop arriving the last known state is
+// extrapolated for a short while before freezing.
+package interpolation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	DEFAULT_CAPACITY      = 32
+	DEFAULT_DELAY         = 100 * time.Millisecond
+	DEFAULT_EXTRAPOLATION = 150 * time.Millisecond
+	DEFAULT_TICK_RATE     = 60
+)
+
+// Buffer keeps the most recent snapshots ordered by tick
+type Buffer struct {
+	// DelayTicks is how far behind the newest state remote entities are rendered
+	DelayTicks int
+	// MaxExtrapolationTicks caps how far past the newest snapshot an entity is projected
+	MaxExtrapolationTicks int
+	// TickDuration converts velocities (per second) into per tick offsets
+	TickDuration float64
+
+	capacity      int
+	delay         time.Duration
+	extrapolation time.Duration
+	snapshots     []snapshot.Snapshot
+}
+
+// NewBuffer creates a buffer for a server running at tickRate
+func NewBuffer(capacity int, delay, extrapolation time.Duration, tickRate int) *Buffer {
+	b := &Buffer{
+		capacity:      capacity,
+		delay:         delay,
+		extrapolation: extrapolation,
+		snapshots:     make([]snapshot.Snapshot, 0, capacity),
+	}
+	b.SetTickRate(tickRate)
+	return b
+}
+
+// SetTickRate converts the delay and extrapolation cap to ticks at a new
+// server tick rate, zero is ignored
+func (b *Buffer) SetTickRate(tickRate int) {
+	if tickRate <= 0 {
+		return
+	}
+	b.DelayTicks = durationToTicks(b.delay, tickRate)
+	b.MaxExtrapolationTicks = durationToTicks(b.extrapolation, tickRate)
+	b.TickDuration = 1 / float64(tickRate)
+}
+
+// NewDefaultBuffer creates a buffer with a 100ms delay for a 60 tick server,
+// SetTickRate adjusts it once the server reports its rate
+func NewDefaultBuffer() *Buffer {
+	return NewBuffer(DEFAULT_CAPACITY, DEFAULT_DELAY, DEFAULT_EXTRAPOLATION, DEFAULT_TICK_RATE)
+}
+
+// Push stores a snapshot, replacing any existing one for the same tick and
+// discarding the oldest once capacity is reached
+func (b *Buffer) Push(snap snapshot.Snapshot) {
+	i := sort.Search(len(b.snapshots), func(i int) bool { return b.snapshots[i].Tick >= snap.Tick })
+	if i < len(b.snapshots) && b.snapshots[i].Tick == snap.Tick {
+		b.snapshots[i] = snap
+		return
+	}
+
+	b.snapshots = append(b.snapshots, snapshot.Snapshot{})
+	copy(b.snapshots[i+1:], b.snapshots[i:])
+	b.snapshots[i] = snap
+
+	if len(b.snapshots) > b.capacity {
+		b.snapshots = b.snapshots[len(b.snapshots)-b.capacity:]
+	}
+}
+
+// Reset drops every buffered snapshot, for example after changing scenes
+func (b *Buffer) Reset() {
+	b.snapshots = b.snapshots[:0]
+}
+
+// Len returns the number of buffered snapshots
+func (b *Buffer) Len() int {
+	return len(b.snapshots)
+}
+
+// Newest returns the tick of the most recent snapshot
+func (b *Buffer) Newest() (int, bool) {
+	if len(b.snapshots) == 0 {
+		return 0, false
+	}
+	return b.snapshots[len(b.snapshots)-1].Tick, true
+}
+
+// Sample returns the state of an entity at the given (possibly fractional) tick
+//
+// Position and velocity are linearly interpolated between the surrounding
+// snapshots, discrete state (direction, jump and ground state) snaps to the older one
+func (b *Buffer) Sample(id uint32, tick float64) (snapshot.EntityState, bool) {
+	var (
+		before, after         snapshot.EntityState
+		beforeTick, afterTick int
+		hasBefore, hasAfter   bool
+	)
+
+	// Latest snapshot at or before tick that knows the entity
+	for i := len(b.snapshots) - 1; i >= 0; i-- {
+		if float64(b.snapshots[i].Tick) > tick {
+			continue
+		}
+		if state, ok := b.snapshots[i].Find(id); ok {
+			before, beforeTick, hasBefore = state, b.snapshots[i].Tick, true
+			break
+		}
+	}
+
+	// Earliest snapshot after tick that knows the entity
+	for i := range b.snapshots {
+		if float64(b.snapshots[i].Tick) <= tick {
+			continue
+		}
+		if state, ok := b.snapshots[i].Find(id); ok {
+			after, afterTick, hasAfter = state, b.snapshots[i].Tick, true
+			break
+		}
+	}
+
+	switch {
+	case hasBefore && hasAfter:
+		alpha := (tick - float64(beforeTick)) / float64(afterTick-beforeTick)
+		out := before
+		out.Position.X = lerp(before.Position.X, after.Position.X, alpha)
+		out.Position.Y = lerp(before.Position.Y, after.Position.Y, alpha)
+		out.Velocity.X = lerp(before.Velocity.X, after.Velocity.X, alpha)
+		out.Velocity.Y = lerp(before.Velocity.Y, after.Velocity.Y, alpha)
+		return out, true
+
+	case hasBefore:
+		// Packet loss (or a departed entity), project along the last velocity
+		ahead := min(tick-float64(beforeTick), float64(b.MaxExtrapolationTicks))
+		out := before
+		out.Position.X += before.Velocity.X * ahead * b.TickDuration
+		out.Position.Y += before.Velocity.Y * ahead * b.TickDuration
+		return out, true
+
+	case hasAfter:
+		// Entity only exists in the future of the render tick
+		return after, true
+	}
+	return snapshot.EntityState{}, false
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + t*(b-a)
+}
+
+func durationToTicks(d time.Duration, tickRate int) int {
+	return int(d.Seconds()*float64(tickRate) + 0.5)
+}
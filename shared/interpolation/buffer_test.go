@@ -0,0 +1,200 @@
+package interpolation
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	testTickRate = 60
+	testEntity   = 7
+	testOther    = 9
+)
+
+// moving is an entity travelling 60px/s along X, 1px per tick at 60 ticks/s
+func moving(id uint32, tick int) snapshot.EntityState {
+	return snapshot.EntityState{
+		ID:       id,
+		Position: vector.Two{X: float64(tick), Y: 100},
+		Velocity: vector.Two{X: 60},
+	}
+}
+
+func snap(tick int, states ...snapshot.EntityState) snapshot.Snapshot {
+	snapshot.SortStates(states)
+	return snapshot.Snapshot{Tick: tick, Entities: states}
+}
+
+func TestBufferSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		pushed   []snapshot.Snapshot
+		id       uint32
+		tick     float64
+		wantX    float64
+		wantMiss bool
+	}{
+		{
+			name:   "interpolates between neighbours",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10)), snap(12, moving(testEntity, 12))},
+			id:     testEntity, tick: 11, wantX: 11,
+		},
+		{
+			name:   "interpolates at a fractional tick",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10)), snap(11, moving(testEntity, 11))},
+			id:     testEntity, tick: 10.25, wantX: 10.25,
+		},
+		{
+			name: "reordered arrival",
+			pushed: []snapshot.Snapshot{
+				snap(14, moving(testEntity, 14)),
+				snap(10, moving(testEntity, 10)),
+				snap(12, moving(testEntity, 12)),
+			},
+			id: testEntity, tick: 13, wantX: 13,
+		},
+		{
+			name:   "gap of lost snapshots",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10)), snap(20, moving(testEntity, 20))},
+			id:     testEntity, tick: 17, wantX: 17,
+		},
+		{
+			name: "entity missing from the middle snapshot",
+			pushed: []snapshot.Snapshot{
+				snap(10, moving(testEntity, 10), moving(testOther, 10)),
+				snap(12, moving(testOther, 12)),
+				snap(14, moving(testEntity, 14), moving(testOther, 14)),
+			},
+			id: testEntity, tick: 12, wantX: 12,
+		},
+		{
+			name:   "extrapolates past the newest snapshot",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10))},
+			id:     testEntity, tick: 12, wantX: 12,
+		},
+		{
+			name:   "extrapolation is capped",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10))},
+			// 50ms at 60 ticks/s is 3 ticks
+			id: testEntity, tick: 30, wantX: 13,
+		},
+		{
+			name:   "before the oldest snapshot",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10)), snap(12, moving(testEntity, 12))},
+			id:     testEntity, tick: 5, wantX: 10,
+		},
+		{
+			name:   "unknown entity",
+			pushed: []snapshot.Snapshot{snap(10, moving(testEntity, 10))},
+			id:     testOther, tick: 10, wantMiss: true,
+		},
+		{
+			name: "duplicate tick replaces the earlier snapshot",
+			pushed: []snapshot.Snapshot{
+				snap(10, moving(testEntity, 0)),
+				snap(12, moving(testEntity, 12)),
+				snap(10, moving(testEntity, 10)),
+			},
+			id: testEntity, tick: 11, wantX: 11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuffer(DEFAULT_CAPACITY, DEFAULT_DELAY, 50*time.Millisecond, testTickRate)
+			for _, s := range tt.pushed {
+				b.Push(s)
+			}
+
+			state, ok := b.Sample(tt.id, tt.tick)
+			if tt.wantMiss {
+				if ok {
+					t.Fatalf("sampled %+v, want no state", state)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("no state sampled")
+			}
+			if math.Abs(state.Position.X-tt.wantX) > 1e-9 {
+				t.Errorf("x = %v, want %v", state.Position.X, tt.wantX)
+			}
+			if state.Position.Y != 100 {
+				t.Errorf("y = %v, want 100", state.Position.Y)
+			}
+		})
+	}
+}
+
+func TestBufferOrderAndCapacity(t *testing.T) {
+	b := NewBuffer(3, DEFAULT_DELAY, DEFAULT_EXTRAPOLATION, testTickRate)
+	for _, tick := range []int{5, 1, 4, 2, 3} {
+		b.Push(snap(tick, moving(testEntity, tick)))
+	}
+
+	if b.Len() != 3 {
+		t.Fatalf("len = %d, want 3", b.Len())
+	}
+	newest, ok := b.Newest()
+	if !ok || newest != 5 {
+		t.Fatalf("newest = %d, want 5", newest)
+	}
+	for i, want := range []int{3, 4, 5} {
+		if got := b.snapshots[i].Tick; got != want {
+			t.Errorf("snapshot %d has tick %d, want %d", i, got, want)
+		}
+	}
+
+	// A late snapshot older than everything held is still ordered, then trimmed
+	b.Push(snap(0, moving(testEntity, 0)))
+	if b.snapshots[0].Tick != 3 {
+		t.Errorf("oldest tick = %d after a late push, want 3", b.snapshots[0].Tick)
+	}
+
+	b.Reset()
+	if _, ok := b.Newest(); ok || b.Len() != 0 {
+		t.Error("buffer not empty after Reset")
+	}
+}
+
+func TestBufferDiscreteStateSnapsToOlder(t *testing.T) {
+	b := NewBuffer(DEFAULT_CAPACITY, DEFAULT_DELAY, DEFAULT_EXTRAPOLATION, testTickRate)
+	before := moving(testEntity, 10)
+	before.Direction = 1
+	after := moving(testEntity, 12)
+	after.Direction = -1
+	b.Push(snap(10, before))
+	b.Push(snap(12, after))
+
+	state, ok := b.Sample(testEntity, 11.9)
+	if !ok {
+		t.Fatal("no state sampled")
+	}
+	if state.Direction != 1 {
+		t.Errorf("direction = %d, want the older snapshot's 1", state.Direction)
+	}
+}
+
+func TestBufferSetTickRate(t *testing.T) {
+	b := NewBuffer(DEFAULT_CAPACITY, 100*time.Millisecond, 50*time.Millisecond, testTickRate)
+	b.SetTickRate(30)
+	if b.DelayTicks != 3 || b.MaxExtrapolationTicks != 2 {
+		t.Errorf("at 30 ticks/s delay = %d, extrapolation = %d ticks, want 3 and 2", b.DelayTicks, b.MaxExtrapolationTicks)
+	}
+
+	// 60px/s is 2px per tick at 30 ticks/s
+	b.Push(snap(10, moving(testEntity, 10)))
+	state, ok := b.Sample(testEntity, 11)
+	if !ok || math.Abs(state.Position.X-12) > 1e-9 {
+		t.Errorf("extrapolated x = %v, want 12", state.Position.X)
+	}
+
+	b.SetTickRate(0)
+	if b.DelayTicks != 3 {
+		t.Errorf("a zero tick rate changed the delay to %d ticks", b.DelayTicks)
+	}
+}
@@ -0,0 +1,62 @@
+package interpolation
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// System applies buffered, delayed state to remote players every tick
+type System struct {
+	Buffer *Buffer
+	// TickOffset returns how far the local clock runs ahead of the server (the
+	// prediction lead), nil when it doesn't
+	TickOffset func() int
+}
+
+type sampledEntity struct {
+	entity warehouse.Entity
+	state  snapshot.EntityState
+}
+
+func (sys System) Run(scene blueprint.Scene, dt float64) error {
+	if sys.Buffer.Len() == 0 {
+		return nil
+	}
+
+	remotePlayersQuery := warehouse.Factory.NewQuery().And(
+		input.Components.ActionBuffer,
+		components.RemoteTag,
+	)
+	cursor := scene.NewCursor(remotePlayersQuery)
+	offset := 0
+	if sys.TickOffset != nil {
+		offset = sys.TickOffset()
+	}
+	renderTick := float64(scene.CurrentTick() - offset - sys.Buffer.DelayTicks)
+
+	// Applying may add/remove OnGround, which can't happen mid iteration
+	var sampled []sampledEntity
+
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		state, ok := sys.Buffer.Sample(uint32(en.ID()), renderTick)
+		if !ok {
+			continue
+		}
+		sampled = append(sampled, sampledEntity{entity: en, state: state})
+	}
+
+	for _, s := range sampled {
+		err := snapshot.Apply(s.entity, s.state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package ldtk
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+)
+
+// rawProject is the subset of the LDtk JSON needed to list entity positions
+type rawProject struct {
+	Levels []struct {
+		Identifier     string `json:"identifier"`
+		LayerInstances []struct {
+			EntityInstances []struct {
+				Identifier string `json:"__identifier"`
+				Px         []int  `json:"px"`
+			} `json:"entityInstances"`
+		} `json:"layerInstances"`
+	} `json:"levels"`
+}
+
+var (
+	rawOnce sync.Once
+	raw     rawProject
+	rawErr  error
+)
+
+// EntityPositions returns the pixel positions of a level's entities, sorted
+// left to right. With no identifiers every entity in the level is returned
+func EntityPositions(level string, identifiers ...string) ([]vector.Two, error) {
+	rawOnce.Do(func() {
+		bytes, err := data.ReadFile("data.ldtk")
+		if err != nil {
+			rawErr = err
+			return
+		}
+		rawErr = json.Unmarshal(bytes, &raw)
+	})
+	if rawErr != nil {
+		return nil, rawErr
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range identifiers {
+		wanted[id] = true
+	}
+
+	positions := []vector.Two{}
+	for _, lvl := range raw.Levels {
+		if lvl.Identifier != level {
+			continue
+		}
+		for _, layer := range lvl.LayerInstances {
+			for _, en := range layer.EntityInstances {
+				if len(wanted) > 0 && !wanted[en.Identifier] {
+					continue
+				}
+				if len(en.Px) < 2 {
+					continue
+				}
+				positions = append(positions, vector.Two{X: float64(en.Px[0]), Y: float64(en.Px[1])})
+			}
+		}
+	}
+
+	sort.Slice(positions, func(i, j int) bool { return positions[i].X < positions[j].X })
+	return positions, nil
+}
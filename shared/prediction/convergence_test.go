@@ -0,0 +1,181 @@
+package prediction_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/prediction"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	convergenceScene = "Scene1"
+	convergenceTicks = 300
+	// Each way, in ticks
+	convergenceLatency = 3
+	// Covers the round trip, so every input reaches the server in time
+	convergenceLead = 2 * convergenceLatency
+	// How far right of the local player the other player starts
+	convergenceOtherOffset = 400.0
+)
+
+// scriptedInputs are the local player's inputs by client stamp
+func scriptedInputs(tick int) []input.Action {
+	var pressed []input.Action
+	switch {
+	case tick >= 20 && tick < 80:
+		pressed = append(pressed, actions.Right)
+	case tick >= 100 && tick < 140:
+		pressed = append(pressed, actions.Left)
+	case tick >= 180 && tick < 200:
+		pressed = append(pressed, actions.Crouch)
+	}
+	switch {
+	case tick >= 30 && tick < 38, tick >= 150 && tick < 152, tick == 160:
+		pressed = append(pressed, actions.Jump)
+	case tick == 110, tick == 240:
+		pressed = append(pressed, actions.Dash)
+	}
+	return pressed
+}
+
+// TestPredictionConvergesWithServer runs a server and a predicting client with
+// a fixed latency each way. Every prediction the client made for a snapshot's
+// tick must match the server, and the client must end on the server's state.
+// Another player falls through the scene, the client holds it as a remote
+// player that reconciling must leave where its snapshot put it
+func TestPredictionConvergesWithServer(t *testing.T) {
+	server, serverPlayer, serverOther := newSimulation(t)
+	client, clientPlayer, clientOther := newSimulation(t)
+	err := clientOther.AddComponent(components.RemoteTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	physics := scenes.LevelMovementConfig(convergenceScene)
+	predictor := prediction.NewPredictor(coresystems.NewCoreSystems(physics), client.SetTick)
+	predictor.LeadTicks = convergenceLead
+	client.Systems = predictor.CoreSystems()
+
+	type packet struct {
+		arrive int
+		tick   int
+		state  snapshot.EntityState
+		other  snapshot.EntityState
+	}
+	var (
+		inFlight     []packet
+		serverStates = map[int]snapshot.EntityState{}
+		checked      int
+	)
+
+	// The client clock runs lead ticks ahead of the newest snapshot it holds
+	client.SetTick(convergenceLead - convergenceLatency + 1)
+
+	for now := 0; now < convergenceTicks; now++ {
+		for len(inFlight) > 0 && inFlight[0].arrive <= now {
+			snap := inFlight[0]
+			inFlight = inFlight[1:]
+
+			if predicted, ok := predictor.Predicted(snap.tick); ok {
+				checked++
+				if !sameState(predicted, snap.state) {
+					t.Errorf("tick %d: predicted %+v\n server %+v", snap.tick, predicted, snap.state)
+				}
+			}
+			err := snapshot.Apply(clientOther, snap.other)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = predictor.Reconcile(client, clientPlayer, snap.state, snap.tick)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other := client.State(clientOther); !sameState(other, snap.other) {
+				t.Errorf("tick %d: reconciling moved the remote player to %+v\nsnapshot has %+v", snap.tick, other, snap.other)
+			}
+			client.SetTick(snap.tick + predictor.LeadTicks + 1)
+		}
+
+		// Inputs are stamped with the client tick and reach the server before
+		// it simulates that tick
+		stamp := client.CurrentTick()
+		pressed := scriptedInputs(stamp)
+		client.Press(clientPlayer, stamp, pressed...)
+		server.Press(serverPlayer, stamp, pressed...)
+
+		err := client.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = server.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		state := server.State(serverPlayer)
+		serverStates[now] = state
+		inFlight = append(inFlight, packet{
+			arrive: now + convergenceLatency,
+			tick:   now,
+			state:  state,
+			other:  server.State(serverOther),
+		})
+	}
+
+	if checked == 0 {
+		t.Fatal("no prediction was checked against the server")
+	}
+
+	// Let the server catch up with the client's newest predicted tick
+	last := client.CurrentTick() - 1
+	for server.CurrentTick() <= last {
+		err := server.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverStates[server.CurrentTick()-1] = server.State(serverPlayer)
+	}
+	if !sameState(client.State(clientPlayer), serverStates[last]) {
+		t.Errorf("tick %d: client ended on %+v\nserver has %+v", last, client.State(clientPlayer), serverStates[last])
+	}
+}
+
+// newSimulation builds the scene with the local player at its start and another
+// player dropping in further right
+func newSimulation(t *testing.T) (*headless.Simulation, warehouse.Entity, warehouse.Entity) {
+	t.Helper()
+
+	scene, ok := scenes.ByName(convergenceScene)
+	if !ok {
+		t.Fatalf("unknown scene %s", convergenceScene)
+	}
+	physics := scenes.LevelMovementConfig(convergenceScene)
+	sim, err := headless.New(scene.Name, scene.Width, scene.Height, scene.Plan, coresystems.NewCoreSystems(physics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := sim.SpawnPlayerAtStart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := sim.State(player).Position
+	other, err := sim.SpawnPlayer(start.X+convergenceOtherOffset, start.Y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim, player, other
+}
+
+// sameState compares states of the same player in different storages
+func sameState(a, b snapshot.EntityState) bool {
+	a.ID, b.ID = 0, 0
+	return reflect.DeepEqual(a, b)
+}
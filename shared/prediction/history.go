@@ -0,0 +1,106 @@
+package prediction
+
+import (
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// DEFAULT_HISTORY_TICKS is how many ticks of local input are remembered for replay
+const DEFAULT_HISTORY_TICKS = 128
+
+// InputHistory is a ring buffer of the local player's stamped inputs, keyed by tick
+type InputHistory struct {
+	entries []historyEntry
+}
+
+type historyEntry struct {
+	tick    int
+	actions []input.StampedAction
+	// sent is when the inputs were recorded, zero when there were none or
+	// once the server acknowledged them
+	sent time.Time
+}
+
+func NewInputHistory(capacity int) *InputHistory {
+	entries := make([]historyEntry, capacity)
+	for i := range entries {
+		entries[i].tick = -1
+	}
+	return &InputHistory{entries: entries}
+}
+
+// Set stores the inputs for a tick, replacing anything recorded for it before
+func (h *InputHistory) Set(tick int, actions []input.StampedAction) {
+	entry := &h.entries[ringIndex(tick, len(h.entries))]
+	entry.tick = tick
+	entry.actions = append(entry.actions[:0], actions...)
+	entry.sent = time.Time{}
+	if len(actions) > 0 {
+		entry.sent = time.Now()
+	}
+}
+
+// Acknowledge returns when the inputs of a tick were recorded, the first time
+// the server acknowledges them only
+func (h *InputHistory) Acknowledge(tick int) (time.Time, bool) {
+	entry := &h.entries[ringIndex(tick, len(h.entries))]
+	if entry.tick != tick || entry.sent.IsZero() {
+		return time.Time{}, false
+	}
+	sent := entry.sent
+	entry.sent = time.Time{}
+	return sent, true
+}
+
+// At returns the inputs recorded for a tick, or nil if it was never recorded or
+// has since been overwritten
+func (h *InputHistory) At(tick int) []input.StampedAction {
+	entry := h.entries[ringIndex(tick, len(h.entries))]
+	if entry.tick != tick {
+		return nil
+	}
+	return entry.actions
+}
+
+// StateHistory is a ring buffer of the local player's predicted state at the end
+// of each simulated tick, used to check predictions against the server
+type StateHistory struct {
+	entries []stateEntry
+}
+
+type stateEntry struct {
+	tick  int
+	state snapshot.EntityState
+}
+
+func NewStateHistory(capacity int) *StateHistory {
+	entries := make([]stateEntry, capacity)
+	for i := range entries {
+		entries[i].tick = -1
+	}
+	return &StateHistory{entries: entries}
+}
+
+// Set stores the predicted state for a tick
+func (h *StateHistory) Set(tick int, state snapshot.EntityState) {
+	h.entries[ringIndex(tick, len(h.entries))] = stateEntry{tick: tick, state: state}
+}
+
+// At returns the state predicted for a tick, if it is still held
+func (h *StateHistory) At(tick int) (snapshot.EntityState, bool) {
+	entry := h.entries[ringIndex(tick, len(h.entries))]
+	if entry.tick != tick {
+		return snapshot.EntityState{}, false
+	}
+	return entry.state, true
+}
+
+func ringIndex(tick, size int) int {
+	i := tick % size
+	if i < 0 {
+		i += size
+	}
+	return i
+}
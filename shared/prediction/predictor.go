@@ -0,0 +1,244 @@
+// Package prediction implements client-side prediction for the local player.
+//
+// The networked client runs the core systems for its own entity ahead of the
+// server. Every snapshot rewinds that entity to the authoritative state and
+// replays the inputs the server has not simulated yet.
+package prediction
+
+import (
+	"math"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	// DEFAULT_TICK_RATE is assumed until a snapshot names the server's tick rate
+	DEFAULT_TICK_RATE = 60
+	// INITIAL_LEAD_TICKS is used until the first round trip is measured
+	INITIAL_LEAD_TICKS = 6
+	MIN_LEAD_TICKS     = 1
+	MAX_LEAD_TICKS     = DEFAULT_HISTORY_TICKS / 2
+	// LEAD_MARGIN_TICKS covers jitter on top of the measured round trip
+	LEAD_MARGIN_TICKS = 2
+	// RTT_SMOOTHING is the weight of each new round trip sample
+	RTT_SMOOTHING = 0.125
+)
+
+// Predictor records local inputs and reconciles the local player with snapshots
+//
+// Inputs are stamped with the client tick, which runs LeadTicks ahead of the
+// server. A snapshot for tick S therefore contains every input stamped up to S,
+// and the inputs stamped after S are the unacknowledged ones to replay.
+//
+// An input stamped at client tick C reaches the server half a round trip
+// later, while the client clock itself trails the server by the other half. So
+// the lead has to cover a full round trip for inputs to arrive before the
+// server simulates their tick. The round trip is measured from the input acks
+// in snapshots and the lead follows it one tick per snapshot
+type Predictor struct {
+	History   *InputHistory
+	States    *StateHistory
+	Systems   []blueprint.CoreSystem
+	LeadTicks int
+	// TickRate is the server's ticks per second, predicted ticks use 1/TickRate as dt
+	TickRate int
+	// SetTick moves the simulation clock (coldbrew.ForceSetTick on the client)
+	SetTick func(int)
+
+	rtt time.Duration
+}
+
+func NewPredictor(systems []blueprint.CoreSystem, setTick func(int)) *Predictor {
+	return &Predictor{
+		History:   NewInputHistory(DEFAULT_HISTORY_TICKS),
+		States:    NewStateHistory(DEFAULT_HISTORY_TICKS),
+		Systems:   systems,
+		LeadTicks: INITIAL_LEAD_TICKS,
+		TickRate:  DEFAULT_TICK_RATE,
+		SetTick:   setTick,
+	}
+}
+
+// Lead returns how many ticks the client currently runs ahead of the server
+func (p *Predictor) Lead() int {
+	return p.LeadTicks
+}
+
+// DT is the fixed step of a predicted tick
+func (p *Predictor) DT() float64 {
+	return 1 / float64(p.TickRate)
+}
+
+// SetTickRate adopts the tick rate reported by the server, zero is ignored
+func (p *Predictor) SetTickRate(rate int) {
+	if rate > 0 {
+		p.TickRate = rate
+	}
+}
+
+// RTT returns the smoothed round trip time, zero until the first ack
+func (p *Predictor) RTT() time.Duration {
+	return p.rtt
+}
+
+// Acknowledge times the round trip of the inputs stamped at tick, which the
+// server reported receiving at now, and moves the lead one tick towards the
+// round trip
+func (p *Predictor) Acknowledge(tick int, now time.Time) {
+	sent, ok := p.History.Acknowledge(tick)
+	if !ok {
+		return
+	}
+	sample := now.Sub(sent)
+	if p.rtt == 0 {
+		p.rtt = sample
+	} else {
+		p.rtt += time.Duration(RTT_SMOOTHING * float64(sample-p.rtt))
+	}
+
+	target := TargetLead(p.rtt, p.TickRate)
+	switch {
+	case target > p.LeadTicks:
+		p.LeadTicks++
+	case target < p.LeadTicks:
+		p.LeadTicks--
+	}
+}
+
+// TargetLead is the lead (in ticks) that covers a round trip at tickRate
+func TargetLead(rtt time.Duration, tickRate int) int {
+	ticks := int(math.Ceil(rtt.Seconds()*float64(tickRate))) + LEAD_MARGIN_TICKS
+	return min(max(ticks, MIN_LEAD_TICKS), MAX_LEAD_TICKS)
+}
+
+// CoreSystems returns the systems the client registers for its scenes: an input
+// recorder, the predicted systems and finally a recorder for the predicted state
+func (p *Predictor) CoreSystems() []blueprint.CoreSystem {
+	systems := []blueprint.CoreSystem{inputRecordSystem{history: p.History}}
+	systems = append(systems, p.Systems...)
+	return append(systems, stateRecordSystem{history: p.States})
+}
+
+// Predicted returns the state the local player was predicted to have at tick
+func (p *Predictor) Predicted(tick int) (snapshot.EntityState, bool) {
+	return p.States.At(tick)
+}
+
+// Reconcile rewinds the local player to the authoritative state at tick and
+// replays the recorded inputs up to tick+LeadTicks
+//
+// Only the local player is replayed. The systems run over the whole scene, so
+// remote players are put back where their snapshots left them after every
+// replayed tick instead of being integrated again
+func (p *Predictor) Reconcile(scene blueprint.Scene, en warehouse.Entity, auth snapshot.EntityState, tick int) error {
+	err := snapshot.Apply(en, auth)
+	if err != nil {
+		return err
+	}
+
+	remotes, err := captureRemotes(scene)
+	if err != nil {
+		return err
+	}
+
+	// Anything left in the buffer is either stale or about to be replayed
+	buffer := input.Components.ActionBuffer.GetFromEntity(en)
+	buffer.Values = buffer.Values[:0]
+
+	for t := tick + 1; t <= tick+p.LeadTicks; t++ {
+		p.SetTick(t)
+
+		buffer := input.Components.ActionBuffer.GetFromEntity(en)
+		buffer.Add(p.History.At(t)...)
+
+		for _, sys := range p.Systems {
+			err := sys.Run(scene, p.DT())
+			if err != nil {
+				return err
+			}
+		}
+		for remote, state := range remotes {
+			err := snapshot.Apply(remote, state)
+			if err != nil {
+				return err
+			}
+		}
+		p.States.Set(t, snapshot.Capture(en))
+	}
+	return nil
+}
+
+var remotePlayersQuery = warehouse.Factory.NewQuery().And(
+	input.Components.ActionBuffer,
+	components.RemoteTag,
+)
+
+// captureRemotes reads the state of every remote player in the scene
+func captureRemotes(scene blueprint.Scene) (map[warehouse.Entity]snapshot.EntityState, error) {
+	remotes := map[warehouse.Entity]snapshot.EntityState{}
+	cursor := scene.NewCursor(remotePlayersQuery)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return nil, err
+		}
+		remotes[en] = snapshot.Capture(en)
+	}
+	return remotes, nil
+}
+
+// inputRecordSystem copies the inputs stamped this tick from the local player's
+// buffer into the history, before the movement system consumes them
+type inputRecordSystem struct {
+	history *InputHistory
+}
+
+func (s inputRecordSystem) Run(scene blueprint.Scene, dt float64) error {
+	localPlayerQuery := warehouse.Factory.NewQuery().And(
+		input.Components.ActionBuffer,
+		warehouse.Factory.NewQuery().Not(components.RemoteTag),
+	)
+	cursor := scene.NewCursor(localPlayerQuery)
+	currentTick := scene.CurrentTick()
+
+	for range cursor.Next() {
+		buffer := input.Components.ActionBuffer.GetFromCursor(cursor)
+
+		stamped := []input.StampedAction{}
+		for _, action := range buffer.Values {
+			if action.Tick == currentTick {
+				stamped = append(stamped, action)
+			}
+		}
+		s.history.Set(currentTick, stamped)
+	}
+	return nil
+}
+
+// stateRecordSystem stores the local player's state once the predicted systems
+// have run for the tick
+type stateRecordSystem struct {
+	history *StateHistory
+}
+
+func (s stateRecordSystem) Run(scene blueprint.Scene, dt float64) error {
+	localPlayerQuery := warehouse.Factory.NewQuery().And(
+		input.Components.ActionBuffer,
+		warehouse.Factory.NewQuery().Not(components.RemoteTag),
+	)
+	cursor := scene.NewCursor(localPlayerQuery)
+
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		s.history.Set(scene.CurrentTick(), snapshot.Capture(en))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"fmt"
+
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// Divergence is a recorded checksum the replay did not reproduce
+type Divergence struct {
+	Tick     int
+	Expected uint64
+	Actual   uint64
+	// Entities lists the recorded ids whose state was hashed
+	Entities []uint32
+}
+
+// Result summarises a replay of a single scene
+type Result struct {
+	Scene       string
+	FirstTick   int
+	LastTick    int
+	Checked     int
+	Divergences []Divergence
+}
+
+// Play re-runs the recorded inputs of one scene through its default core
+// systems, stepping at the recorded tick rate, and compares the state against
+// every recorded checksum
+func Play(records []Record, scene string) (Result, error) {
+	result := Result{Scene: scene}
+
+	byTick := map[int][]Record{}
+	first := true
+	for _, rec := range records {
+		if rec.Scene != scene {
+			continue
+		}
+		byTick[rec.Tick] = append(byTick[rec.Tick], rec)
+		if first || rec.Tick < result.FirstTick {
+			result.FirstTick = rec.Tick
+		}
+		if first || rec.Tick > result.LastTick {
+			result.LastTick = rec.Tick
+		}
+		first = false
+	}
+	if first {
+		return result, fmt.Errorf("replay: no records for scene %s", scene)
+	}
+
+	sim, err := headless.FromScene(scene)
+	if err != nil {
+		return result, err
+	}
+	sim.SetTick(result.FirstTick)
+	sim.SetTickRate(TickRate(records))
+
+	// Recorded entity ids mapped to the replay's entities
+	players := map[uint32]warehouse.Entity{}
+
+	for tick := result.FirstTick; tick <= result.LastTick; tick++ {
+		recs := byTick[tick]
+
+		// Membership first, so hashes and inputs see the same players the
+		// recorder did
+		for _, rec := range recs {
+			switch rec.Kind {
+			case KindJoin:
+				en, err := join(sim, rec)
+				if err != nil {
+					return result, fmt.Errorf("replay: tick %d: %w", tick, err)
+				}
+				players[rec.Entity] = en
+			case KindLeave:
+				en, ok := players[rec.Entity]
+				if !ok {
+					continue
+				}
+				delete(players, rec.Entity)
+				err := sim.Storage().DestroyEntities(en)
+				if err != nil {
+					return result, err
+				}
+			}
+		}
+
+		for _, rec := range recs {
+			switch rec.Kind {
+			case KindHash:
+				result.Checked++
+				snap := capture(players, tick)
+				if actual := snap.Checksum(); actual != rec.Hash {
+					div := Divergence{Tick: tick, Expected: rec.Hash, Actual: actual}
+					for _, state := range snap.Entities {
+						div.Entities = append(div.Entities, state.ID)
+					}
+					result.Divergences = append(result.Divergences, div)
+				}
+			case KindInput:
+				en, ok := players[rec.Entity]
+				if !ok {
+					continue
+				}
+				sim.Deliver(en, rec.Actions...)
+			}
+		}
+
+		err := sim.Step()
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// join spawns a recorded player and restores its seed state
+func join(sim *headless.Simulation, rec Record) (warehouse.Entity, error) {
+	if rec.State == nil {
+		return nil, fmt.Errorf("join for entity %d has no state", rec.Entity)
+	}
+	en, err := sim.SpawnPlayer(rec.State.Position.X, rec.State.Position.Y)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Movement != nil {
+		*components.MovementConfigComponent.GetFromEntity(en) = *rec.Movement
+	}
+	return en, snapshot.Apply(en, *rec.State)
+}
+
+// capture hashes players under their recorded ids so checksums line up
+func capture(players map[uint32]warehouse.Entity, tick int) snapshot.Snapshot {
+	snap := snapshot.Snapshot{Tick: tick}
+	for id, en := range players {
+		state := snapshot.Capture(en)
+		state.ID = id
+		snap.Entities = append(snap.Entities, state)
+	}
+	snapshot.SortStates(snap.Entities)
+	return snap
+}
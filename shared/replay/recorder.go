@@ -0,0 +1,170 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// Recorder writes a recording, it is shared by every scene on the server
+type Recorder struct {
+	HashInterval int
+
+	mu          sync.Mutex
+	w           *bufio.Writer
+	enc         *json.Encoder
+	closer      io.Closer
+	connections map[uint32]string
+	present     map[string]map[uint32]bool
+	err         error
+}
+
+// NewRecorder writes a recording of a server running at tickRate to w
+func NewRecorder(w io.Writer, tickRate int) *Recorder {
+	bw := bufio.NewWriter(w)
+	r := &Recorder{
+		HashInterval: DEFAULT_HASH_INTERVAL,
+		w:            bw,
+		enc:          json.NewEncoder(bw),
+		connections:  map[uint32]string{},
+		present:      map[string]map[uint32]bool{},
+	}
+	r.write(Record{Kind: KindHeader, Version: FORMAT_VERSION, HashInterval: r.HashInterval, TickRate: tickRate})
+	return r
+}
+
+// Create writes a recording of a server running at tickRate to a new file at path
+func Create(path string, tickRate int) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(f, tickRate)
+	r.closer = f
+	return r, nil
+}
+
+// Associate remembers which connection controls an entity
+func (r *Recorder) Associate(conn string, entity uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connections[entity] = conn
+}
+
+// System returns the core system that records a scene, it must run before
+// anything that consumes actions
+func (r *Recorder) System(scene string) blueprint.CoreSystem {
+	return recordSystem{recorder: r, scene: scene}
+}
+
+// Close flushes the recording and reports the first write error, if any
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.w.Flush()
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// write must be called with mu held (or before the recorder is shared)
+func (r *Recorder) write(rec Record) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(rec)
+}
+
+type recordSystem struct {
+	recorder *Recorder
+	scene    string
+}
+
+func (s recordSystem) Run(scene blueprint.Scene, dt float64) error {
+	r := s.recorder
+	tick := scene.CurrentTick()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		// Recording is best effort, the game keeps running
+		return nil
+	}
+
+	present := r.present[s.scene]
+	if present == nil {
+		present = map[uint32]bool{}
+		r.present[s.scene] = present
+	}
+
+	snap := snapshot.Snapshot{Tick: tick}
+	current := map[uint32]bool{}
+	cursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
+
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		state := snapshot.Capture(en)
+		id := state.ID
+		current[id] = true
+		snap.Entities = append(snap.Entities, state)
+
+		if !present[id] {
+			join := Record{
+				Kind:       KindJoin,
+				Tick:       tick,
+				Scene:      s.scene,
+				Connection: r.connections[id],
+				Entity:     id,
+				State:      &state,
+			}
+			if ok, movement := components.MovementConfigComponent.GetFromCursorSafe(cursor); ok {
+				cfg := *movement
+				join.Movement = &cfg
+			}
+			r.write(join)
+		}
+
+		buffer := input.Components.ActionBuffer.GetFromCursor(cursor)
+		if len(buffer.Values) > 0 {
+			r.write(Record{
+				Kind:    KindInput,
+				Tick:    tick,
+				Scene:   s.scene,
+				Entity:  id,
+				Actions: slices.Clone(buffer.Values),
+			})
+		}
+	}
+
+	for id := range present {
+		if !current[id] {
+			r.write(Record{Kind: KindLeave, Tick: tick, Scene: s.scene, Entity: id})
+		}
+	}
+	r.present[s.scene] = current
+
+	if r.HashInterval > 0 && tick%r.HashInterval == 0 && len(snap.Entities) > 0 {
+		snapshot.SortStates(snap.Entities)
+		r.write(Record{Kind: KindHash, Tick: tick, Scene: s.scene, Hash: snap.Checksum()})
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package replay records the inputs the server applies to every player and
+// re-runs them headlessly to reproduce movement bugs.
+//
+// A recording is a stream of JSON records, one per line. Players join with
+// their full state (the seed), their buffered actions are stored with the tick
+// they were applied on, and every HashInterval ticks the recorder stores a
+// checksum of all player states so a replay can tell where it diverged.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const (
+	FORMAT_VERSION        = 1
+	DEFAULT_HASH_INTERVAL = 30 // Ticks between recorded state checksums
+)
+
+type Kind string
+
+const (
+	KindHeader Kind = "header"
+	KindJoin   Kind = "join"
+	KindLeave  Kind = "leave"
+	KindInput  Kind = "input"
+	KindHash   Kind = "hash"
+)
+
+var ErrUnsupportedVersion = errors.New("replay: unsupported recording version")
+
+// Record is a single line of a recording
+//
+// Records for a tick describe the scene at the start of that tick, before the
+// core systems ran
+type Record struct {
+	Kind  Kind   `json:"kind"`
+	Tick  int    `json:"tick"`
+	Scene string `json:"scene,omitempty"`
+
+	// Header
+	Version      int `json:"version,omitempty"`
+	HashInterval int `json:"hashInterval,omitempty"`
+	TickRate     int `json:"tickRate,omitempty"`
+
+	// Join, leave and input
+	Connection string                     `json:"conn,omitempty"`
+	Entity     uint32                     `json:"entity,omitempty"`
+	State      *snapshot.EntityState      `json:"state,omitempty"`
+	Movement   *components.MovementConfig `json:"movement,omitempty"`
+	Actions    []input.StampedAction      `json:"actions,omitempty"`
+
+	// Hash
+	Hash uint64 `json:"hash,omitempty"`
+}
+
+// Load reads every record of a recording
+func Load(r io.Reader) ([]Record, error) {
+	records := []Record{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		err := json.Unmarshal(scanner.Bytes(), &rec)
+		if err != nil {
+			return nil, fmt.Errorf("replay: line %d: %w", line, err)
+		}
+		if rec.Kind == KindHeader && rec.Version != FORMAT_VERSION {
+			return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, rec.Version)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// TickRate returns the server tick rate stored in a recording's header, zero
+// when the header does not have one
+func TickRate(records []Record) int {
+	for _, rec := range records {
+		if rec.Kind == KindHeader {
+			return rec.TickRate
+		}
+	}
+	return 0
+}
+
+// Scenes returns the scenes that appear in a recording, in order of first appearance
+func Scenes(records []Record) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, rec := range records {
+		if rec.Scene == "" || seen[rec.Scene] {
+			continue
+		}
+		seen[rec.Scene] = true
+		names = append(names, rec.Scene)
+	}
+	return names
+}
@@ -25,6 +25,10 @@ var PlayerComposition = []warehouse.Component{
 	motion.Components.Dynamics,
 	client.Components.SoundBundle,
 	components.JumpStateComponent,
+	components.DashStateComponent,
+	components.CrouchStateComponent,
+	components.MovementConfigComponent,
+	components.InputAckComponent,
 }
 
 var BlockTerrainComposition = []warehouse.Component{
@@ -43,6 +47,12 @@ var PlatformComposition = []warehouse.Component{
 	motion.Components.Dynamics,
 }
 
+// MovingPlatformComposition is a one way platform that follows a path
+var MovingPlatformComposition = append([]warehouse.Component{
+	components.MovingPlatformComponent,
+	components.DynamicTerrainTag,
+}, PlatformComposition...)
+
 var MusicComposition = []warehouse.Component{
 	client.Components.SoundBundle,
 	components.MusicTag,
@@ -17,7 +17,15 @@ const PLAYER_SPRITE_SHEET_PATH = "images/characters/box_man_sheet.png"
 
 var DEFAULT_PLAYER_SPR_BUNDLE = client.NewSpriteBundle().
 	AddSprite(PLAYER_SPRITE_SHEET_PATH, true).
-	WithAnimations(animations.IdleAnimation, animations.RunAnimation, animations.FallAnimation, animations.JumpAnimation).
+	WithAnimations(
+		animations.IdleAnimation,
+		animations.RunAnimation,
+		animations.FallAnimation,
+		animations.JumpAnimation,
+		animations.WallSlideAnimation,
+		animations.DashAnimation,
+		animations.CrouchAnimation,
+	).
 	SetActiveAnimation(animations.IdleAnimation).
 	WithOffset(vector.Two{X: -72, Y: -59}).
 	WithPriority(20)
@@ -27,14 +35,12 @@ var DEFAULT_PLAYER_SND_BUNDLE = client.NewSoundBundle().
 	AddSoundFromConfig(sounds.Jump).
 	AddSoundFromConfig(sounds.Land)
 
-// NewPlayer creates a player spawn for the scene
-func NewPlayerSpawn(x, y float64, sto warehouse.Storage) (warehouse.Entity, error) {
+// NewPlayerSpawn creates a player spawn point for the scene
+func NewPlayerSpawn(sto warehouse.Storage, spawn components.PlayerSpawn) (warehouse.Entity, error) {
 	spawnArchetype, err := sto.NewOrExistingArchetype(
 		components.PlayerSpawnComponent,
 	)
-	entities, err := spawnArchetype.GenerateAndReturnEntity(1,
-		components.PlayerSpawn{X: x, Y: y},
-	)
+	entities, err := spawnArchetype.GenerateAndReturnEntity(1, spawn)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +49,12 @@ func NewPlayerSpawn(x, y float64, sto warehouse.Storage) (warehouse.Entity, erro
 
 // NewPlayer creates a player entity for the scene
 func NewPlayer(x, y float64, sto warehouse.Storage) (warehouse.Entity, error) {
+	return NewLocalPlayer(x, y, sto, 0)
+}
+
+// NewLocalPlayer creates a player driven by the given receiver and followed by
+// the camera with the same index, for local split screen
+func NewLocalPlayer(x, y float64, sto warehouse.Storage, index int) (warehouse.Entity, error) {
 	playerArchetype, err := sto.NewOrExistingArchetype(
 		PlayerComposition...,
 	)
@@ -51,10 +63,11 @@ func NewPlayer(x, y float64, sto warehouse.Storage) (warehouse.Entity, error) {
 		spatial.NewRectangle(18, 58),
 		motion.NewDynamics(10),
 		spatial.NewDirectionRight(),
-		input.ActionBuffer{ReceiverIndex: 0},
-		client.CameraIndex(0),
+		input.ActionBuffer{ReceiverIndex: index},
+		client.CameraIndex(index),
 		DEFAULT_PLAYER_SND_BUNDLE,
 		DEFAULT_PLAYER_SPR_BUNDLE,
+		SceneMovementConfig(sto),
 	)
 	if err != nil {
 		return nil, err
@@ -79,6 +92,27 @@ func NewPlatformRotated(sto warehouse.Storage, x, y, rotation float64) error {
 	)
 }
 
+// NewMovingPlatform creates a one way platform that moves along its path
+func NewMovingPlatform(sto warehouse.Storage, platform components.MovingPlatform) error {
+	if len(platform.Path) == 0 {
+		return nil
+	}
+	platformArche, err := sto.NewOrExistingArchetype(MovingPlatformComposition...)
+	if err != nil {
+		return err
+	}
+	start := platform.Path[0]
+	return platformArche.Generate(1,
+		platform,
+		spatial.NewPosition(start.X, start.Y),
+		spatial.Rotation(0),
+		spatial.NewTriangularPlatform(144, 16),
+		client.NewSpriteBundle().
+			AddSprite("images/terrain/platform.png", true).
+			WithOffset(vector.Two{X: -72, Y: -8}),
+	)
+}
+
 // NewRamp creates a ramp (sloped block hexagon)
 func NewRamp(sto warehouse.Storage, x, y float64) error {
 	// Add a sprite
@@ -0,0 +1,99 @@
+package scenes
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/table"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/ldtk"
+)
+
+var transferQuery = warehouse.Factory.NewQuery().And(components.PlayerSceneTransferComponent)
+
+// TestLevelsLoad builds every LDtk level through its catalog scene and checks
+// each SceneTransfer lands inside a scene that exists
+func TestLevelsLoad(t *testing.T) {
+	if len(ldtk.DATA.Levels) == 0 {
+		t.Fatal("the LDtk project has no levels")
+	}
+
+	for _, level := range ldtk.DATA.Levels {
+		t.Run(level.Identifier, func(t *testing.T) {
+			scene, ok := ByName(level.Identifier)
+			if !ok {
+				t.Fatalf("level %q has no scene in the catalog", level.Identifier)
+			}
+
+			sto := warehouse.Factory.NewStorage(table.Factory.NewSchema())
+			err := scene.Plan(scene.Width, scene.Height, sto)
+			if err != nil {
+				t.Fatalf("building the scene: %v", err)
+			}
+
+			cursor := warehouse.Factory.NewCursor(transferQuery, sto)
+			for range cursor.Next() {
+				transfer := components.PlayerSceneTransferComponent.GetFromCursor(cursor)
+
+				target, ok := ByName(transfer.Dest)
+				if !ok {
+					t.Errorf("transfer targets unknown scene %q", transfer.Dest)
+					continue
+				}
+				if transfer.X < 0 || transfer.X > float64(target.Width) ||
+					transfer.Y < 0 || transfer.Y > float64(target.Height) {
+					t.Errorf("transfer to %s lands outside it at (%v, %v), the scene is %dx%d",
+						target.Name, transfer.X, transfer.Y, target.Width, target.Height)
+				}
+			}
+		})
+	}
+}
+
+// TestCatalogScenesHaveLevels makes sure no catalog scene points at a missing level
+func TestCatalogScenesHaveLevels(t *testing.T) {
+	levels := map[string]bool{}
+	for _, level := range ldtk.DATA.Levels {
+		levels[level.Identifier] = true
+	}
+	for _, scene := range Catalog {
+		if !levels[scene.Name] {
+			t.Errorf("scene %q has no LDtk level", scene.Name)
+		}
+	}
+}
+
+// TestLevelFieldsDefined checks the LDtk project defines every level field
+// LevelMovementConfig reads, so they can be edited per level
+func TestLevelFieldsDefined(t *testing.T) {
+	data, err := os.ReadFile("../ldtk/data.ldtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var project struct {
+		Defs struct {
+			LevelFields []struct {
+				Identifier string `json:"identifier"`
+			} `json:"levelFields"`
+		} `json:"defs"`
+	}
+	err = json.Unmarshal(data, &project)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defined := map[string]bool{}
+	for _, field := range project.Defs.LevelFields {
+		defined[field.Identifier] = true
+	}
+	for _, name := range []string{
+		"gravity", "friction", "damp", "speedX", "jumpForce", "snapForce",
+		"coyoteTime", "inputBufferTicks", "airJumps", "playerCollision", "pushMass",
+	} {
+		if !defined[name] {
+			t.Errorf("level field %q is not defined", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/ldtk"
+)
+
+var sceneMovementConfigQuery = warehouse.Factory.NewQuery().And(components.SceneMovementConfigComponent)
+
+// LevelMovementConfig reads the physics custom fields of an LDtk level on top of
+// the defaults. Fields that are missing or null keep their default value
+//
+// Supported level fields: gravity, friction, damp, speedX, jumpForce,
+// snapForce, coyoteTime, inputBufferTicks, airJumps, playerCollision and pushMass
+func LevelMovementConfig(level string) components.MovementConfig {
+	cfg := coresystems.DefaultPhysics()
+
+	for _, lvl := range ldtk.DATA.Levels {
+		if lvl.Identifier != level {
+			continue
+		}
+		for _, field := range lvl.FieldInstances {
+			switch field.Identifier {
+			case "gravity":
+				setFloatField(&cfg.Gravity, field.Value)
+			case "friction":
+				setFloatField(&cfg.Friction, field.Value)
+			case "damp":
+				setFloatField(&cfg.Damp, field.Value)
+			case "speedX":
+				setFloatField(&cfg.SpeedX, field.Value)
+			case "jumpForce":
+				setFloatField(&cfg.JumpForce, field.Value)
+			case "snapForce":
+				setFloatField(&cfg.SnapForce, field.Value)
+			case "coyoteTime":
+				setIntField(&cfg.CoyoteTime, field.Value)
+			case "inputBufferTicks":
+				setIntField(&cfg.InputBufferTicks, field.Value)
+			case "airJumps":
+				setIntField(&cfg.AirJumps, field.Value)
+			case "playerCollision":
+				setBoolField(&cfg.PlayerCollision, field.Value)
+			case "pushMass":
+				setFloatField(&cfg.PushMass, field.Value)
+			}
+		}
+	}
+	return cfg
+}
+
+// SetSceneMovementConfig stores the scene wide movement config, creating the
+// settings entity the first time
+func SetSceneMovementConfig(sto warehouse.Storage, cfg components.MovementConfig) error {
+	cursor := warehouse.Factory.NewCursor(sceneMovementConfigQuery, sto)
+	for range cursor.Next() {
+		*components.SceneMovementConfigComponent.GetFromCursor(cursor) = components.SceneMovementConfig(cfg)
+		cursor.Reset()
+		return nil
+	}
+
+	settingsArche, err := sto.NewOrExistingArchetype(components.SceneMovementConfigComponent)
+	if err != nil {
+		return err
+	}
+	return settingsArche.Generate(1, components.SceneMovementConfig(cfg))
+}
+
+// SceneMovementConfig returns the movement config new players in sto start with
+func SceneMovementConfig(sto warehouse.Storage) components.MovementConfig {
+	cursor := warehouse.Factory.NewCursor(sceneMovementConfigQuery, sto)
+	for range cursor.Next() {
+		cfg := components.MovementConfig(*components.SceneMovementConfigComponent.GetFromCursor(cursor))
+		cursor.Reset()
+		return cfg
+	}
+	return coresystems.DefaultPhysics()
+}
+
+// LDtk stores every number as a JSON number (float64)
+func setFloatField(dst *float64, value any) {
+	if v, ok := value.(float64); ok {
+		*dst = v
+	}
+}
+
+func setIntField(dst *int, value any) {
+	if v, ok := value.(float64); ok {
+		*dst = int(v)
+	}
+}
+
+func setBoolField(dst *bool, value any) {
+	if v, ok := value.(bool); ok {
+		*dst = v
+	}
+}
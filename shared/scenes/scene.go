@@ -1,15 +1,39 @@
 package scenes
 
 import (
+	"fmt"
+
 	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
 	"github.com/TheBitDrifter/bappa/warehouse"
 
 	"github.com/TheBitDrifter/bappa/blueprint/client"
 	"github.com/TheBitDrifter/bappa/blueprint/ldtk"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
 )
 
 var entityRegistry = ldtk.NewLDtkEntityRegistry()
 
+// LDTK_GRID_SIZE is the cell size of the LDtk levels, in pixels
+const LDTK_GRID_SIZE = 16
+
+// Catalog lists every scene, in registration order
+// The first scene is the one clients start in
+var Catalog = []Scene{
+	SceneOne,
+	SceneTwo,
+}
+
+// ByName finds a scene in the catalog
+func ByName(name string) (Scene, bool) {
+	for _, scene := range Catalog {
+		if scene.Name == name {
+			return scene, true
+		}
+	}
+	return Scene{}, false
+}
+
 // Local scene object makes it easier to organize scene plans
 type Scene struct {
 	Name          string
@@ -21,10 +45,14 @@ type Scene struct {
 
 // Registering custom LDTK entities
 func init() {
-	// Player start position handler
+	// Player start positions, a level can have any number of them
 	entityRegistry.Register("PlayerStart", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
-		// Create the player at the position defined in LDtk
-		_, err := NewPlayerSpawn(float64(entity.Position[0]), float64(entity.Position[1]), sto)
+		_, err := NewPlayerSpawn(sto, components.PlayerSpawn{
+			X:      float64(entity.Position[0]),
+			Y:      float64(entity.Position[1]),
+			Team:   entity.StringFieldOr("team", ""),
+			Weight: entity.FloatFieldOr("weight", 1),
+		})
 		if err != nil {
 			return err
 		}
@@ -49,4 +77,64 @@ func init() {
 			-0.25,
 		)
 	})
+
+	// MovingPlatform travels from its position through the points of its path
+	entityRegistry.Register("MovingPlatform", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		platform := components.MovingPlatform{
+			Path:   []vector.Two{{X: float64(entity.Position[0]), Y: float64(entity.Position[1])}},
+			Speed:  entity.FloatFieldOr("speed", 1),
+			Easing: components.Easing(entity.StringFieldOr("easing", string(components.EaseLinear))),
+			Wait:   int(entity.FloatFieldOr("wait", 30)),
+		}
+		for _, field := range entity.FieldInstances {
+			switch field.Identifier {
+			case "path":
+				platform.Path = append(platform.Path, pathField(field.Value)...)
+			case "loop":
+				setBoolField(&platform.Loop, field.Value)
+			}
+		}
+		return NewMovingPlatform(sto, platform)
+	})
+
+	// SceneTransfer moves the player to a position in another scene
+	entityRegistry.Register("SceneTransfer", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		target := entity.StringFieldOr("targetScene", "")
+		if target == "" {
+			return fmt.Errorf("scenes: SceneTransfer at %v has no targetScene", entity.Position)
+		}
+		if _, ok := ByName(target); !ok {
+			return fmt.Errorf("scenes: SceneTransfer at %v targets unknown scene %q", entity.Position, target)
+		}
+		return NewCollisionPlayerTransfer(
+			sto,
+			float64(entity.Position[0]),
+			float64(entity.Position[1]),
+			entity.FloatFieldOr("width", float64(entity.Width)),
+			entity.FloatFieldOr("height", float64(entity.Height)),
+			entity.FloatFieldOr("targetX", 0),
+			entity.FloatFieldOr("targetY", 0),
+			target,
+		)
+	})
+}
+
+// LDtk points are grid cells, the platform moves between their centers
+func pathField(value any) []vector.Two {
+	points, _ := value.([]any)
+	path := make([]vector.Two, 0, len(points))
+	for _, p := range points {
+		point, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		var cx, cy float64
+		setFloatField(&cx, point["cx"])
+		setFloatField(&cy, point["cy"])
+		path = append(path, vector.Two{
+			X: cx*LDTK_GRID_SIZE + LDTK_GRID_SIZE/2,
+			Y: cy*LDTK_GRID_SIZE + LDTK_GRID_SIZE/2,
+		})
+	}
+	return path
 }
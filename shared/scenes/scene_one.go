@@ -30,6 +30,12 @@ func sceneOnePlan(width, height int, sto warehouse.Storage) error {
 		return err
 	}
 
+	// Scene physics, players spawned here copy it into their MovementConfig
+	err = SetSceneMovementConfig(sto, LevelMovementConfig(SCENE_ONE_NAME))
+	if err != nil {
+		return err
+	}
+
 	// Load the terrain
 	// Pass the terrain archetypes in order of int grid layer they map to
 	blockArchetype, _ := sto.NewOrExistingArchetype(BlockTerrainComposition...)
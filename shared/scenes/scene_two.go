@@ -0,0 +1,63 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/ldtk"
+	"github.com/TheBitDrifter/netcode_example/shared/sounds"
+)
+
+const SCENE_TWO_NAME = "Scene2"
+
+var SCENE_TWO_PRELOADED_ASSETS = client.NewPreLoadBlueprint().
+	AddSprite(PLAYER_SPRITE_SHEET_PATH).
+	AddSound(sounds.Jump).
+	AddSound(sounds.Land).
+	AddSound(sounds.Run)
+
+var SceneTwo = Scene{
+	Name:    SCENE_TWO_NAME,
+	Plan:    sceneTwoPlan,
+	Width:   ldtk.DATA.WidthFor(SCENE_TWO_NAME),
+	Height:  ldtk.DATA.HeightFor(SCENE_TWO_NAME),
+	Preload: *SCENE_TWO_PRELOADED_ASSETS,
+}
+
+func sceneTwoPlan(width, height int, sto warehouse.Storage) error {
+	// Load the image tiles
+	err := ldtk.DATA.LoadTiles(SCENE_TWO_NAME, sto)
+	if err != nil {
+		return err
+	}
+
+	// Scene physics, players spawned here copy it into their MovementConfig
+	err = SetSceneMovementConfig(sto, LevelMovementConfig(SCENE_TWO_NAME))
+	if err != nil {
+		return err
+	}
+
+	// Load the terrain
+	// Pass the terrain archetypes in order of int grid layer they map to
+	blockArchetype, _ := sto.NewOrExistingArchetype(BlockTerrainComposition...)
+	platArchetype, _ := sto.NewOrExistingArchetype(PlatformComposition...)
+	transferArchetype, _ := sto.NewOrExistingArchetype(CollisionPlayerTransferComposition...)
+
+	err = ldtk.DATA.LoadIntGrid(SCENE_TWO_NAME, sto, blockArchetype, platArchetype, transferArchetype)
+	if err != nil {
+		return err
+	}
+
+	// Load custom LDTK entities (including the transfer back to Scene1)
+	err = ldtk.DATA.LoadEntities(SCENE_TWO_NAME, sto, entityRegistry)
+	if err != nil {
+		return err
+	}
+
+	// Music
+	err = NewJazzMusic(sto)
+	if err != nil {
+		return err
+	}
+
+	return NewSkyBackground(sto)
+}
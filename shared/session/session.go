@@ -0,0 +1,169 @@
+// Package session is the protocol clients use to resume their player after a
+// dropped connection.
+//
+// drip has no per connection messages, so sessions live on a small HTTP
+// endpoint next to the game server. After connecting, a client sends a random
+// Proof over its drip connection as an action, then claims the connection by
+// presenting the same proof and receives a token. When it reconnects within the server's grace period it
+// claims the new connection with that token and the server moves the player's
+// old state, position and scene onto the new entity.
+package session
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+)
+
+const (
+	// DEFAULT_ADDRESS is the conventional session address, the server only
+	// serves sessions when one is configured
+	DEFAULT_ADDRESS = "localhost:8081"
+	// CLAIM_PATH is the endpoint connections are claimed on
+	CLAIM_PATH = "/session/claim"
+)
+
+var (
+	// ErrUnknownConnection means the server has no player for the connection
+	// yet, claims should be retried shortly
+	ErrUnknownConnection = errors.New("session: connection not found")
+	// ErrAlreadyClaimed means the connection's session was claimed before
+	ErrAlreadyClaimed = errors.New("session: connection already claimed")
+)
+
+// ClaimRequest claims the drip connection that sent Proof
+type ClaimRequest struct {
+	// Proof is the String of the proof the client sent over its connection
+	Proof string `json:"proof"`
+	// Token resumes an earlier session when still within its grace period
+	Token string `json:"token,omitempty"`
+}
+
+// Proof ties a claim to a drip connection. Only the client holding the
+// connection can send the proof over it, so nobody else can claim its player
+//
+// It travels in the X and Y of a SessionProof action, 31 random bits each
+type Proof struct {
+	X, Y int
+}
+
+// NewProof returns a random proof
+func NewProof() Proof {
+	var buf [8]byte
+	_, err := crand.Read(buf[:])
+	if err != nil {
+		panic(err)
+	}
+	return Proof{
+		X: int(binary.LittleEndian.Uint32(buf[:4]) >> 1),
+		Y: int(binary.LittleEndian.Uint32(buf[4:]) >> 1),
+	}
+}
+
+// ProofOf reads the proof carried by a SessionProof action
+func ProofOf(action input.StampedAction) Proof {
+	return Proof{X: action.X, Y: action.Y}
+}
+
+// Action wraps the proof in a SessionProof action stamped with tick
+func (p Proof) Action(tick int) input.StampedAction {
+	return input.StampedAction{Tick: tick, Val: actions.SessionProof, X: p.X, Y: p.Y}
+}
+
+func (p Proof) String() string {
+	return fmt.Sprintf("%08x%08x", p.X, p.Y)
+}
+
+// ClaimResponse is the session the connection now belongs to
+type ClaimResponse struct {
+	Token   string `json:"token"`
+	Entity  uint32 `json:"entity"`
+	Scene   string `json:"scene"`
+	Resumed bool   `json:"resumed"`
+}
+
+var httpClient = &http.Client{Timeout: 2 * time.Second}
+
+// Claim sends a single claim to the session endpoint (host:port)
+func Claim(endpoint string, req ClaimRequest) (ClaimResponse, error) {
+	var resp ClaimResponse
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := httpClient.Post("http://"+endpoint+CLAIM_PATH, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	defer httpResp.Body.Close()
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		err := json.NewDecoder(httpResp.Body).Decode(&resp)
+		return resp, err
+	case http.StatusNotFound:
+		return resp, ErrUnknownConnection
+	case http.StatusConflict:
+		return resp, ErrAlreadyClaimed
+	default:
+		return resp, fmt.Errorf("session: claim failed with %s", httpResp.Status)
+	}
+}
+
+// ClaimWithRetry retries claims for a connection the server does not know yet
+// (its proof may still be in flight), for up to timeout
+func ClaimWithRetry(endpoint string, req ClaimRequest, timeout time.Duration) (ClaimResponse, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := Claim(endpoint, req)
+		if !errors.Is(err, ErrUnknownConnection) || time.Now().After(deadline) {
+			return resp, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Backoff spaces out reconnection attempts, doubling from Min up to Max with
+// some jitter so a server restart isn't hit by every client at once
+type Backoff struct {
+	Min, Max time.Duration
+	attempt  int
+}
+
+func NewBackoff() *Backoff {
+	return &Backoff{Min: 250 * time.Millisecond, Max: 10 * time.Second}
+}
+
+// Next returns how long to wait before the next attempt
+func (b *Backoff) Next() time.Duration {
+	delay := b.Max
+	if b.attempt < 32 {
+		if d := b.Min << b.attempt; d > 0 && d < b.Max {
+			delay = d
+		}
+	}
+	b.attempt++
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay - delay/10 + jitter
+}
+
+// Attempts is how many delays were handed out since the last reset
+func (b *Backoff) Attempts() int {
+	return b.attempt
+}
+
+// Reset starts over from Min after a successful attempt
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,40 @@
+package snapshot
+
+import (
+	"hash/fnv"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+)
+
+// Ack tells the server the newest frame of a scene's stream a client decoded,
+// the server computes that client's next deltas against it
+//
+// It travels in the X and Y of a SnapshotAck action, the tick and an ID of the
+// scene, so an ack sent just before a scene transfer is not mistaken for a
+// frame of the new scene
+type Ack struct {
+	Scene string
+	Tick  int
+}
+
+// Action wraps the ack in a SnapshotAck action stamped with tick
+func (a Ack) Action(tick int) input.StampedAction {
+	return input.StampedAction{Tick: tick, Val: actions.SnapshotAck, X: a.Tick, Y: sceneID(a.Scene)}
+}
+
+// AckOf reads the acknowledged tick of a SnapshotAck action, it reports false
+// when the ack belongs to another scene's stream
+func AckOf(scene string, action input.StampedAction) (int, bool) {
+	if action.Y != sceneID(scene) {
+		return 0, false
+	}
+	return action.X, true
+}
+
+// sceneID hashes a scene name into 31 bits
+func sceneID(scene string) int {
+	h := fnv.New32a()
+	h.Write([]byte(scene))
+	return int(h.Sum32() >> 1)
+}
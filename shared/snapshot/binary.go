@@ -0,0 +1,393 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Binary frame layout (little endian):
+//
+//	magic    u8
+//	kind     u8   (frameKeyframe or frameDelta)
+//	tick     u32
+//	base     u32  (tick the delta was computed against, 0 for keyframes)
+//	rate     u16  (server ticks per second)
+//	scene    u8 length + scene name
+//	spawns   u32 length + JSON encoded warehouse.SerializedStorage
+//	removed  u16 count + u32 entity ids
+//	updates  u16 count + records
+//	sums     u16 count + (u32 entity id, u64 checksum)
+//	acks     u16 count + (u32 entity id, u32 input stamp)
+//
+// Each update record is an entity id (u32), a field mask (u8) and then only the
+// fields named by the mask, each with a fixed width. The checksums cover the full
+// authoritative state of every entity in the snapshot, not just the updated ones.
+// Acks hold the newest input stamp the server received per player, only those
+// that changed since the previous frame are sent.
+const (
+	Magic = 0xB1
+
+	frameKeyframe uint8 = 1
+	frameDelta    uint8 = 2
+)
+
+const (
+	fieldPosition uint8 = 1 << iota
+	fieldVelocity
+	fieldDirection
+	fieldJump
+	fieldOnGround
+	fieldOnWall
+	fieldDash
+	fieldCrouch
+
+	fieldAll = fieldPosition | fieldVelocity | fieldDirection | fieldJump | fieldOnGround | fieldOnWall | fieldDash | fieldCrouch
+)
+
+var ErrMalformedFrame = errors.New("snapshot: malformed binary frame")
+
+// frame is the wire level representation of a binary snapshot
+type frame struct {
+	kind    uint8
+	tick    uint32
+	base    uint32
+	rate    uint16
+	scene   string
+	spawns  []byte
+	removed []uint32
+	updates []update
+	sums    []entitySum
+	acks    []inputAck
+}
+
+type entitySum struct {
+	id  uint32
+	sum uint64
+}
+
+type inputAck struct {
+	id   uint32
+	tick uint32
+}
+
+type update struct {
+	mask  uint8
+	state EntityState
+}
+
+// diff returns the mask of fields that changed between two states
+func diff(prev, cur EntityState) uint8 {
+	var mask uint8
+	if prev.Position != cur.Position {
+		mask |= fieldPosition
+	}
+	if prev.Velocity != cur.Velocity {
+		mask |= fieldVelocity
+	}
+	if prev.Direction != cur.Direction {
+		mask |= fieldDirection
+	}
+	if prev.Jump != cur.Jump {
+		mask |= fieldJump
+	}
+	if prev.Grounded != cur.Grounded || prev.OnGround != cur.OnGround {
+		mask |= fieldOnGround
+	}
+	if prev.TouchingWall != cur.TouchingWall || prev.OnWall != cur.OnWall {
+		mask |= fieldOnWall
+	}
+	if prev.Dash != cur.Dash {
+		mask |= fieldDash
+	}
+	if prev.Crouch != cur.Crouch {
+		mask |= fieldCrouch
+	}
+	return mask
+}
+
+// merge copies the masked fields of src onto dst
+func merge(dst *EntityState, src EntityState, mask uint8) {
+	if mask&fieldPosition != 0 {
+		dst.Position = src.Position
+	}
+	if mask&fieldVelocity != 0 {
+		dst.Velocity = src.Velocity
+	}
+	if mask&fieldDirection != 0 {
+		dst.Direction = src.Direction
+	}
+	if mask&fieldJump != 0 {
+		dst.Jump = src.Jump
+	}
+	if mask&fieldOnGround != 0 {
+		dst.Grounded = src.Grounded
+		dst.OnGround = src.OnGround
+	}
+	if mask&fieldOnWall != 0 {
+		dst.TouchingWall = src.TouchingWall
+		dst.OnWall = src.OnWall
+	}
+	if mask&fieldDash != 0 {
+		dst.Dash = src.Dash
+	}
+	if mask&fieldCrouch != 0 {
+		dst.Crouch = src.Crouch
+	}
+}
+
+func (f frame) marshal() []byte {
+	buf := make([]byte, 0, 20+len(f.spawns)+len(f.removed)*4+len(f.updates)*64+len(f.sums)*12+len(f.acks)*8)
+	buf = append(buf, Magic, f.kind)
+	buf = binary.LittleEndian.AppendUint32(buf, f.tick)
+	buf = binary.LittleEndian.AppendUint32(buf, f.base)
+	buf = binary.LittleEndian.AppendUint16(buf, f.rate)
+	scene := f.scene[:min(len(f.scene), math.MaxUint8)]
+	buf = append(buf, uint8(len(scene)))
+	buf = append(buf, scene...)
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(f.spawns)))
+	buf = append(buf, f.spawns...)
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(f.removed)))
+	for _, id := range f.removed {
+		buf = binary.LittleEndian.AppendUint32(buf, id)
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(f.updates)))
+	for _, u := range f.updates {
+		buf = binary.LittleEndian.AppendUint32(buf, u.state.ID)
+		buf = append(buf, u.mask)
+		if u.mask&fieldPosition != 0 {
+			buf = appendFloat(buf, u.state.Position.X)
+			buf = appendFloat(buf, u.state.Position.Y)
+		}
+		if u.mask&fieldVelocity != 0 {
+			buf = appendFloat(buf, u.state.Velocity.X)
+			buf = appendFloat(buf, u.state.Velocity.Y)
+		}
+		if u.mask&fieldDirection != 0 {
+			buf = append(buf, byte(u.state.Direction))
+		}
+		if u.mask&fieldJump != 0 {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Jump.LastJump)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Jump.LastHeld)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Jump.LastPress)))
+			buf = append(buf, uint8(min(max(u.state.Jump.AirJumps, 0), math.MaxUint8)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Jump.WallLockout)))
+		}
+		if u.mask&fieldOnGround != 0 && !u.state.Grounded {
+			buf = append(buf, 0)
+		} else if u.mask&fieldOnGround != 0 {
+			buf = append(buf, 1)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.OnGround.LastTouch)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.OnGround.Landed)))
+			buf = appendFloat(buf, u.state.OnGround.SlopeNormal.X)
+			buf = appendFloat(buf, u.state.OnGround.SlopeNormal.Y)
+			buf = appendFloat(buf, u.state.OnGround.PlatformVel.X)
+			buf = appendFloat(buf, u.state.OnGround.PlatformVel.Y)
+		}
+		if u.mask&fieldOnWall != 0 && !u.state.TouchingWall {
+			buf = append(buf, 0)
+		} else if u.mask&fieldOnWall != 0 {
+			buf = append(buf, 1)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.OnWall.LastTouch)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.OnWall.Grabbed)))
+			buf = append(buf, byte(int8(u.state.OnWall.Side)))
+		}
+		if u.mask&fieldDash != 0 {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Dash.LastDash)))
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Dash.LastHeld)))
+			buf = append(buf, byte(int8(u.state.Dash.Direction)))
+		}
+		if u.mask&fieldCrouch != 0 {
+			buf = appendBool(buf, u.state.Crouch.Crouching)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(u.state.Crouch.LastHeld)))
+			buf = appendFloat(buf, u.state.Crouch.StandHeight)
+		}
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(f.sums)))
+	for _, s := range f.sums {
+		buf = binary.LittleEndian.AppendUint32(buf, s.id)
+		buf = binary.LittleEndian.AppendUint64(buf, s.sum)
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(f.acks)))
+	for _, a := range f.acks {
+		buf = binary.LittleEndian.AppendUint32(buf, a.id)
+		buf = binary.LittleEndian.AppendUint32(buf, a.tick)
+	}
+	return buf
+}
+
+func unmarshalFrame(data []byte) (frame, error) {
+	r := reader{data: data}
+	var f frame
+
+	if r.u8() != Magic {
+		return f, ErrMalformedFrame
+	}
+	f.kind = r.u8()
+	f.tick = r.u32()
+	f.base = r.u32()
+	f.rate = r.u16()
+	f.scene = string(r.bytes(int(r.u8())))
+
+	spawnLen := int(r.u32())
+	f.spawns = r.bytes(spawnLen)
+
+	removedCount := int(r.u16())
+	for range removedCount {
+		f.removed = append(f.removed, r.u32())
+	}
+
+	updateCount := int(r.u16())
+	for range updateCount {
+		u := update{}
+		u.state.ID = r.u32()
+		u.mask = r.u8()
+		if u.mask&fieldPosition != 0 {
+			u.state.Position.X = r.f64()
+			u.state.Position.Y = r.f64()
+		}
+		if u.mask&fieldVelocity != 0 {
+			u.state.Velocity.X = r.f64()
+			u.state.Velocity.Y = r.f64()
+		}
+		if u.mask&fieldDirection != 0 {
+			u.state.Direction = int8(r.u8())
+		}
+		if u.mask&fieldJump != 0 {
+			u.state.Jump.LastJump = int(int32(r.u32()))
+			u.state.Jump.LastHeld = int(int32(r.u32()))
+			u.state.Jump.LastPress = int(int32(r.u32()))
+			u.state.Jump.AirJumps = int(r.u8())
+			u.state.Jump.WallLockout = int(int32(r.u32()))
+		}
+		if u.mask&fieldOnGround != 0 && r.u8() == 1 {
+			u.state.Grounded = true
+			u.state.OnGround.LastTouch = int(int32(r.u32()))
+			u.state.OnGround.Landed = int(int32(r.u32()))
+			u.state.OnGround.SlopeNormal.X = r.f64()
+			u.state.OnGround.SlopeNormal.Y = r.f64()
+			u.state.OnGround.PlatformVel.X = r.f64()
+			u.state.OnGround.PlatformVel.Y = r.f64()
+		}
+		if u.mask&fieldOnWall != 0 && r.u8() == 1 {
+			u.state.TouchingWall = true
+			u.state.OnWall.LastTouch = int(int32(r.u32()))
+			u.state.OnWall.Grabbed = int(int32(r.u32()))
+			u.state.OnWall.Side = int(int8(r.u8()))
+		}
+		if u.mask&fieldDash != 0 {
+			u.state.Dash.LastDash = int(int32(r.u32()))
+			u.state.Dash.LastHeld = int(int32(r.u32()))
+			u.state.Dash.Direction = int(int8(r.u8()))
+		}
+		if u.mask&fieldCrouch != 0 {
+			u.state.Crouch.Crouching = r.u8() == 1
+			u.state.Crouch.LastHeld = int(int32(r.u32()))
+			u.state.Crouch.StandHeight = r.f64()
+		}
+		f.updates = append(f.updates, u)
+	}
+
+	sumCount := int(r.u16())
+	for range sumCount {
+		s := entitySum{id: r.u32()}
+		s.sum = r.u64()
+		f.sums = append(f.sums, s)
+	}
+
+	ackCount := int(r.u16())
+	for range ackCount {
+		a := inputAck{id: r.u32()}
+		a.tick = r.u32()
+		f.acks = append(f.acks, a)
+	}
+
+	if r.err {
+		return frame{}, ErrMalformedFrame
+	}
+	if f.kind != frameKeyframe && f.kind != frameDelta {
+		return frame{}, fmt.Errorf("%w: unknown frame kind %d", ErrMalformedFrame, f.kind)
+	}
+	return f, nil
+}
+
+// peekScene reads the scene name from a binary frame header
+func peekScene(data []byte) string {
+	r := reader{data: data, off: 12}
+	return string(r.bytes(int(r.u8())))
+}
+
+func appendFloat(buf []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+func appendBool(buf []byte, v bool) []byte {
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+// reader is a bounds checked cursor over a frame, once it runs out of data
+// every read returns zero and err is set
+type reader struct {
+	data []byte
+	off  int
+	err  bool
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err || n < 0 || r.off+n > len(r.data) {
+		r.err = true
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) u8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) u16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *reader) u32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) u64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *reader) f64() float64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
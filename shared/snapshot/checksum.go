@@ -0,0 +1,66 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// Checksum is a cheap FNV-1a hash of an entity's replicated state
+func (state EntityState) Checksum() uint64 {
+	h := fnv.New64a()
+	h.Write(state.appendChecksum(make([]byte, 0, 64)))
+	return h.Sum64()
+}
+
+// Checksum hashes every entity in the snapshot in id order, two snapshots with
+// the same replicated state always produce the same value
+func (s Snapshot) Checksum() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 64)
+	for _, state := range s.Entities {
+		buf = state.appendChecksum(buf[:0])
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+func (state EntityState) appendChecksum(buf []byte) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, state.ID)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.Position.X))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.Position.Y))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.Velocity.X))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.Velocity.Y))
+	buf = append(buf, byte(state.Direction))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Jump.LastJump)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Jump.LastHeld)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Jump.LastPress)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Jump.AirJumps)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Jump.WallLockout)))
+	if state.Grounded {
+		buf = append(buf, 1)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.OnGround.LastTouch)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.OnGround.Landed)))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.OnGround.SlopeNormal.X))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.OnGround.SlopeNormal.Y))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.OnGround.PlatformVel.X))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.OnGround.PlatformVel.Y))
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Dash.LastDash)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Dash.LastHeld)))
+	buf = append(buf, byte(int8(state.Dash.Direction)))
+	buf = appendBool(buf, state.Crouch.Crouching)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.Crouch.LastHeld)))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(state.Crouch.StandHeight))
+	if state.TouchingWall {
+		buf = append(buf, 1)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.OnWall.LastTouch)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(state.OnWall.Grabbed)))
+		buf = append(buf, byte(int8(state.OnWall.Side)))
+	} else {
+		buf = append(buf, 0)
+	}
+	return buf
+}
@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/TheBitDrifter/bappa/blueprint/input"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+)
+
+// Codec selects the wire format of a snapshot stream
+//
+// Clients offer the codecs they decode (see OfferAction) and the server picks
+// one per connection with Negotiate, JSON is the fallback every client and tool
+// understands. The decoder detects the format of each frame on its own (see
+// IsBinary)
+type Codec string
+
+const (
+	CodecJSON   Codec = "json"
+	CodecBinary Codec = "binary"
+)
+
+// codecBits are the codecs' bits in a CodecOffer action
+var codecBits = map[Codec]int{
+	CodecJSON:   1 << 0,
+	CodecBinary: 1 << 1,
+}
+
+// serializedVersion tags the JSON storage, the stream's scene and tick rate
+// travel in the JSON frame's own fields
+const serializedVersion = "net"
+
+// OfferAction wraps the codecs a client decodes in a CodecOffer action stamped
+// with tick
+func OfferAction(tick int, codecs ...Codec) input.StampedAction {
+	offer := 0
+	for _, codec := range codecs {
+		offer |= codecBits[codec]
+	}
+	return input.StampedAction{Tick: tick, Val: actions.CodecOffer, X: offer}
+}
+
+// Negotiate picks the codec of a connection that sent offer: the preferred
+// codec when the client decodes it, JSON otherwise
+func Negotiate(preferred Codec, offer input.StampedAction) Codec {
+	if offer.X&codecBits[preferred] != 0 {
+		return preferred
+	}
+	return CodecJSON
+}
+
+// ParseCodec converts a codec name (as used by flags and config) into a Codec
+func ParseCodec(name string) (Codec, error) {
+	switch Codec(name) {
+	case CodecJSON, CodecBinary:
+		return Codec(name), nil
+	}
+	return "", fmt.Errorf("snapshot: unknown codec %q (expected %q or %q)", name, CodecJSON, CodecBinary)
+}
+
+// IsBinary reports whether data holds a binary frame rather than a JSON document
+func IsBinary(data []byte) bool {
+	return len(data) > 0 && data[0] == Magic
+}
+
+// jsonFrame is a JSON snapshot: the stream's details next to every entity as
+// a full record in the warehouse storage format. S is the storage, as prepared
+// for marshalling when encoding
+type jsonFrame[S any] struct {
+	// Scene the stream belongs to, empty when the server did not say
+	Scene string `json:"scene,omitempty"`
+	// TickRate is the server's ticks per second, zero when unknown
+	TickRate int `json:"tick_rate,omitempty"`
+	// Checksums of the server's state per entity, the same as a binary frame's
+	Checksums map[uint32]uint64 `json:"checksums,omitempty"`
+	Storage   S                 `json:"storage"`
+}
+
+// EncodeJSON marshals a scene's serialized entities and the checksums of their
+// states into a JSON frame, tickRate is the server's tick rate (zero when unknown)
+func EncodeJSON(tick int, scene string, tickRate int, entities []warehouse.SerializedEntity, checksums map[uint32]uint64) ([]byte, error) {
+	storage, err := prepareStorage(tick, entities)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonFrame[any]{Scene: scene, TickRate: tickRate, Checksums: checksums, Storage: storage})
+}
+
+func unmarshalJSONFrame(data []byte) (jsonFrame[warehouse.SerializedStorage], error) {
+	var f jsonFrame[warehouse.SerializedStorage]
+	err := json.Unmarshal(data, &f)
+	return f, err
+}
+
+// encodeStorage marshals serialized entities into the bare JSON storage
+// format, binary frames carry their spawns in it
+func encodeStorage(tick int, entities []warehouse.SerializedEntity) ([]byte, error) {
+	storage, err := prepareStorage(tick, entities)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(storage)
+}
+
+func decodeStorage(data []byte) (warehouse.SerializedStorage, error) {
+	var world warehouse.SerializedStorage
+	err := json.Unmarshal(data, &world)
+	return world, err
+}
+
+func prepareStorage(tick int, entities []warehouse.SerializedEntity) (any, error) {
+	return warehouse.PrepareForJSONMarshal(warehouse.SerializedStorage{
+		Entities:    entities,
+		CurrentTick: tick,
+		Version:     serializedVersion,
+	})
+}
@@ -0,0 +1,226 @@
+package snapshot
+
+import (
+	"errors"
+
+	"github.com/TheBitDrifter/bappa/table"
+	"github.com/TheBitDrifter/bappa/warehouse"
+)
+
+// ErrMissingBaseline is returned for delta frames computed against a snapshot
+// the decoder does not hold, the frame should be dropped until the next keyframe
+var ErrMissingBaseline = errors.New("snapshot: delta baseline not available")
+
+// Frame is a decoded snapshot ready to be applied to client storage
+type Frame struct {
+	// Snapshot is the full authoritative state (baseline plus delta)
+	Snapshot Snapshot
+	// Spawns are full entity records for entities the client may not have yet
+	Spawns []warehouse.SerializedEntity
+	// Removed lists entities that left the snapshot since the baseline
+	Removed []uint32
+	// Keyframe frames describe every entity, anything absent should be purged
+	Keyframe bool
+	// Scene the frame belongs to, empty when the server did not say
+	Scene string
+	// Checksums of the server's state per entity
+	Checksums map[uint32]uint64
+	// Acks are the newest input stamps the server received per player that
+	// changed since the previous frame, binary frames only (JSON records carry
+	// the InputAck component instead)
+	Acks map[uint32]int
+	// TickRate is the server's ticks per second, zero when the server did not say
+	TickRate int
+}
+
+// Checksum returns the server's checksum for an entity
+func (f Frame) Checksum(id uint32) (uint64, bool) {
+	sum, ok := f.Checksums[id]
+	return sum, ok
+}
+
+// Ack returns the newest input stamp the server received from a player
+func (f Frame) Ack(id uint32) (int, bool) {
+	tick, ok := f.Acks[id]
+	return tick, ok
+}
+
+// Contains reports whether the frame carries the given entity
+func (f Frame) Contains(id uint32) bool {
+	if _, ok := f.Snapshot.Find(id); ok {
+		return true
+	}
+	for _, se := range f.Spawns {
+		if uint32(se.ID) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Decoder rebuilds full snapshots from the server's frame stream
+//
+// It keeps the snapshots of the last MAX_BASELINES ticks, deltas may be
+// computed against any of them
+type Decoder struct {
+	history map[int]Snapshot
+}
+
+// Decode parses a binary or JSON frame
+func (d *Decoder) Decode(data []byte) (Frame, error) {
+	if !IsBinary(data) {
+		return d.decodeJSON(data)
+	}
+
+	f, err := unmarshalFrame(data)
+	if err != nil {
+		return Frame{}, err
+	}
+
+	out := Frame{
+		Snapshot: Snapshot{Tick: int(f.tick)},
+		Removed:  f.removed,
+		Keyframe: f.kind == frameKeyframe,
+		Scene:    f.scene,
+		TickRate: int(f.rate),
+	}
+
+	if len(f.sums) > 0 {
+		out.Checksums = make(map[uint32]uint64, len(f.sums))
+		for _, s := range f.sums {
+			out.Checksums[s.id] = s.sum
+		}
+	}
+	if len(f.acks) > 0 {
+		out.Acks = make(map[uint32]int, len(f.acks))
+		for _, a := range f.acks {
+			out.Acks[a.id] = int(a.tick)
+		}
+	}
+
+	if len(f.spawns) > 0 {
+		world, err := decodeStorage(f.spawns)
+		if err != nil {
+			return Frame{}, err
+		}
+		out.Spawns = world.Entities
+	}
+
+	if out.Keyframe {
+		for _, u := range f.updates {
+			out.Snapshot.Entities = append(out.Snapshot.Entities, u.state)
+		}
+		SortStates(out.Snapshot.Entities)
+		d.remember(out.Snapshot)
+		return out, nil
+	}
+
+	base, ok := d.history[int(f.base)]
+	if !ok {
+		return Frame{}, ErrMissingBaseline
+	}
+
+	states := make(map[uint32]EntityState, len(base.Entities))
+	for _, state := range base.Entities {
+		states[state.ID] = state
+	}
+	for _, id := range f.removed {
+		delete(states, id)
+	}
+	for _, u := range f.updates {
+		state, ok := states[u.state.ID]
+		if !ok {
+			state.ID = u.state.ID
+		}
+		merge(&state, u.state, u.mask)
+		states[u.state.ID] = state
+	}
+
+	out.Snapshot.Entities = make([]EntityState, 0, len(states))
+	for _, state := range states {
+		out.Snapshot.Entities = append(out.Snapshot.Entities, state)
+	}
+	SortStates(out.Snapshot.Entities)
+
+	d.remember(out.Snapshot)
+	return out, nil
+}
+
+// remember keeps a decoded snapshot as a baseline for later deltas
+func (d *Decoder) remember(snap Snapshot) {
+	if d.history == nil {
+		d.history = map[int]Snapshot{}
+	}
+	d.history[snap.Tick] = snap
+	for tick := range d.history {
+		if tick <= snap.Tick-MAX_BASELINES {
+			delete(d.history, tick)
+		}
+	}
+}
+
+// decodeJSON handles the JSON fallback, every entity arrives as a full record
+func (d *Decoder) decodeJSON(data []byte) (Frame, error) {
+	f, err := unmarshalJSONFrame(data)
+	if err != nil {
+		return Frame{}, err
+	}
+	states, err := captureRecords(f.Storage.Entities)
+	if err != nil {
+		return Frame{}, err
+	}
+	d.history = nil
+	return Frame{
+		Snapshot:  Snapshot{Tick: f.Storage.CurrentTick, Entities: states},
+		Spawns:    f.Storage.Entities,
+		Keyframe:  true,
+		Scene:     f.Scene,
+		Checksums: f.Checksums,
+		TickRate:  f.TickRate,
+	}, nil
+}
+
+// captureRecords reads the replicated state out of full entity records, which
+// it materializes in a scratch storage
+func captureRecords(records []warehouse.SerializedEntity) ([]EntityState, error) {
+	sto := warehouse.Factory.NewStorage(table.Factory.NewSchema())
+	states := make([]EntityState, 0, len(records))
+	for _, se := range records {
+		en, err := sto.ForceSerializedEntity(se)
+		if err != nil {
+			return nil, err
+		}
+		err = se.SetValue(en)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, Capture(en))
+	}
+	SortStates(states)
+	return states, nil
+}
+
+// SceneDecoders keeps a Decoder per scene, so streams from several server
+// scenes (for example around a scene transfer) don't break each other's deltas
+type SceneDecoders struct {
+	decoders map[string]*Decoder
+}
+
+// Decode parses a frame with the decoder for the scene it belongs to
+func (sd *SceneDecoders) Decode(data []byte) (Frame, error) {
+	if !IsBinary(data) {
+		var d Decoder
+		return d.decodeJSON(data)
+	}
+
+	if sd.decoders == nil {
+		sd.decoders = map[string]*Decoder{}
+	}
+	scene := peekScene(data)
+	d, ok := sd.decoders[scene]
+	if !ok {
+		d = &Decoder{}
+		sd.decoders[scene] = d
+	}
+	return d.Decode(data)
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"log"
+	"sync"
+)
+
+// Names of the replicated components reported by Desync
+const (
+	ComponentPosition  = "Position"
+	ComponentVelocity  = "Velocity"
+	ComponentDirection = "Direction"
+	ComponentJumpState = "JumpState"
+	ComponentOnGround  = "OnGround"
+	ComponentOnWall    = "OnWall"
+	ComponentDash      = "DashState"
+	ComponentCrouch    = "CrouchState"
+)
+
+// Desync describes a state that did not match the server's checksum
+type Desync struct {
+	Tick       int
+	Entity     uint32
+	Components []string
+}
+
+// DesyncDetector compares local state against the checksums the server sends
+// with every frame and keeps running totals
+//
+// The client and the bots check their predicted player. VerifyFrame instead
+// checks a stream against itself, which catches codec corruption but not
+// desyncs
+type DesyncDetector struct {
+	// Log prints every desync as it is detected
+	Log bool
+
+	mu          sync.Mutex
+	checked     int
+	desyncs     int
+	byComponent map[string]int
+}
+
+// DesyncStats is a copy of a detector's totals
+type DesyncStats struct {
+	Checked     int
+	Desyncs     int
+	ByComponent map[string]int
+}
+
+// Rate is the fraction of checks that found a desync
+func (s DesyncStats) Rate() float64 {
+	if s.Checked == 0 {
+		return 0
+	}
+	return float64(s.Desyncs) / float64(s.Checked)
+}
+
+// Check compares local against the server's checksum for the same entity and
+// tick. auth is the decoded server state, used to name the offending components
+// (pass ok false when it is unavailable). It reports whether the states matched
+func (d *DesyncDetector) Check(tick int, local EntityState, checksum uint64, auth EntityState, ok bool) bool {
+	match := local.Checksum() == checksum
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.checked++
+	if match {
+		return true
+	}
+
+	desync := Desync{Tick: tick, Entity: local.ID}
+	if ok {
+		desync.Components = DiffComponents(auth, local)
+	}
+
+	d.desyncs++
+	if d.byComponent == nil {
+		d.byComponent = map[string]int{}
+	}
+	for _, name := range desync.Components {
+		d.byComponent[name]++
+	}
+
+	if d.Log {
+		log.Printf("Desync at tick %d: entity %d differs in %v (%d of %d checks)",
+			desync.Tick, desync.Entity, desync.Components, d.desyncs, d.checked)
+	}
+	return false
+}
+
+// VerifyFrame is a stream integrity check, it hashes every decoded state in a
+// frame and compares it with the checksum sent alongside. A mismatch means the
+// frame decoded into something other than what the server encoded, it says
+// nothing about whether a local simulation agrees with the server
+func (d *DesyncDetector) VerifyFrame(frame Frame) {
+	for _, state := range frame.Snapshot.Entities {
+		sum, ok := frame.Checksum(state.ID)
+		if !ok {
+			continue
+		}
+		d.Check(frame.Snapshot.Tick, state, sum, state, false)
+	}
+}
+
+// Stats returns the totals so far
+func (d *DesyncDetector) Stats() DesyncStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := DesyncStats{
+		Checked:     d.checked,
+		Desyncs:     d.desyncs,
+		ByComponent: make(map[string]int, len(d.byComponent)),
+	}
+	for name, n := range d.byComponent {
+		stats.ByComponent[name] = n
+	}
+	return stats
+}
+
+// DiffComponents names the replicated components that differ between two states
+func DiffComponents(a, b EntityState) []string {
+	mask := diff(a, b)
+	names := []string{}
+	for _, field := range []struct {
+		bit  uint8
+		name string
+	}{
+		{fieldPosition, ComponentPosition},
+		{fieldVelocity, ComponentVelocity},
+		{fieldDirection, ComponentDirection},
+		{fieldJump, ComponentJumpState},
+		{fieldOnGround, ComponentOnGround},
+		{fieldOnWall, ComponentOnWall},
+		{fieldDash, ComponentDash},
+		{fieldCrouch, ComponentCrouch},
+	} {
+		if mask&field.bit != 0 {
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,241 @@
+package snapshot
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+// DEFAULT_KEYFRAME_INTERVAL is how many ticks may pass between keyframes
+const DEFAULT_KEYFRAME_INTERVAL = 60
+
+// MAX_BASELINES is how many ticks of frames an encoder keeps to compute deltas
+// against and a decoder keeps to apply them to. A recipient whose newest ack
+// is older is sent a keyframe
+const MAX_BASELINES = 128
+
+// Encoder produces a snapshot stream, either broadcast to a whole scene or for
+// a single recipient
+//
+// A recipient's stream is delta compressed against the newest frame the
+// recipient acknowledged (see Acknowledge), so a frame it never received is
+// not used as its baseline. Until its first ack, and on a broadcast stream,
+// where acks of different listeners would disagree, deltas are computed
+// against the previous frame: frames travel over a reliable, ordered stream,
+// so by the time the next one is written every listener holds it.
+//
+// Entities that enter the stream are sent as spawn records inside the delta
+// and entities that leave it as explicit removals. On a per recipient stream
+// that is how entities moving in and out of the recipient's area of interest
+// are despawned and spawned again.
+//
+// A keyframe carries a full record of every entity. It is sent when there is
+// no baseline (the first frame, or an ack older than MAX_BASELINES ticks), when
+// a listener joins a broadcast stream (see Listener), when RequestKeyframe was
+// called and every KeyframeInterval ticks, zero disables the periodic ones
+type Encoder struct {
+	Codec            Codec
+	KeyframeInterval int
+	PerRecipient     bool
+	// Scene the stream belongs to, clients use it to follow their player
+	Scene string
+	// TickRate of the server, clients derive their fixed dt from it
+	TickRate int
+	// Listener reports whether an entity is controlled by a connection that
+	// receives this stream. When nil every spawn is treated as a new listener
+	Listener func(warehouse.Entity) bool
+
+	baselines     map[int]baseline
+	last          int
+	sinceKeyframe int
+	requested     atomic.Bool
+	acked         atomic.Int64
+}
+
+// baseline is what a sent frame held, later deltas may be computed against it
+type baseline struct {
+	states   map[uint32]EntityState
+	recycled map[uint32]int
+	acks     map[uint32]int
+}
+
+// NewEncoder creates a broadcast encoder for a scene
+func NewEncoder(codec Codec, scene string) *Encoder {
+	enc := &Encoder{
+		Codec:            codec,
+		KeyframeInterval: DEFAULT_KEYFRAME_INTERVAL,
+		Scene:            scene,
+		baselines:        map[int]baseline{},
+	}
+	enc.acked.Store(-1)
+	return enc
+}
+
+// NewRecipientEncoder creates an encoder for a stream sent to a single recipient
+func NewRecipientEncoder(codec Codec, scene string) *Encoder {
+	enc := NewEncoder(codec, scene)
+	enc.PerRecipient = true
+	return enc
+}
+
+// RequestKeyframe makes the next binary frame a keyframe, for listeners that
+// lost their baseline without spawning into the stream (for example a resumed
+// session reusing a player)
+func (enc *Encoder) RequestKeyframe() {
+	enc.requested.Store(true)
+}
+
+// Acknowledge records that the recipient decoded the frame of tick, ignored on
+// broadcast streams and for ticks older than an earlier ack
+func (enc *Encoder) Acknowledge(tick int) {
+	if !enc.PerRecipient {
+		return
+	}
+	for {
+		acked := enc.acked.Load()
+		if int64(tick) <= acked || enc.acked.CompareAndSwap(acked, int64(tick)) {
+			return
+		}
+	}
+}
+
+// SetCodec switches the stream to another codec, the next binary frame is a
+// keyframe
+func (enc *Encoder) SetCodec(codec Codec) {
+	if codec == enc.Codec {
+		return
+	}
+	enc.Codec = codec
+	enc.baselines = map[int]baseline{}
+	enc.acked.Store(-1)
+}
+
+// baseline returns the frame the next delta is computed against and its tick
+func (enc *Encoder) baseline() (baseline, int, bool) {
+	tick := enc.last
+	if acked := enc.acked.Load(); acked >= 0 {
+		tick = int(acked)
+	}
+	base, ok := enc.baselines[tick]
+	return base, tick, ok
+}
+
+// Encode serializes the given player entities for tick, omitting the excluded
+// components from any full entity records
+func (enc *Encoder) Encode(tick int, entities []warehouse.Entity, exclude ...warehouse.Component) ([]byte, error) {
+	if enc.Codec == CodecJSON {
+		return enc.encodeJSON(tick, entities, exclude)
+	}
+	return enc.encodeBinary(tick, entities, exclude)
+}
+
+func (enc *Encoder) encodeJSON(tick int, entities []warehouse.Entity, exclude []warehouse.Component) ([]byte, error) {
+	sEntities := make([]warehouse.SerializedEntity, 0, len(entities))
+	checksums := make(map[uint32]uint64, len(entities))
+	for _, en := range entities {
+		sEntities = append(sEntities, en.SerializeExclude(exclude...))
+		state := Capture(en)
+		checksums[state.ID] = state.Checksum()
+	}
+	return EncodeJSON(tick, enc.Scene, enc.TickRate, sEntities, checksums)
+}
+
+func (enc *Encoder) encodeBinary(tick int, entities []warehouse.Entity, exclude []warehouse.Component) ([]byte, error) {
+	states := make([]EntityState, 0, len(entities))
+	byID := make(map[uint32]warehouse.Entity, len(entities))
+	current := baseline{
+		states:   make(map[uint32]EntityState, len(entities)),
+		recycled: make(map[uint32]int, len(entities)),
+		acks:     make(map[uint32]int, len(entities)),
+	}
+	spawned := map[uint32]bool{}
+
+	base, baseTick, hasBase := enc.baseline()
+	keyframe := !hasBase || enc.requested.Swap(false) ||
+		(enc.KeyframeInterval > 0 && enc.sinceKeyframe >= enc.KeyframeInterval)
+
+	for _, en := range entities {
+		state := Capture(en)
+		states = append(states, state)
+		byID[state.ID] = en
+		current.states[state.ID] = state
+		current.recycled[state.ID] = en.Recycled()
+		if en.Table().Contains(components.InputAckComponent) {
+			current.acks[state.ID] = components.InputAckComponent.GetFromEntity(en).Tick
+		}
+
+		prevRecycled, known := base.recycled[state.ID]
+		if !known || prevRecycled != en.Recycled() {
+			spawned[state.ID] = true
+
+			// A new listener of a broadcast stream has no baseline to apply
+			// a delta to, a recipient's own stream already has one
+			if !enc.PerRecipient && (enc.Listener == nil || enc.Listener(en)) {
+				keyframe = true
+			}
+		}
+	}
+	SortStates(states)
+
+	f := frame{tick: uint32(tick), rate: uint16(min(max(enc.TickRate, 0), math.MaxUint16)), scene: enc.Scene}
+	for _, state := range states {
+		f.sums = append(f.sums, entitySum{id: state.ID, sum: state.Checksum()})
+
+		ack, ok := current.acks[state.ID]
+		prev, sent := base.acks[state.ID]
+		if ok && (keyframe || spawned[state.ID] || !sent || prev != ack) {
+			f.acks = append(f.acks, inputAck{id: state.ID, tick: uint32(max(ack, 0))})
+		}
+	}
+	spawns := []warehouse.SerializedEntity{}
+
+	if keyframe {
+		f.kind = frameKeyframe
+		for _, state := range states {
+			spawns = append(spawns, byID[state.ID].SerializeExclude(exclude...))
+			f.updates = append(f.updates, update{mask: fieldAll, state: state})
+		}
+		enc.sinceKeyframe = 0
+	} else {
+		f.kind = frameDelta
+		f.base = uint32(baseTick)
+		for id := range base.states {
+			if _, ok := current.states[id]; !ok || spawned[id] {
+				f.removed = append(f.removed, id)
+			}
+		}
+		slices.Sort(f.removed)
+		for _, state := range states {
+			if spawned[state.ID] {
+				spawns = append(spawns, byID[state.ID].SerializeExclude(exclude...))
+				f.updates = append(f.updates, update{mask: fieldAll, state: state})
+				continue
+			}
+			if mask := diff(base.states[state.ID], state); mask != 0 {
+				f.updates = append(f.updates, update{mask: mask, state: state})
+			}
+		}
+		enc.sinceKeyframe++
+	}
+
+	if len(spawns) > 0 {
+		spawnData, err := encodeStorage(tick, spawns)
+		if err != nil {
+			return nil, err
+		}
+		f.spawns = spawnData
+	}
+
+	enc.baselines[tick] = current
+	enc.last = tick
+	for old := range enc.baselines {
+		if old <= tick-MAX_BASELINES {
+			delete(enc.baselines, old)
+		}
+	}
+
+	return f.marshal(), nil
+}
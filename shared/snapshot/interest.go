@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"math"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+)
+
+// DEFAULT_INTEREST_CELL_SIZE is the grid cell size (in pixels) used to bucket entities
+const DEFAULT_INTEREST_CELL_SIZE = 256.0
+
+// Interest decides which entities are relevant to a recipient
+type Interest struct {
+	// Radius around the recipient's player, zero disables filtering
+	Radius float64
+	// CellSize of the grid used to look up nearby entities
+	CellSize float64
+	// AlwaysRelevant entities are sent regardless of distance (optional)
+	AlwaysRelevant func(warehouse.Entity) bool
+}
+
+// InterestGrid buckets entities into uniform cells so each recipient only
+// examines the cells within its radius
+type InterestGrid struct {
+	interest Interest
+	cells    map[[2]int][]gridEntry
+	always   []warehouse.Entity
+	all      []warehouse.Entity
+}
+
+type gridEntry struct {
+	entity   warehouse.Entity
+	position vector.Two
+}
+
+// NewInterestGrid builds the grid for a tick's worth of entities
+func NewInterestGrid(interest Interest, entities []warehouse.Entity) *InterestGrid {
+	if interest.CellSize <= 0 {
+		interest.CellSize = DEFAULT_INTEREST_CELL_SIZE
+	}
+	grid := &InterestGrid{
+		interest: interest,
+		cells:    map[[2]int][]gridEntry{},
+		all:      entities,
+	}
+	for _, en := range entities {
+		if interest.AlwaysRelevant != nil && interest.AlwaysRelevant(en) {
+			grid.always = append(grid.always, en)
+			continue
+		}
+		pos := spatial.Components.Position.GetFromEntity(en).Two
+		cell := grid.cellFor(pos)
+		grid.cells[cell] = append(grid.cells[cell], gridEntry{entity: en, position: pos})
+	}
+	return grid
+}
+
+// Relevant returns the entities a recipient at viewer should receive
+func (grid *InterestGrid) Relevant(viewer vector.Two) []warehouse.Entity {
+	if grid.interest.Radius <= 0 {
+		return grid.all
+	}
+
+	relevant := append([]warehouse.Entity{}, grid.always...)
+	radius := grid.interest.Radius
+	min := grid.cellFor(vector.Two{X: viewer.X - radius, Y: viewer.Y - radius})
+	max := grid.cellFor(vector.Two{X: viewer.X + radius, Y: viewer.Y + radius})
+
+	for cx := min[0]; cx <= max[0]; cx++ {
+		for cy := min[1]; cy <= max[1]; cy++ {
+			for _, entry := range grid.cells[[2]int{cx, cy}] {
+				dx := entry.position.X - viewer.X
+				dy := entry.position.Y - viewer.Y
+				if dx*dx+dy*dy <= radius*radius {
+					relevant = append(relevant, entry.entity)
+				}
+			}
+		}
+	}
+	return relevant
+}
+
+func (grid *InterestGrid) cellFor(pos vector.Two) [2]int {
+	return [2]int{
+		int(math.Floor(pos.X / grid.interest.CellSize)),
+		int(math.Floor(pos.Y / grid.interest.CellSize)),
+	}
+}
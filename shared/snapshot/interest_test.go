@@ -0,0 +1,135 @@
+package snapshot_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+func spawnAt(t *testing.T, sto warehouse.Storage, x, y float64) warehouse.Entity {
+	t.Helper()
+	en, err := scenes.NewPlayer(x, y, sto)
+	if err != nil {
+		t.Fatalf("spawning player at %v,%v: %v", x, y, err)
+	}
+	return en
+}
+
+func ids(entities []warehouse.Entity) []uint32 {
+	out := []uint32{}
+	for _, en := range entities {
+		out = append(out, uint32(en.ID()))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestInterestGridRelevant(t *testing.T) {
+	sto := newStorage()
+	viewer := spawnAt(t, sto, 1000, 1000)
+	near := spawnAt(t, sto, 1250, 1000)
+	diagonal := spawnAt(t, sto, 1200, 1200)
+	far := spawnAt(t, sto, 1400, 1000)
+	behind := spawnAt(t, sto, 600, 1000)
+	all := []warehouse.Entity{viewer, near, diagonal, far, behind}
+
+	tests := []struct {
+		name     string
+		interest snapshot.Interest
+		want     []warehouse.Entity
+	}{
+		{
+			name:     "within the radius",
+			interest: snapshot.Interest{Radius: 300, CellSize: 128},
+			want:     []warehouse.Entity{viewer, near, diagonal},
+		},
+		{
+			name:     "cells larger than the radius",
+			interest: snapshot.Interest{Radius: 300, CellSize: 4096},
+			want:     []warehouse.Entity{viewer, near, diagonal},
+		},
+		{
+			name: "always relevant",
+			interest: snapshot.Interest{Radius: 300, CellSize: 128, AlwaysRelevant: func(en warehouse.Entity) bool {
+				return en.ID() == far.ID()
+			}},
+			want: []warehouse.Entity{viewer, near, diagonal, far},
+		},
+		{
+			name:     "no radius sends everything",
+			interest: snapshot.Interest{},
+			want:     all,
+		},
+	}
+
+	at := spatial.Components.Position.GetFromEntity(viewer).Two
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snapshot.NewInterestGrid(tt.interest, all).Relevant(at)
+			if !slices.Equal(ids(got), ids(tt.want)) {
+				t.Errorf("relevant = %v, want %v", ids(got), ids(tt.want))
+			}
+		})
+	}
+}
+
+// TestRecipientStreamFollowsInterest moves a player out of a recipient's area
+// and back, it must be removed by a delta and spawned again
+func TestRecipientStreamFollowsInterest(t *testing.T) {
+	sto := newStorage()
+	viewer := spawnAt(t, sto, 1000, 1000)
+	other := spawnAt(t, sto, 1100, 1000)
+	players := []warehouse.Entity{viewer, other}
+	interest := snapshot.Interest{Radius: 300}
+
+	enc := snapshot.NewRecipientEncoder(snapshot.CodecBinary, "Scene1")
+	var decoder snapshot.SceneDecoders
+
+	frameAt := func(tick int, x float64) snapshot.Frame {
+		t.Helper()
+		spatial.Components.Position.GetFromEntity(other).Two = vector.Two{X: x, Y: 1000}
+		relevant := snapshot.NewInterestGrid(interest, players).Relevant(vector.Two{X: 1000, Y: 1000})
+		data, err := enc.Encode(tick, relevant, client.Components.SpriteBundle, client.Components.SoundBundle)
+		if err != nil {
+			t.Fatalf("tick %d: encode: %v", tick, err)
+		}
+		frame, err := decoder.Decode(data)
+		if err != nil {
+			t.Fatalf("tick %d: decode: %v", tick, err)
+		}
+		return frame
+	}
+
+	id := uint32(other.ID())
+
+	frame := frameAt(1, 1100)
+	if !frame.Keyframe || !frame.Contains(id) {
+		t.Fatalf("tick 1: want a keyframe holding the nearby player, got keyframe %v", frame.Keyframe)
+	}
+
+	frame = frameAt(2, 2000)
+	if frame.Keyframe {
+		t.Fatalf("tick 2: leaving the area sent a keyframe")
+	}
+	if !slices.Contains(frame.Removed, id) || frame.Contains(id) {
+		t.Errorf("tick 2: removed %v, want the player that left", frame.Removed)
+	}
+
+	frame = frameAt(3, 1200)
+	if frame.Keyframe {
+		t.Fatalf("tick 3: entering the area sent a keyframe")
+	}
+	spawned := false
+	for _, se := range frame.Spawns {
+		spawned = spawned || uint32(se.ID) == id
+	}
+	if !spawned {
+		t.Errorf("tick 3: the player entering the area was not spawned")
+	}
+}
@@ -0,0 +1,241 @@
+package snapshot_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/table"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+const roundTripTicks = 12
+
+// TestCodecsProduceIdenticalClientStorage encodes the same server stream with
+// both codecs, including spawns, removals and a recycled ID, and checks the
+// client storage rebuilt from each matches the server after every tick
+func TestCodecsProduceIdenticalClientStorage(t *testing.T) {
+	server := newStorage()
+	jsonEnc := snapshot.NewEncoder(snapshot.CodecJSON, "Scene1")
+	binaryEnc := snapshot.NewEncoder(snapshot.CodecBinary, "Scene1")
+
+	var (
+		jsonFrames   [][]byte
+		binaryFrames [][]byte
+		expected     [][]snapshot.EntityState
+		players      []warehouse.Entity
+	)
+
+	for tick := 1; tick <= roundTripTicks; tick++ {
+		switch tick {
+		case 1, 2, 5:
+			players = append(players, newPlayer(t, server, tick))
+		case 7:
+			// Removal of an entity in the middle of the stream
+			destroy(t, server, players[0])
+			players = players[1:]
+		case 9:
+			// Removal and spawn in one frame, the ID is likely recycled
+			destroy(t, server, players[0])
+			players = append(players[1:], newPlayer(t, server, tick))
+		}
+
+		for i, en := range players {
+			err := snapshot.Apply(en, scriptedState(en, tick, i))
+			if err != nil {
+				t.Fatalf("tick %d: applying state: %v", tick, err)
+			}
+		}
+
+		expected = append(expected, captureAll(server))
+
+		data, err := jsonEnc.Encode(tick, players, client.Components.SpriteBundle, client.Components.SoundBundle)
+		if err != nil {
+			t.Fatalf("tick %d: json encode: %v", tick, err)
+		}
+		jsonFrames = append(jsonFrames, data)
+
+		data, err = binaryEnc.Encode(tick, players, client.Components.SpriteBundle, client.Components.SoundBundle)
+		if err != nil {
+			t.Fatalf("tick %d: binary encode: %v", tick, err)
+		}
+		binaryFrames = append(binaryFrames, data)
+	}
+
+	// Client entities are forced to the server's IDs, release them first
+	for _, en := range players {
+		destroy(t, server, en)
+	}
+
+	jsonStates := replayStream(t, "json", jsonFrames)
+	binaryStates := replayStream(t, "binary", binaryFrames)
+
+	for i := range expected {
+		tick := i + 1
+		if !reflect.DeepEqual(jsonStates[i], expected[i]) {
+			t.Errorf("tick %d: json client storage\n got %+v\nwant %+v", tick, jsonStates[i], expected[i])
+		}
+		if !reflect.DeepEqual(binaryStates[i], expected[i]) {
+			t.Errorf("tick %d: binary client storage\n got %+v\nwant %+v", tick, binaryStates[i], expected[i])
+		}
+	}
+}
+
+// replayStream applies frames to a fresh client storage the way the networked
+// client does and captures the storage after each one
+func replayStream(t *testing.T, name string, frames [][]byte) [][]snapshot.EntityState {
+	t.Helper()
+
+	sto := newStorage()
+	var decoder snapshot.SceneDecoders
+	states := [][]snapshot.EntityState{}
+
+	for i, data := range frames {
+		frame, err := decoder.Decode(data)
+		if err != nil {
+			t.Fatalf("%s tick %d: decode: %v", name, i+1, err)
+		}
+		err = applyFrame(sto, frame)
+		if err != nil {
+			t.Fatalf("%s tick %d: apply: %v", name, i+1, err)
+		}
+		states = append(states, captureAll(sto))
+	}
+
+	err := sto.DestroyEntities(players(sto)...)
+	if err != nil {
+		t.Fatalf("%s: releasing client entities: %v", name, err)
+	}
+	return states
+}
+
+func applyFrame(sto warehouse.Storage, frame snapshot.Frame) error {
+	if !frame.Keyframe {
+		removed := []warehouse.Entity{}
+		for _, id := range frame.Removed {
+			en, err := sto.Entity(int(id))
+			if err == nil {
+				removed = append(removed, en)
+			}
+		}
+		err := sto.DestroyEntities(removed...)
+		if err != nil {
+			return err
+		}
+	}
+
+	seen := map[uint32]bool{}
+	for _, se := range frame.Spawns {
+		en, err := sto.ForceSerializedEntity(se)
+		if err != nil {
+			return err
+		}
+		err = se.SetValue(en)
+		if err != nil {
+			return err
+		}
+		seen[uint32(se.ID)] = true
+	}
+
+	for _, state := range frame.Snapshot.Entities {
+		seen[state.ID] = true
+		en, err := sto.Entity(int(state.ID))
+		if err != nil {
+			return err
+		}
+		err = snapshot.Apply(en, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if frame.Keyframe {
+		purge := []warehouse.Entity{}
+		for _, en := range players(sto) {
+			if !seen[uint32(en.ID())] {
+				purge = append(purge, en)
+			}
+		}
+		return sto.DestroyEntities(purge...)
+	}
+	return nil
+}
+
+// scriptedState gives every player a distinct state per tick, switching the
+// optional ground and wall components on and off
+func scriptedState(en warehouse.Entity, tick, index int) snapshot.EntityState {
+	f := float64(tick*10 + index)
+	state := snapshot.EntityState{
+		ID:        uint32(en.ID()),
+		Position:  vector.Two{X: 100 + f*1.5, Y: 200 - f*0.25},
+		Velocity:  vector.Two{X: f / 3, Y: -f / 7},
+		Direction: 1,
+		Jump:      components.JumpState{LastJump: tick - 1, LastHeld: tick, LastPress: tick - 4, AirJumps: index % 2},
+		Dash:      components.DashState{LastDash: tick - 2, LastHeld: tick, Direction: -1},
+		Crouch:    components.CrouchState{LastHeld: tick - 3, StandHeight: 58},
+	}
+	if tick%2 == 0 {
+		state.Direction = -1
+	}
+	if (tick+index)%3 != 0 {
+		state.Grounded = true
+		state.OnGround = components.OnGround{
+			LastTouch:   tick,
+			Landed:      tick - index,
+			SlopeNormal: vector.Two{X: 0, Y: -1},
+			PlatformVel: vector.Two{X: f / 11},
+		}
+	}
+	if (tick+index)%4 == 0 {
+		state.TouchingWall = true
+		state.OnWall = components.OnWall{LastTouch: tick, Grabbed: tick - 1, Side: 1}
+	}
+	return state
+}
+
+func newStorage() warehouse.Storage {
+	return warehouse.Factory.NewStorage(table.Factory.NewSchema())
+}
+
+func newPlayer(t *testing.T, sto warehouse.Storage, tick int) warehouse.Entity {
+	t.Helper()
+	en, err := scenes.NewPlayer(float64(tick), 0, sto)
+	if err != nil {
+		t.Fatalf("tick %d: spawning player: %v", tick, err)
+	}
+	return en
+}
+
+func destroy(t *testing.T, sto warehouse.Storage, en warehouse.Entity) {
+	t.Helper()
+	err := sto.DestroyEntities(en)
+	if err != nil {
+		t.Fatalf("destroying %d: %v", en.ID(), err)
+	}
+}
+
+func players(sto warehouse.Storage) []warehouse.Entity {
+	entities := []warehouse.Entity{}
+	cursor := warehouse.Factory.NewCursor(blueprint.Queries.ActionBuffer, sto)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
+		if err == nil {
+			entities = append(entities, en)
+		}
+	}
+	return entities
+}
+
+func captureAll(sto warehouse.Storage) []snapshot.EntityState {
+	states := []snapshot.EntityState{}
+	for _, en := range players(sto) {
+		states = append(states, snapshot.Capture(en))
+	}
+	snapshot.SortStates(states)
+	return states
+}
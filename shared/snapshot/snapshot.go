@@ -0,0 +1,124 @@
+// Package snapshot implements the compact network snapshot format shared by the
+// server and the networked client.
+//
+// A snapshot holds the replicated player state (position, velocity, direction,
+// jump, dash, crouch, ground and wall state) for every ActionBuffer entity in a scene. The server
+// encodes snapshots with an Encoder and the client rebuilds them with a Decoder.
+package snapshot
+
+import (
+	"sort"
+
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+)
+
+// EntityState is the fixed set of replicated fields for a single player entity
+type EntityState struct {
+	ID           uint32
+	Position     vector.Two
+	Velocity     vector.Two
+	Direction    int8
+	Jump         components.JumpState
+	Dash         components.DashState
+	Crouch       components.CrouchState
+	Grounded     bool
+	OnGround     components.OnGround
+	TouchingWall bool
+	OnWall       components.OnWall
+}
+
+// Snapshot is the authoritative state of a scene at a given tick
+type Snapshot struct {
+	Tick     int
+	Entities []EntityState
+}
+
+// Find returns the state for the given entity id
+func (s Snapshot) Find(id uint32) (EntityState, bool) {
+	i := sort.Search(len(s.Entities), func(i int) bool { return s.Entities[i].ID >= id })
+	if i < len(s.Entities) && s.Entities[i].ID == id {
+		return s.Entities[i], true
+	}
+	return EntityState{}, false
+}
+
+// Capture reads the replicated state from a player entity
+func Capture(en warehouse.Entity) EntityState {
+	pos := spatial.Components.Position.GetFromEntity(en)
+	dyn := motion.Components.Dynamics.GetFromEntity(en)
+	dir := spatial.Components.Direction.GetFromEntity(en)
+	jumpState := components.JumpStateComponent.GetFromEntity(en)
+
+	state := EntityState{
+		ID:        uint32(en.ID()),
+		Position:  pos.Two,
+		Velocity:  dyn.Vel,
+		Direction: int8(dir.AsFloat()),
+		Jump:      *jumpState,
+		Dash:      *components.DashStateComponent.GetFromEntity(en),
+		Crouch:    *components.CrouchStateComponent.GetFromEntity(en),
+	}
+
+	if en.Table().Contains(components.OnGroundComponent) {
+		state.Grounded = true
+		state.OnGround = *components.OnGroundComponent.GetFromEntity(en)
+	}
+	if en.Table().Contains(components.OnWallComponent) {
+		state.TouchingWall = true
+		state.OnWall = *components.OnWallComponent.GetFromEntity(en)
+	}
+	return state
+}
+
+// Apply writes the replicated state onto an existing entity
+func Apply(en warehouse.Entity, state EntityState) error {
+	pos := spatial.Components.Position.GetFromEntity(en)
+	pos.Two = state.Position
+
+	dyn := motion.Components.Dynamics.GetFromEntity(en)
+	dyn.Vel = state.Velocity
+
+	dir := spatial.Components.Direction.GetFromEntity(en)
+	if state.Direction < 0 {
+		dir.SetLeft()
+	} else {
+		dir.SetRight()
+	}
+
+	*components.JumpStateComponent.GetFromEntity(en) = state.Jump
+	*components.DashStateComponent.GetFromEntity(en) = state.Dash
+	*components.CrouchStateComponent.GetFromEntity(en) = state.Crouch
+	coresystems.CrouchShape(spatial.Components.Shape.GetFromEntity(en), state.Crouch)
+
+	err := applyOptional(en, components.OnGroundComponent, state.Grounded, state.OnGround)
+	if err != nil {
+		return err
+	}
+	return applyOptional(en, components.OnWallComponent, state.TouchingWall, state.OnWall)
+}
+
+// applyOptional sets, adds or removes a component that players only have some
+// of the time
+func applyOptional[T any](en warehouse.Entity, component warehouse.AccessibleComponent[T], present bool, value T) error {
+	has := en.Table().Contains(component)
+
+	switch {
+	case present && has:
+		*component.GetFromEntity(en) = value
+	case present:
+		return en.AddComponentWithValue(component, value)
+	case has:
+		return en.RemoveComponent(component)
+	}
+	return nil
+}
+
+// SortStates orders states by entity id so snapshots are deterministic
+func SortStates(states []EntityState) {
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+}
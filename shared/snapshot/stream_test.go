@@ -0,0 +1,200 @@
+package snapshot_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/snapshot"
+)
+
+// lossyClient decodes the frames it receives and acknowledges each one
+type lossyClient struct {
+	t       *testing.T
+	enc     *snapshot.Encoder
+	decoder snapshot.SceneDecoders
+	players []warehouse.Entity
+	sto     warehouse.Storage
+}
+
+func newLossyClient(t *testing.T) *lossyClient {
+	sto := newStorage()
+	c := &lossyClient{t: t, enc: snapshot.NewRecipientEncoder(snapshot.CodecBinary, "Scene1"), sto: sto}
+	for i := range 3 {
+		c.players = append(c.players, newPlayer(t, sto, i+1))
+	}
+	return c
+}
+
+// send encodes tick and, unless lost, decodes it, checks it against the server
+// and acknowledges it
+func (c *lossyClient) send(tick int, lost bool) (snapshot.Frame, error) {
+	c.t.Helper()
+	for i, en := range c.players {
+		err := snapshot.Apply(en, scriptedState(en, tick, i))
+		if err != nil {
+			c.t.Fatalf("tick %d: applying state: %v", tick, err)
+		}
+	}
+	data, err := c.enc.Encode(tick, c.players, client.Components.SpriteBundle, client.Components.SoundBundle)
+	if err != nil {
+		c.t.Fatalf("tick %d: encode: %v", tick, err)
+	}
+	if lost {
+		return snapshot.Frame{}, nil
+	}
+
+	frame, err := c.decoder.Decode(data)
+	if err != nil {
+		return frame, err
+	}
+	if want := captureAll(c.sto); !reflect.DeepEqual(frame.Snapshot.Entities, want) {
+		c.t.Errorf("tick %d: decoded\n got %+v\nwant %+v", tick, frame.Snapshot.Entities, want)
+	}
+	c.enc.Acknowledge(tick)
+	return frame, nil
+}
+
+func TestRecipientStreamDeltasAgainstAcks(t *testing.T) {
+	c := newLossyClient(t)
+
+	_, err := c.send(1, false)
+	if err != nil {
+		t.Fatalf("keyframe: %v", err)
+	}
+	// Frames the client never got are not used as its baseline
+	c.send(2, true)
+	c.send(3, true)
+	frame, err := c.send(4, false)
+	if err != nil {
+		t.Fatalf("delta after lost frames: %v", err)
+	}
+	if frame.Keyframe {
+		t.Errorf("recovering from lost frames needed a keyframe")
+	}
+}
+
+func TestRecipientStreamKeyframesStaleAcks(t *testing.T) {
+	c := newLossyClient(t)
+	c.enc.KeyframeInterval = 0
+
+	_, err := c.send(1, false)
+	if err != nil {
+		t.Fatalf("keyframe: %v", err)
+	}
+	for tick := 2; tick < 2+snapshot.MAX_BASELINES; tick++ {
+		c.send(tick, true)
+	}
+	frame, err := c.send(2+snapshot.MAX_BASELINES, false)
+	if err != nil {
+		t.Fatalf("frame after a stale ack: %v", err)
+	}
+	if !frame.Keyframe {
+		t.Errorf("an ack older than the kept baselines was sent a delta")
+	}
+}
+
+func TestBroadcastStreamKeyframesPeriodically(t *testing.T) {
+	sto := newStorage()
+	players := []warehouse.Entity{newPlayer(t, sto, 1)}
+	enc := snapshot.NewEncoder(snapshot.CodecBinary, "Scene1")
+	var decoder snapshot.Decoder
+
+	keyframes := 0
+	for tick := 1; tick <= 3*snapshot.DEFAULT_KEYFRAME_INTERVAL; tick++ {
+		data, err := enc.Encode(tick, players)
+		if err != nil {
+			t.Fatalf("tick %d: encode: %v", tick, err)
+		}
+		frame, err := decoder.Decode(data)
+		if err != nil {
+			t.Fatalf("tick %d: decode: %v", tick, err)
+		}
+		if frame.Keyframe {
+			keyframes++
+		}
+	}
+	if keyframes < 3 {
+		t.Errorf("got %d keyframes in %d ticks, want one every %d", keyframes, 3*snapshot.DEFAULT_KEYFRAME_INTERVAL, snapshot.DEFAULT_KEYFRAME_INTERVAL)
+	}
+}
+
+func TestAckOf(t *testing.T) {
+	action := snapshot.Ack{Scene: "Scene1", Tick: 42}.Action(7)
+	if tick, ok := snapshot.AckOf("Scene1", action); !ok || tick != 42 {
+		t.Errorf("AckOf(Scene1) = %d, %v, want 42, true", tick, ok)
+	}
+	if _, ok := snapshot.AckOf("Scene2", action); ok {
+		t.Errorf("an ack of Scene1 was read as one of Scene2")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred snapshot.Codec
+		offer     []snapshot.Codec
+		want      snapshot.Codec
+	}{
+		{"binary offered", snapshot.CodecBinary, []snapshot.Codec{snapshot.CodecJSON, snapshot.CodecBinary}, snapshot.CodecBinary},
+		{"json only", snapshot.CodecBinary, []snapshot.Codec{snapshot.CodecJSON}, snapshot.CodecJSON},
+		{"nothing offered", snapshot.CodecBinary, nil, snapshot.CodecJSON},
+		{"server prefers json", snapshot.CodecJSON, []snapshot.Codec{snapshot.CodecBinary}, snapshot.CodecJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snapshot.Negotiate(tt.preferred, snapshot.OfferAction(1, tt.offer...))
+			if got != tt.want {
+				t.Errorf("Negotiate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFrameHeader(t *testing.T) {
+	data, err := snapshot.EncodeJSON(9, "Scene2", 30, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoder snapshot.Decoder
+	frame, err := decoder.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Scene != "Scene2" || frame.TickRate != 30 || frame.Snapshot.Tick != 9 {
+		t.Errorf("decoded scene %q, tick rate %d, tick %d, want Scene2, 30, 9", frame.Scene, frame.TickRate, frame.Snapshot.Tick)
+	}
+
+	_, err = decoder.Decode([]byte("{not json"))
+	if err == nil || errors.Is(err, snapshot.ErrMissingBaseline) {
+		t.Errorf("malformed JSON decoded with %v", err)
+	}
+}
+
+func TestJSONFrameChecksums(t *testing.T) {
+	sto := newStorage()
+	players := []warehouse.Entity{newPlayer(t, sto, 1), newPlayer(t, sto, 2)}
+	enc := snapshot.NewEncoder(snapshot.CodecJSON, "Scene1")
+	var decoder snapshot.Decoder
+
+	data, err := enc.Encode(5, players, client.Components.SpriteBundle, client.Components.SoundBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := decoder.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := captureAll(sto); !reflect.DeepEqual(frame.Snapshot.Entities, want) {
+		t.Errorf("decoded\n got %+v\nwant %+v", frame.Snapshot.Entities, want)
+	}
+	for _, state := range frame.Snapshot.Entities {
+		sum, ok := frame.Checksum(state.ID)
+		if !ok || sum != state.Checksum() {
+			t.Errorf("entity %d: checksum %d, %v, want %d", state.ID, sum, ok, state.Checksum())
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package spawn picks where new players enter a scene
+//
+// Scenes can hold any number of PlayerStart points (PlayerSpawn components),
+// each optionally tied to a team and weighted. A Policy chooses between the
+// points open to the player, the server and the standalone client use the same
+// policies so a scene plays the same either way
+package spawn
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+)
+
+const (
+	ROUND_ROBIN    = "round-robin"
+	RANDOM         = "random"
+	LEAST_CROWDED  = "least-crowded"
+	FURTHEST       = "furthest-from-others"
+	DEFAULT_POLICY = ROUND_ROBIN
+
+	// CROWD_RADIUS is how close a player has to be to count against a point
+	// for the least crowded policy
+	CROWD_RADIUS = 160.0
+)
+
+// Policy chooses a spawn point for a new player
+type Policy interface {
+	// Choose returns the index of one of points, occupied holds the positions
+	// of the players already in the scene. points is never empty
+	Choose(points []components.PlayerSpawn, occupied []vector.Two) int
+}
+
+// Default is used when no policy was configured
+var Default Policy = &RoundRobin{}
+
+var policies = map[string]func() Policy{
+	ROUND_ROBIN:   func() Policy { return &RoundRobin{} },
+	RANDOM:        func() Policy { return Random{} },
+	LEAST_CROWDED: func() Policy { return LeastCrowded{Radius: CROWD_RADIUS} },
+	FURTHEST:      func() Policy { return FurthestFromOthers{} },
+}
+
+// New creates a policy by name
+func New(name string) (Policy, error) {
+	create, ok := policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown spawn policy %q (expected %s)", name, strings.Join(Names(), ", "))
+	}
+	return create(), nil
+}
+
+// Names lists the available policies
+func Names() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RoundRobin hands out the points in order
+type RoundRobin struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (p *RoundRobin) Choose(points []components.PlayerSpawn, occupied []vector.Two) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.next % len(points)
+	p.next++
+	return i
+}
+
+// Random picks a point at random, in proportion to its weight
+type Random struct{}
+
+func (Random) Choose(points []components.PlayerSpawn, occupied []vector.Two) int {
+	total := 0.0
+	for _, point := range points {
+		total += max(point.Weight, 0)
+	}
+	if total <= 0 {
+		return rand.Intn(len(points))
+	}
+
+	r := rand.Float64() * total
+	for i, point := range points {
+		r -= max(point.Weight, 0)
+		if r < 0 {
+			return i
+		}
+	}
+	return len(points) - 1
+}
+
+// LeastCrowded picks the point with the fewest players within Radius, ties go
+// to the heavier point
+type LeastCrowded struct {
+	Radius float64
+}
+
+func (p LeastCrowded) Choose(points []components.PlayerSpawn, occupied []vector.Two) int {
+	best, bestCount := 0, math.MaxInt
+	for i, point := range points {
+		count := 0
+		for _, pos := range occupied {
+			if distance(point, pos) <= p.Radius {
+				count++
+			}
+		}
+		if count < bestCount || (count == bestCount && point.Weight > points[best].Weight) {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+// FurthestFromOthers picks the point whose nearest player is furthest away,
+// ties go to the heavier point
+type FurthestFromOthers struct{}
+
+func (FurthestFromOthers) Choose(points []components.PlayerSpawn, occupied []vector.Two) int {
+	best, bestDist := 0, -1.0
+	for i, point := range points {
+		nearest := math.Inf(1)
+		for _, pos := range occupied {
+			nearest = min(nearest, distance(point, pos))
+		}
+		if nearest > bestDist || (nearest == bestDist && point.Weight > points[best].Weight) {
+			best, bestDist = i, nearest
+		}
+	}
+	return best
+}
+
+func distance(point components.PlayerSpawn, pos vector.Two) float64 {
+	return math.Hypot(point.X-pos.X, point.Y-pos.Y)
+}
+
+// ForTeam keeps the points open to team, points without a team are open to
+// everyone. A player without a team can use any point, and if no point is
+// open to team every point is returned
+func ForTeam(points []components.PlayerSpawn, team string) []components.PlayerSpawn {
+	if team == "" {
+		return points
+	}
+	open := []components.PlayerSpawn{}
+	for _, point := range points {
+		if point.Team == "" || point.Team == team {
+			open = append(open, point)
+		}
+	}
+	if len(open) == 0 {
+		return points
+	}
+	return open
+}
+
+// Teams assigns players to Names in turn, with no names players get no team
+type Teams struct {
+	Names []string
+
+	mu   sync.Mutex
+	next int
+}
+
+// DefaultTeams is used when no teams were configured
+var DefaultTeams = &Teams{}
+
+// Next returns the team of the next player to join
+func (t *Teams) Next() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	team := t.Of(t.next)
+	t.next++
+	return team
+}
+
+// Of returns the team of the nth player (counting from 0), for local players
+// that keep their index
+func (t *Teams) Of(n int) string {
+	if len(t.Names) == 0 {
+		return ""
+	}
+	return t.Names[n%len(t.Names)]
+}
+
+var pointsQuery = warehouse.Factory.NewQuery().And(components.PlayerSpawnComponent)
+
+var occupiedQuery = warehouse.Factory.NewQuery().And(blueprint.Queries.ActionBuffer, spatial.Components.Position)
+
+// Points lists the spawn points in a storage
+func Points(sto warehouse.Storage) []components.PlayerSpawn {
+	points := []components.PlayerSpawn{}
+	cursor := warehouse.Factory.NewCursor(pointsQuery, sto)
+	for range cursor.Next() {
+		points = append(points, *components.PlayerSpawnComponent.GetFromCursor(cursor))
+	}
+	return points
+}
+
+// Occupied lists the positions of the players in a storage
+func Occupied(sto warehouse.Storage) []vector.Two {
+	positions := []vector.Two{}
+	cursor := warehouse.Factory.NewCursor(occupiedQuery, sto)
+	for range cursor.Next() {
+		positions = append(positions, spatial.Components.Position.GetFromCursor(cursor).Two)
+	}
+	return positions
+}
+
+// Select picks the spawn point for a new player of team, a nil policy uses
+// Default. It reports false when the storage has no spawn points
+func Select(sto warehouse.Storage, policy Policy, team string) (components.PlayerSpawn, bool) {
+	points := ForTeam(Points(sto), team)
+	if len(points) == 0 {
+		return components.PlayerSpawn{}, false
+	}
+	if policy == nil {
+		policy = Default
+	}
+	return points[policy.Choose(points, Occupied(sto))], true
+}
@@ -0,0 +1,167 @@
+package spawn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
+	"github.com/TheBitDrifter/netcode_example/shared/headless"
+	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
+)
+
+const players = 600
+
+// newSim builds a scene holding only the given spawn points
+func newSim(t *testing.T, points ...components.PlayerSpawn) *headless.Simulation {
+	t.Helper()
+	plan := func(width, height int, sto warehouse.Storage) error {
+		for _, point := range points {
+			_, err := scenes.NewPlayerSpawn(sto, point)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	sim, err := headless.New("spawn", 4000, 800, plan, coresystems.NewCoreSystems(coresystems.DefaultPhysics()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+// spread returns n points further apart than the crowd radius
+func spread(n int) []components.PlayerSpawn {
+	points := make([]components.PlayerSpawn, n)
+	for i := range points {
+		points[i] = components.PlayerSpawn{X: 200 + float64(i)*3*spawn.CROWD_RADIUS, Y: 100, Weight: 1}
+	}
+	return points
+}
+
+// spawnMany spawns n players without a team and counts them per point X
+func spawnMany(t *testing.T, sim *headless.Simulation, policy spawn.Policy, n int) map[float64]int {
+	t.Helper()
+	counts := map[float64]int{}
+	for range n {
+		player, err := sim.SpawnPlayerWith(policy, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[sim.State(player).Position.X]++
+	}
+	return counts
+}
+
+func TestRoundRobinDistribution(t *testing.T) {
+	points := spread(4)
+	counts := spawnMany(t, newSim(t, points...), &spawn.RoundRobin{}, players)
+
+	for _, point := range points {
+		if got := counts[point.X]; got != players/len(points) {
+			t.Errorf("point at x %v got %d players, want %d", point.X, got, players/len(points))
+		}
+	}
+}
+
+func TestRandomDistribution(t *testing.T) {
+	points := spread(4)
+	weights := []float64{1, 2, 3, 0}
+	for i := range points {
+		points[i].Weight = weights[i]
+	}
+	const n = 6000
+	counts := spawnMany(t, newSim(t, points...), spawn.Random{}, n)
+
+	for i, point := range points {
+		want := n * point.Weight / 6
+		got := float64(counts[point.X])
+		if math.Abs(got-want) > 0.15*want {
+			t.Errorf("point %d (weight %v) got %v players, want about %v", i, point.Weight, got, want)
+		}
+	}
+	if counts[points[3].X] != 0 {
+		t.Errorf("zero weight point got %d players", counts[points[3].X])
+	}
+}
+
+func TestLeastCrowdedDistribution(t *testing.T) {
+	points := spread(3)
+	policy := spawn.LeastCrowded{Radius: spawn.CROWD_RADIUS}
+	counts := spawnMany(t, newSim(t, points...), policy, players)
+
+	for _, point := range points {
+		if got := counts[point.X]; got != players/len(points) {
+			t.Errorf("point at x %v got %d players, want %d", point.X, got, players/len(points))
+		}
+	}
+}
+
+func TestFurthestFromOthersDistribution(t *testing.T) {
+	points := spread(5)
+	sim := newSim(t, points...)
+
+	// Every point is used once before any is shared
+	counts := spawnMany(t, sim, spawn.FurthestFromOthers{}, len(points))
+	for _, point := range points {
+		if counts[point.X] != 1 {
+			t.Errorf("point at x %v got %d of the first %d players, want 1", point.X, counts[point.X], len(points))
+		}
+	}
+
+	// Every point is as far from its nearest player, the heaviest wins the tie
+	points[2].Weight = 2
+	counts = spawnMany(t, newSim(t, points...), spawn.FurthestFromOthers{}, players)
+	if counts[points[2].X] != players-len(points)+1 {
+		t.Errorf("heaviest point got %d players, want %d", counts[points[2].X], players-len(points)+1)
+	}
+}
+
+func TestTeamsDistribution(t *testing.T) {
+	points := spread(5)
+	points[0].Team = "red"
+	points[1].Team = "red"
+	points[2].Team = "blue"
+	points[3].Team = "blue"
+	// points[4] is open to everyone
+
+	sim := newSim(t, points...)
+	teams := &spawn.Teams{Names: []string{"red", "blue"}}
+	policy := &spawn.RoundRobin{}
+
+	team := map[float64]string{}
+	for _, point := range points {
+		team[point.X] = point.Team
+	}
+	perTeam := map[string]int{}
+	for range players {
+		name := teams.Next()
+		player, err := sim.SpawnPlayerWith(policy, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := sim.State(player).Position.X
+		if got := team[x]; got != "" && got != name {
+			t.Fatalf("%s player spawned at a %s point", name, got)
+		}
+		perTeam[name]++
+	}
+	if perTeam["red"] != players/2 || perTeam["blue"] != players/2 {
+		t.Errorf("teams got %v, want an even split", perTeam)
+	}
+}
+
+func TestTeamsOf(t *testing.T) {
+	teams := &spawn.Teams{Names: []string{"red", "blue", "green"}}
+	for i, want := range []string{"red", "blue", "green", "red"} {
+		if got := teams.Of(i); got != want {
+			t.Errorf("Of(%d) = %q, want %q", i, got, want)
+		}
+	}
+	if got := (&spawn.Teams{}).Of(3); got != "" {
+		t.Errorf("no teams gave %q, want no team", got)
+	}
+}
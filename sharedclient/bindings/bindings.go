@@ -0,0 +1,273 @@
+package bindings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TheBitDrifter/bappa/coldbrew"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Bindings maps action names (see actions.ByName) to the keys and gamepad
+// buttons that trigger them, an action can have any number of each
+type Bindings struct {
+	Keyboard map[string][]ebiten.Key `json:"keyboard"`
+	Gamepad  map[string][]Button     `json:"gamepad"`
+	Pad      int                     `json:"pad"` // Gamepad index the buttons are read from
+}
+
+// MAX_PLAYERS is how many local players have default layouts
+const MAX_PLAYERS = 4
+
+// Default keyboard layouts per local player, each on its own part of the keyboard
+var keyboardLayouts = [MAX_PLAYERS]map[string][]ebiten.Key{
+	{
+		"jump":   {ebiten.KeySpace, ebiten.KeyW},
+		"left":   {ebiten.KeyA},
+		"right":  {ebiten.KeyD},
+		"down":   {ebiten.KeyS},
+		"dash":   {ebiten.KeyShiftLeft},
+		"crouch": {ebiten.KeyC},
+	},
+	{
+		"jump":   {ebiten.KeyArrowUp},
+		"left":   {ebiten.KeyArrowLeft},
+		"right":  {ebiten.KeyArrowRight},
+		"down":   {ebiten.KeyArrowDown},
+		"dash":   {ebiten.KeyShiftRight},
+		"crouch": {ebiten.KeyEnter},
+	},
+	{
+		"jump":   {ebiten.KeyI},
+		"left":   {ebiten.KeyJ},
+		"right":  {ebiten.KeyL},
+		"down":   {ebiten.KeyK},
+		"dash":   {ebiten.KeyU},
+		"crouch": {ebiten.KeyO},
+	},
+	{
+		"jump":   {ebiten.KeyNumpad8},
+		"left":   {ebiten.KeyNumpad4},
+		"right":  {ebiten.KeyNumpad6},
+		"down":   {ebiten.KeyNumpad5},
+		"dash":   {ebiten.KeyNumpad0},
+		"crouch": {ebiten.KeyNumpadEnter},
+	},
+}
+
+// Default returns the WASD/Space layout with a standard gamepad layout alongside it
+func Default() *Bindings {
+	return PlayerDefault(0)
+}
+
+// PlayerDefault returns the default layout for a local player, players past the
+// first get another part of the keyboard and the gamepad matching their index
+func PlayerDefault(player int) *Bindings {
+	b := &Bindings{
+		Keyboard: map[string][]ebiten.Key{},
+		Gamepad: map[string][]Button{
+			"jump":   {Button(ebiten.StandardGamepadButtonRightBottom)},
+			"left":   {Button(ebiten.StandardGamepadButtonLeftLeft)},
+			"right":  {Button(ebiten.StandardGamepadButtonLeftRight)},
+			"down":   {Button(ebiten.StandardGamepadButtonLeftBottom)},
+			"dash":   {Button(ebiten.StandardGamepadButtonRightLeft)},
+			"crouch": {Button(ebiten.StandardGamepadButtonFrontBottomRight)},
+		},
+		Pad: player,
+	}
+	for name, keys := range keyboardLayouts[player%MAX_PLAYERS] {
+		b.Keyboard[name] = append([]ebiten.Key(nil), keys...)
+	}
+	return b
+}
+
+// PlayerPath returns the bindings file for a local player, the first player
+// uses path as is and the others get a numbered file next to it
+// (bindings.json -> bindings.p2.json)
+func PlayerPath(path string, player int) string {
+	if player == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.p%d%s", strings.TrimSuffix(path, ext), player+1, ext)
+}
+
+// Load reads bindings from a JSON file, falling back to the defaults when the
+// file doesn't exist yet
+func Load(path string) (*Bindings, error) {
+	return LoadPlayer(path, 0)
+}
+
+// LoadPlayer is Load with the local player's defaults as the fallback
+func LoadPlayer(path string, player int) (*Bindings, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return PlayerDefault(player), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bindings{}
+	err = json.Unmarshal(data, b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	err = b.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the bindings as indented JSON so they stay hand editable
+func (b *Bindings) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Validate reports every unknown action name and any out of range pad index
+func (b *Bindings) Validate() error {
+	var errs []error
+	for _, name := range sortedKeys(b.Keyboard) {
+		if _, ok := actions.ByName[name]; !ok {
+			errs = append(errs, fmt.Errorf("keyboard: unknown action %q", name))
+		}
+	}
+	for _, name := range sortedKeys(b.Gamepad) {
+		if _, ok := actions.ByName[name]; !ok {
+			errs = append(errs, fmt.Errorf("gamepad: unknown action %q", name))
+		}
+	}
+	if b.Pad < 0 {
+		errs = append(errs, fmt.Errorf("pad must not be negative, got %d", b.Pad))
+	}
+	return errors.Join(errs...)
+}
+
+// Apply registers every binding with the receiver
+//
+// Receivers can't unregister, Clear the bindings applied before so keys that
+// were dropped since stop driving the player
+func (b *Bindings) Apply(receiver coldbrew.Receiver) {
+	for _, name := range sortedKeys(b.Keyboard) {
+		action, ok := actions.ByName[name]
+		if !ok {
+			continue
+		}
+		for _, key := range b.Keyboard[name] {
+			receiver.RegisterKey(key, action)
+		}
+	}
+
+	if len(b.Gamepad) == 0 {
+		return
+	}
+	receiver.RegisterPad(b.Pad)
+	for _, name := range sortedKeys(b.Gamepad) {
+		action, ok := actions.ByName[name]
+		if !ok {
+			continue
+		}
+		for _, button := range b.Gamepad[name] {
+			receiver.RegisterGamepadButton(ebiten.StandardGamepadButton(button), action)
+		}
+	}
+}
+
+// Clear points every key and button of the bindings at actions.Unbound, so
+// they no longer drive the receiver's player
+func (b *Bindings) Clear(receiver coldbrew.Receiver) {
+	for _, name := range sortedKeys(b.Keyboard) {
+		for _, key := range b.Keyboard[name] {
+			receiver.RegisterKey(key, actions.Unbound)
+		}
+	}
+	for _, name := range sortedKeys(b.Gamepad) {
+		for _, button := range b.Gamepad[name] {
+			receiver.RegisterGamepadButton(ebiten.StandardGamepadButton(button), actions.Unbound)
+		}
+	}
+}
+
+// KeyAction returns the name of the action key is bound to
+func (b *Bindings) KeyAction(key ebiten.Key) (string, bool) {
+	for _, name := range sortedKeys(b.Keyboard) {
+		for _, bound := range b.Keyboard[name] {
+			if bound == key {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ButtonAction returns the name of the action button is bound to
+func (b *Bindings) ButtonAction(button Button) (string, bool) {
+	for _, name := range sortedKeys(b.Gamepad) {
+		for _, bound := range b.Gamepad[name] {
+			if bound == button {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// SetKeys replaces the keys bound to an action
+func (b *Bindings) SetKeys(name string, keys ...ebiten.Key) error {
+	if _, ok := actions.ByName[name]; !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+	if b.Keyboard == nil {
+		b.Keyboard = map[string][]ebiten.Key{}
+	}
+	b.Keyboard[name] = keys
+	return nil
+}
+
+// SetButtons replaces the gamepad buttons bound to an action
+func (b *Bindings) SetButtons(name string, buttons ...Button) error {
+	if _, ok := actions.ByName[name]; !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+	if b.Gamepad == nil {
+		b.Gamepad = map[string][]Button{}
+	}
+	b.Gamepad[name] = buttons
+	return nil
+}
+
+// Clone returns a deep copy, for edits that may be thrown away
+func (b *Bindings) Clone() *Bindings {
+	clone := &Bindings{
+		Keyboard: make(map[string][]ebiten.Key, len(b.Keyboard)),
+		Gamepad:  make(map[string][]Button, len(b.Gamepad)),
+		Pad:      b.Pad,
+	}
+	for name, keys := range b.Keyboard {
+		clone.Keyboard[name] = append([]ebiten.Key(nil), keys...)
+	}
+	for name, buttons := range b.Gamepad {
+		clone.Gamepad[name] = append([]Button(nil), buttons...)
+	}
+	return clone
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,57 @@
+package bindings
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Button is a standard gamepad button that reads and writes as a name like
+// "right-bottom" instead of ebiten's numeric value
+type Button ebiten.StandardGamepadButton
+
+var buttonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "right-bottom",
+	ebiten.StandardGamepadButtonRightRight:       "right-right",
+	ebiten.StandardGamepadButtonRightLeft:        "right-left",
+	ebiten.StandardGamepadButtonRightTop:         "right-top",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "front-top-left",
+	ebiten.StandardGamepadButtonFrontTopRight:    "front-top-right",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "front-bottom-left",
+	ebiten.StandardGamepadButtonFrontBottomRight: "front-bottom-right",
+	ebiten.StandardGamepadButtonCenterLeft:       "center-left",
+	ebiten.StandardGamepadButtonCenterRight:      "center-right",
+	ebiten.StandardGamepadButtonLeftStick:        "left-stick",
+	ebiten.StandardGamepadButtonRightStick:       "right-stick",
+	ebiten.StandardGamepadButtonLeftTop:          "left-top",
+	ebiten.StandardGamepadButtonLeftBottom:       "left-bottom",
+	ebiten.StandardGamepadButtonLeftLeft:         "left-left",
+	ebiten.StandardGamepadButtonLeftRight:        "left-right",
+	ebiten.StandardGamepadButtonCenterCenter:     "center-center",
+}
+
+func (b Button) String() string {
+	name, ok := buttonNames[ebiten.StandardGamepadButton(b)]
+	if !ok {
+		return fmt.Sprintf("button-%d", int(b))
+	}
+	return name
+}
+
+func (b Button) MarshalText() ([]byte, error) {
+	name, ok := buttonNames[ebiten.StandardGamepadButton(b)]
+	if !ok {
+		return nil, fmt.Errorf("unknown gamepad button %d", int(b))
+	}
+	return []byte(name), nil
+}
+
+func (b *Button) UnmarshalText(text []byte) error {
+	for button, name := range buttonNames {
+		if name == string(text) {
+			*b = Button(button)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gamepad button %q", text)
+}
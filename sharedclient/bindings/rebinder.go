@@ -0,0 +1,180 @@
+package bindings
+
+import (
+	"log"
+	"slices"
+
+	"github.com/TheBitDrifter/bappa/coldbrew"
+	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const CANCEL_KEY = ebiten.KeyEscape // Drops the walk without saving
+
+// REBIND_KEYS start walking through the actions, one per receiver index
+var REBIND_KEYS = [MAX_PLAYERS]ebiten.Key{ebiten.KeyF1, ebiten.KeyF2, ebiten.KeyF3, ebiten.KeyF4}
+
+// Rebinder is a global client system for rebinding keys and gamepad buttons in
+// game, one for all local players
+//
+// Pressing a player's rebind key prompts (in the log) for each action in turn,
+// the next key, or button on the player's pad, replaces that action's keys or
+// buttons. Keys and buttons another local player uses are refused. During the
+// walk the player's bindings are cleared from its receiver so the presses
+// don't reach the game. Once every action is bound the bindings are saved and
+// registered again
+type Rebinder struct {
+	Players []*Bindings // Bindings of each local player, by receiver index
+	Paths   []string    // Files the bindings are saved to, by receiver index
+
+	pending *Bindings // nil unless a walk is running
+	player  int
+	step    int
+	keys    []ebiten.Key
+	buttons []ebiten.StandardGamepadButton
+}
+
+func (r *Rebinder) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error {
+	r.keys = inpututil.AppendJustPressedKeys(r.keys[:0])
+
+	if r.pending == nil {
+		for _, key := range r.keys {
+			for player := range r.Players {
+				if key == REBIND_KEYS[player%MAX_PLAYERS] {
+					r.start(cli, player)
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+
+	receiver := cli.Receiver(r.player)
+	for _, key := range r.keys {
+		if key == CANCEL_KEY {
+			log.Printf("Player %d rebinding cancelled", r.player+1)
+			r.Players[r.player].Apply(receiver)
+			r.pending = nil
+			return nil
+		}
+		if isRebindKey(key) || r.keyTaken(key) {
+			continue
+		}
+
+		err := r.pending.SetKeys(actions.Names[r.step], key)
+		if err != nil {
+			return err
+		}
+		if r.next(receiver) {
+			return nil
+		}
+	}
+
+	pad := ebiten.GamepadID(r.pending.Pad)
+	r.buttons = inpututil.AppendJustPressedStandardGamepadButtons(pad, r.buttons[:0])
+	for _, pressed := range r.buttons {
+		button := Button(pressed)
+		if r.buttonTaken(button) {
+			continue
+		}
+
+		err := r.pending.SetButtons(actions.Names[r.step], button)
+		if err != nil {
+			return err
+		}
+		if r.next(receiver) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// start clears the player's bindings from its receiver and prompts for the
+// first action
+func (r *Rebinder) start(cli coldbrew.LocalClient, player int) {
+	r.player = player
+	r.pending = r.Players[player].Clone()
+	r.step = 0
+	r.Players[player].Clear(cli.Receiver(player))
+	r.prompt()
+}
+
+// keyTaken reports (and logs) a key another local player uses, or one already
+// picked earlier in this walk
+func (r *Rebinder) keyTaken(key ebiten.Key) bool {
+	for player, other := range r.Players {
+		if player == r.player {
+			continue
+		}
+		if name, ok := other.KeyAction(key); ok {
+			log.Printf("Player %d: %v is player %d's %q, pick another", r.player+1, key, player+1, name)
+			return true
+		}
+	}
+	for _, name := range actions.Names[:r.step] {
+		if slices.Contains(r.pending.Keyboard[name], key) {
+			log.Printf("Player %d: %v is already %q, pick another", r.player+1, key, name)
+			return true
+		}
+	}
+	return false
+}
+
+// buttonTaken is keyTaken for a button, only players reading the same pad can
+// share it
+func (r *Rebinder) buttonTaken(button Button) bool {
+	for player, other := range r.Players {
+		if player == r.player || other.Pad != r.pending.Pad {
+			continue
+		}
+		if name, ok := other.ButtonAction(button); ok {
+			log.Printf("Player %d: %v is player %d's %q, pick another", r.player+1, button, player+1, name)
+			return true
+		}
+	}
+	for _, name := range actions.Names[:r.step] {
+		if slices.Contains(r.pending.Gamepad[name], button) {
+			log.Printf("Player %d: %v is already %q, pick another", r.player+1, button, name)
+			return true
+		}
+	}
+	return false
+}
+
+// next moves on to the following action, saving and registering the bindings
+// after the last one. It reports whether the walk is over
+func (r *Rebinder) next(receiver coldbrew.Receiver) bool {
+	r.step++
+	if r.step < len(actions.Names) {
+		r.prompt()
+		return false
+	}
+
+	bindings := r.Players[r.player]
+	*bindings = *r.pending
+	r.pending = nil
+	bindings.Apply(receiver)
+
+	path := r.Paths[r.player]
+	err := bindings.Save(path)
+	if err != nil {
+		log.Printf("Failed to save bindings to %s: %v", path, err)
+		return true
+	}
+	log.Printf("Player %d bindings saved to %s", r.player+1, path)
+	return true
+}
+
+func (r *Rebinder) prompt() {
+	log.Printf("Player %d: press a key or gamepad button for %q (%v to cancel)", r.player+1, actions.Names[r.step], CANCEL_KEY)
+}
+
+func isRebindKey(key ebiten.Key) bool {
+	for _, rebind := range REBIND_KEYS {
+		if key == rebind {
+			return true
+		}
+	}
+	return false
+}
@@ -7,20 +7,22 @@ import (
 	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
 	"github.com/TheBitDrifter/bappa/warehouse"
 	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
 )
 
+// CollisionPlayerTransferSystem moves the local players to another scene when
+// one of them touches a PlayerSceneTransfer. The players share the cameras'
+// scenes, so they all go together and none is left behind in a scene no
+// camera shows
 type CollisionPlayerTransferSystem struct{}
 
-type playerTransfer struct {
-	target       string
-	playerEntity warehouse.Entity
-}
+var arrivalQuery = warehouse.Factory.NewQuery().And(components.SceneArrivalTag)
 
-// System handles transfering players when they collide with PlayerSceneTransfer entities
 func (CollisionPlayerTransferSystem) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error {
-	// We transfer after the loop
-	// So we collect pending here
-	var pending []playerTransfer
+	err := adoptScenePhysics(scene)
+	if err != nil {
+		return err
+	}
 
 	// Query the transfer collision entities
 	collisionTransferQuery := warehouse.Factory.NewQuery().And(
@@ -36,63 +38,100 @@ func (CollisionPlayerTransferSystem) Run(cli coldbrew.LocalClient, scene coldbre
 	collisionTransferCursor := scene.NewCursor(collisionTransferQuery)
 	playerWithShapeCursor := scene.NewCursor(playerWithShapeQuery)
 
-	// Iterate through pairs (outer loop)
+	// Find the first transfer any player touches
+	var transfer components.PlayerSceneTransfer
+	found := false
 	for range collisionTransferCursor.Next() {
-
 		// Get pos and collider/shape
 		transferPos := spatial.Components.Position.GetFromCursor(collisionTransferCursor)
 		transferCollider := spatial.Components.Shape.GetFromCursor(collisionTransferCursor)
 
-		// Inner loop
 		for range playerWithShapeCursor.Next() {
-
-			// Get pos and collider/shape
 			playerPos := spatial.Components.Position.GetFromCursor(playerWithShapeCursor)
 			playerCollider := spatial.Components.Shape.GetFromCursor(playerWithShapeCursor)
 
-			// Check for collision
 			if ok, _ := spatial.Detector.Check(*playerCollider, *transferCollider, playerPos, transferPos); ok {
-				sceneTransfer := components.PlayerSceneTransferComponent.GetFromCursor(collisionTransferCursor)
-				playerEn, err := playerWithShapeCursor.CurrentEntity()
-				if err != nil {
-					return err
-				}
-
-				// If collision enqueue transfers
-				transfer := playerTransfer{
-					target:       sceneTransfer.Dest,
-					playerEntity: playerEn,
-				}
-				pending = append(pending, transfer)
-				// Update the player pos
-				playerPos := spatial.Components.Position.GetFromCursor(playerWithShapeCursor)
-				playerPos.X = sceneTransfer.X
-				playerPos.Y = sceneTransfer.Y
-
-				// Update the camera pos
-				camIndex := int(*client.Components.CameraIndex.GetFromCursor(playerWithShapeCursor))
-				cam := cli.Cameras()[camIndex]
-				// Get the cameras local scene position
-				_, cameraScenePosition := cam.Positions()
-				centerX := float64(cam.Surface().Bounds().Dx()) / 2
-				centerY := float64(cam.Surface().Bounds().Dy()) / 2
-
-				// Set position on target player
-				cameraScenePosition.X = playerPos.X - centerX
-				cameraScenePosition.Y = playerPos.Y - centerY
-
+				transfer = *components.PlayerSceneTransferComponent.GetFromCursor(collisionTransferCursor)
+				found = true
+				playerWithShapeCursor.Reset()
+				break
 			}
 		}
+		if found {
+			collisionTransferCursor.Reset()
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	// Every local player goes to the transfer's destination
+	players := []warehouse.Entity{}
+	for range playerWithShapeCursor.Next() {
+		playerEn, err := playerWithShapeCursor.CurrentEntity()
+		if err != nil {
+			return err
+		}
+		players = append(players, playerEn)
+
+		// Update the player pos
+		playerPos := spatial.Components.Position.GetFromCursor(playerWithShapeCursor)
+		playerPos.X = transfer.X
+		playerPos.Y = transfer.Y
+
+		// Update the camera pos
+		camIndex := int(*client.Components.CameraIndex.GetFromCursor(playerWithShapeCursor))
+		cam := cli.Cameras()[camIndex]
+		// Get the cameras local scene position
+		_, cameraScenePosition := cam.Positions()
+		centerX := float64(cam.Surface().Bounds().Dx()) / 2
+		centerY := float64(cam.Surface().Bounds().Dy()) / 2
+
+		// Set position on target player
+		cameraScenePosition.X = playerPos.X - centerX
+		cameraScenePosition.Y = playerPos.Y - centerY
+	}
+
+	// Tag and transfer once the cursor is done with the players
+	for _, en := range players {
+		if en.Table().Contains(components.SceneArrivalTag) {
+			continue
+		}
+		err := en.AddComponent(components.SceneArrivalTag)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = cli.ChangeSceneByName(transfer.Dest, players...)
+	return err
+}
 
+// adoptScenePhysics gives players that arrived from another scene this scene's
+// MovementConfig, once. The target scene may still be loading when players are
+// handed over, so this happens once they are in it rather than on transfer.
+// Every other entity keeps its own tuning
+func adoptScenePhysics(scene coldbrew.Scene) error {
+	if _, ok := coresystems.StoragePhysics(scene.Storage()); !ok {
+		return nil
 	}
 
-	// Process transfers after loop
-	for _, transfer := range pending {
-		_, err := cli.ChangeSceneByName(transfer.target, transfer.playerEntity)
+	arrived := []warehouse.Entity{}
+	cursor := scene.NewCursor(arrivalQuery)
+	for range cursor.Next() {
+		en, err := cursor.CurrentEntity()
 		if err != nil {
 			return err
 		}
+		arrived = append(arrived, en)
 	}
 
+	for _, en := range arrived {
+		coresystems.AdoptScenePhysics(en, scene.Storage())
+		err := en.RemoveComponent(components.SceneArrivalTag)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -12,6 +12,7 @@ var DefaultClientSystems = []coldbrew.ClientSystem{
 	&CameraFollowerSystem{},
 	&coldbrew_clientsystems.BackgroundScrollSystem{},
 	PlayerSpawnSystem{},
+	CollisionPlayerTransferSystem{},
 }
 
 var DefaultClientSystemsNetworked = []coldbrew.ClientSystem{
@@ -9,6 +9,7 @@ import (
 	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
 	"github.com/TheBitDrifter/netcode_example/shared/animations"
 	"github.com/TheBitDrifter/netcode_example/shared/components"
+	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
 )
 
 type PlayerAnimationSystem struct{}
@@ -25,11 +26,31 @@ func (PlayerAnimationSystem) Run(cli coldbrew.LocalClient, scene coldbrew.Scene)
 		if grounded {
 			grounded = scene.CurrentTick()-onGround.LastTouch <= 2
 		}
+		touchingWall, onWall := components.OnWallComponent.GetFromCursorSafe(cursor)
+		if touchingWall {
+			touchingWall = scene.CurrentTick()-onWall.LastTouch <= 2
+		}
+
+		dash := components.DashStateComponent.GetFromCursor(cursor)
+		dashing := dash.LastDash != 0 && scene.CurrentTick()-dash.LastDash < coresystems.DASH_TICKS
+		crouching := components.CrouchStateComponent.GetFromCursor(cursor).Crouching
+
+		// Player is mid dash
+		if dashing {
+			spriteBlueprint.TryAnimation(animations.DashAnimation)
 
-		// Player is moving horizontal and grounded (running)
-		if math.Abs(dyn.Vel.X) > 20 && grounded {
+			// Player is crouching
+		} else if crouching {
+			spriteBlueprint.TryAnimation(animations.CrouchAnimation)
+
+			// Player is moving horizontal and grounded (running)
+		} else if math.Abs(dyn.Vel.X) > 20 && grounded {
 			spriteBlueprint.TryAnimation(animations.RunAnimation)
 
+			// Player is moving down a wall and not grounded (wall sliding)
+		} else if dyn.Vel.Y > 0 && !grounded && touchingWall {
+			spriteBlueprint.TryAnimation(animations.WallSlideAnimation)
+
 			// Player is moving down and not grounded (falling)
 		} else if dyn.Vel.Y > 0 && !grounded {
 			spriteBlueprint.TryAnimation(animations.FallAnimation)
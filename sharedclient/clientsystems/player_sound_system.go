@@ -86,5 +86,46 @@ func (sys PlayerSoundSystem) Run(cli coldbrew.LocalClient, scene coldbrew.Scene)
 		}
 	}
 
+	return sys.wallSounds(scene)
+}
+
+// wallSounds plays the landing sound when a player grabs a wall and the jump
+// sound when they jump off it (they may not be grounded, so the loop above misses it)
+func (PlayerSoundSystem) wallSounds(scene coldbrew.Scene) error {
+	playersWithSoundsOnAWall := warehouse.Factory.NewQuery().And(
+		client.Components.SoundBundle,
+		input.Components.ActionBuffer,
+		components.OnWallComponent,
+	)
+
+	cursor := scene.NewCursor(playersWithSoundsOnAWall)
+	currentTick := scene.CurrentTick()
+
+	for range cursor.Next() {
+		soundBundle := client.Components.SoundBundle.GetFromCursor(cursor)
+		onWall := components.OnWallComponent.GetFromCursor(cursor)
+		jumpState := components.JumpStateComponent.GetFromCursor(cursor)
+
+		var config client.SoundConfig
+		switch currentTick {
+		case onWall.Grabbed:
+			config = sounds.Land
+		case jumpState.LastJump:
+			config = sounds.Jump
+		default:
+			continue
+		}
+
+		sound, err := coldbrew.MaterializeSound(soundBundle, config)
+		if err != nil {
+			return err
+		}
+		player := sound.GetAny()
+
+		if !player.IsPlaying() {
+			player.Rewind()
+			player.Play()
+		}
+	}
 	return nil
 }
@@ -3,12 +3,17 @@ package clientsystems
 import (
 	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/coldbrew"
-	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/netcode_example/shared/components"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
 )
 
-type PlayerSpawnSystem struct{}
+// PlayerSpawnSystem adds a player per active receiver to an empty scene at spawn
+// points chosen by Policy (spawn.Default when nil). The player of receiver i
+// belongs to team i of Teams (spawn.DefaultTeams when nil)
+type PlayerSpawnSystem struct {
+	Policy spawn.Policy
+	Teams  *spawn.Teams
+}
 
 func (s PlayerSpawnSystem) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error {
 	playerCursor := scene.NewCursor(blueprint.Queries.ActionBuffer)
@@ -16,19 +21,25 @@ func (s PlayerSpawnSystem) Run(cli coldbrew.LocalClient, scene coldbrew.Scene) e
 	if playerCount != 0 {
 		return nil
 	}
-	spawnQuery := warehouse.Factory.NewQuery().And(components.PlayerSpawnComponent)
-	spawnCursor := scene.NewCursor(spawnQuery)
 
-	var spawn components.PlayerSpawn
-	for range spawnCursor.Next() {
-		found := components.PlayerSpawnComponent.GetFromCursor(spawnCursor)
-		spawn = *found
-		break
+	teams := s.Teams
+	if teams == nil {
+		teams = spawn.DefaultTeams
 	}
 
-	_, err := scenes.NewPlayer(spawn.X, spawn.Y, scene.Storage())
-	if err != nil {
-		return err
+	for i := range len(cli.Cameras()) {
+		receiver := cli.Receiver(i)
+		if receiver == nil || !receiver.Active() {
+			continue
+		}
+
+		// Scenes without spawn points start the player at the origin
+		point, _ := spawn.Select(scene.Storage(), s.Policy, teams.Of(i))
+
+		_, err := scenes.NewLocalPlayer(point.X, point.Y, scene.Storage(), i)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -7,4 +7,6 @@ const (
 	MAX_SOUNDS_CACHED  = 100
 	MAX_SCENES_CACHED  = 12
 	SERVER_ADDRESS     = "localhost:8080" // Default Drip server address
+	SESSION_ADDRESS    = "localhost:8081" // Usual session endpoint, for resuming after a reconnect when the server enables it
+	BINDINGS_PATH      = "bindings.json"  // Default key and gamepad bindings file, created on first rebind
 )
@@ -24,7 +24,6 @@ require (
 	github.com/TheBitDrifter/bappa/coldbrew v0.0.0-20250408214137-aae872bb6dfc
 	github.com/TheBitDrifter/netcode_example/shared v0.0.0-00010101000000-000000000000
 	github.com/TheBitDrifter/netcode_example/sharedclient v0.0.0-00010101000000-000000000000
-	github.com/hajimehoshi/ebiten/v2 v2.8.7
 )
 
 require (
@@ -42,6 +41,7 @@ require (
 	github.com/ebitengine/oto/v3 v3.3.3 // indirect
 	github.com/ebitengine/purego v0.8.0 // indirect
 	github.com/go-text/typesetting v0.2.0 // indirect
+	github.com/hajimehoshi/ebiten/v2 v2.8.7 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/sync v0.12.0 // indirect
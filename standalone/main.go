@@ -1,22 +1,60 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"strings"
 
+	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/coldbrew"
 	"github.com/TheBitDrifter/bappa/coldbrew/coldbrew_clientsystems"
 	"github.com/TheBitDrifter/bappa/coldbrew/coldbrew_rendersystems"
-	"github.com/TheBitDrifter/netcode_example/shared/actions"
+	"github.com/TheBitDrifter/bappa/warehouse"
 	"github.com/TheBitDrifter/netcode_example/shared/coresystems"
 	"github.com/TheBitDrifter/netcode_example/shared/scenes"
+	"github.com/TheBitDrifter/netcode_example/shared/spawn"
 	"github.com/TheBitDrifter/netcode_example/sharedclient"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/assets"
+	"github.com/TheBitDrifter/netcode_example/sharedclient/bindings"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/clientsystems"
 	"github.com/TheBitDrifter/netcode_example/sharedclient/rendersystems"
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 func main() {
+	spawnPolicy := flag.String("spawn-policy", spawn.DEFAULT_POLICY, "How players pick a spawn point ("+strings.Join(spawn.Names(), ", ")+")")
+	teamList := flag.String("teams", "", "Comma separated teams, local player i joins team i and only uses its spawn points")
+	airJumps := flag.Int("air-jumps", -1, "Jumps players get mid-air before landing, -1 keeps each level's airJumps")
+	bindingsPath := flag.String("bindings", sharedclient.BINDINGS_PATH, "Key and gamepad bindings file (JSON) of player 1, the others use numbered files next to it (bindings.p2.json), F1-F4 in game rebinds and saves to them")
+	players := flag.Int("players", 1, "Local split screen players (1-4)")
+	layout := flag.String("layout", LAYOUT_HORIZONTAL, "How split screen views are arranged ("+LAYOUT_HORIZONTAL+", "+LAYOUT_GRID+")")
+	flag.Parse()
+
+	if *players < 1 || *players > bindings.MAX_PLAYERS {
+		log.Fatalf("-players must be between 1 and %d, got %d", bindings.MAX_PLAYERS, *players)
+	}
+	if *layout != LAYOUT_HORIZONTAL && *layout != LAYOUT_GRID {
+		log.Fatalf("-layout must be %s or %s, got %q", LAYOUT_HORIZONTAL, LAYOUT_GRID, *layout)
+	}
+
+	// Same policies as the server, used by the PlayerSpawnSystem
+	policy, err := spawn.New(*spawnPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	spawn.Default = policy
+	spawn.DefaultTeams = &spawn.Teams{Names: splitList(*teamList)}
+
+	// One bindings file per local player, a single rebinder keeps them apart
+	playerKeys := make([]*bindings.Bindings, *players)
+	paths := make([]string, *players)
+	for i := range playerKeys {
+		paths[i] = bindings.PlayerPath(*bindingsPath, i)
+		playerKeys[i], err = bindings.LoadPlayer(paths[i], i)
+		if err != nil {
+			log.Fatalf("Failed to load bindings: %v", err)
+		}
+	}
+
 	client := coldbrew.NewClient(
 		sharedclient.RESOLUTION_X,
 		sharedclient.RESOLUTION_Y,
@@ -32,19 +70,25 @@ func main() {
 	client.SetResizable(true)
 	client.SetMinimumLoadTime(30)
 
-	log.Println("Registering Scene One...")
-	err := client.RegisterScene(
-		scenes.SceneOne.Name,
-		scenes.SceneOne.Width,
-		scenes.SceneOne.Height,
-		scenes.SceneOne.Plan,
-		rendersystems.DefaultRenderSystems,
-		clientsystems.DefaultClientSystems,
-		coresystems.DefaultCoreSystems,
-		scenes.SceneOne.Preload...,
-	)
-	if err != nil {
-		log.Fatalf("Failed to register Scene One: %v", err)
+	for _, scene := range scenes.Catalog {
+		log.Println("Registering Scene:", scene.Name)
+		plan := scene.Plan
+		if *airJumps >= 0 {
+			plan = withAirJumps(plan, *airJumps)
+		}
+		err := client.RegisterScene(
+			scene.Name,
+			scene.Width,
+			scene.Height,
+			plan,
+			rendersystems.DefaultRenderSystems,
+			clientsystems.DefaultClientSystems,
+			coresystems.NewCoreSystems(scenes.LevelMovementConfig(scene.Name)),
+			scene.Preload...,
+		)
+		if err != nil {
+			log.Fatalf("Failed to register %s: %v", scene.Name, err)
+		}
 	}
 
 	log.Println("Registering Global Systems...")
@@ -56,26 +100,52 @@ func main() {
 		coldbrew_clientsystems.InputBufferSystem{},
 		&coldbrew_clientsystems.CameraSceneAssignerSystem{},
 	)
+	client.RegisterGlobalClientSystem(&bindings.Rebinder{Players: playerKeys, Paths: paths})
 
-	log.Println("Activating Camera...")
-	_, err = client.ActivateCamera()
-	if err != nil {
-		log.Fatalf("Failed to activate camera: %v", err)
-	}
+	// Camera and receiver i belong to player i, see scenes.NewLocalPlayer
+	cameras := make([]coldbrew.Camera, *players)
+	for i, keys := range playerKeys {
+		log.Printf("Activating Camera and Input Receiver for player %d...", i+1)
+		receiver, err := client.ActivateReceiver()
+		if err != nil {
+			log.Fatalf("Failed to activate receiver: %v", err)
+		}
+		keys.Apply(receiver)
 
-	log.Println("Activating Input Receiver and Mapping Keys...")
-	receiver1, err := client.ActivateReceiver()
-	if err != nil {
-		log.Fatalf("Failed to activate receiver: %v", err)
+		cameras[i], err = client.ActivateCamera()
+		if err != nil {
+			log.Fatalf("Failed to activate camera: %v", err)
+		}
 	}
-	receiver1.RegisterKey(ebiten.KeySpace, actions.Jump)
-	receiver1.RegisterKey(ebiten.KeyW, actions.Jump)
-	receiver1.RegisterKey(ebiten.KeyA, actions.Left)
-	receiver1.RegisterKey(ebiten.KeyD, actions.Right)
-	receiver1.RegisterKey(ebiten.KeyS, actions.Down)
+	layoutCameras(cameras, *layout)
 
 	log.Println("Starting Ebiten game loop (blocking)...")
 	if err := client.Start(); err != nil {
 		log.Fatalf("Client exited with error: %v", err)
 	}
 }
+
+// withAirJumps overrides the air jumps of the scene's movement config once its
+// plan has run, players copy it when they spawn
+func withAirJumps(plan blueprint.Plan, airJumps int) blueprint.Plan {
+	return func(width, height int, sto warehouse.Storage) error {
+		err := plan(width, height, sto)
+		if err != nil {
+			return err
+		}
+		cfg := scenes.SceneMovementConfig(sto)
+		cfg.AirJumps = airJumps
+		return scenes.SetSceneMovementConfig(sto, cfg)
+	}
+}
+
+// splitList splits a comma separated flag, dropping empty items
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
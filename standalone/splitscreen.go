@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/TheBitDrifter/bappa/coldbrew"
+	"github.com/TheBitDrifter/netcode_example/sharedclient"
+)
+
+const (
+	LAYOUT_HORIZONTAL = "horizontal" // Views side by side
+	LAYOUT_GRID       = "grid"       // Two views per row, a lone view in the last row spans it
+)
+
+// layoutCameras splits the screen between the cameras, a single camera keeps
+// the whole screen. Three players on a grid get two views over one wide view
+func layoutCameras(cameras []coldbrew.Camera, layout string) {
+	cols, rows := len(cameras), 1
+	if layout == LAYOUT_GRID && len(cameras) > 2 {
+		cols, rows = 2, (len(cameras)+1)/2
+	}
+
+	height := sharedclient.RESOLUTION_Y / rows
+	for i, cam := range cameras {
+		row, col := i/cols, i%cols
+		// The last row can be short, its views share the full width
+		inRow := min(cols, len(cameras)-row*cols)
+		width := sharedclient.RESOLUTION_X / inRow
+
+		cam.SetDimensions(width, height)
+		screenPosition, _ := cam.Positions()
+		screenPosition.X = float64(col * width)
+		screenPosition.Y = float64(row * height)
+	}
+}